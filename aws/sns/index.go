@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -13,16 +15,22 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 	xd_rsync "github.com/fabiofcferreira/xd-rsync"
 	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SNSClient struct {
-	client *sns.Client
-	logger *logger.Logger
+	client  *sns.Client
+	logger  *logger.Logger
+	metrics *metrics.Metrics
 }
 
 type SNSClientCreationInput struct {
-	Region string
-	Logger *logger.Logger
+	Region  string
+	Logger  *logger.Logger
+	Metrics *metrics.Metrics
 }
 
 type MessagePublishSuccess struct {
@@ -41,7 +49,8 @@ func (me MessagePublishError) Error() string {
 
 func CreateClient(input *SNSClientCreationInput) (*SNSClient, error) {
 	clientInstance := &SNSClient{
-		logger: input.Logger,
+		logger:  input.Logger,
+		metrics: input.Metrics,
 	}
 
 	clientInstance.logger.Info("init_sns_client_create", "Creating SNS client instance", nil)
@@ -59,19 +68,40 @@ func CreateClient(input *SNSClientCreationInput) (*SNSClient, error) {
 	return clientInstance, nil
 }
 
-func (s SNSClient) publishMessage(topicArn string, input *xd_rsync.MessagePublishInput, maxRetries int) (*MessagePublishSuccess, error) {
+func buildMessageAttributes(attributes map[string]string) map[string]types.MessageAttributeValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	messageAttributes := map[string]types.MessageAttributeValue{}
+	for key, value := range attributes {
+		messageAttributes[key] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	return messageAttributes
+}
+
+func (s SNSClient) publishMessage(ctx context.Context, topicArn string, input *xd_rsync.MessagePublishInput, maxRetries int) (*MessagePublishSuccess, error) {
 	publishInput := &sns.PublishInput{
-		TopicArn:       &topicArn,
-		Message:        aws.String(input.Message),
-		MessageGroupId: aws.String(input.MessageGroupId),
+		TopicArn:          &topicArn,
+		Message:           aws.String(input.Message),
+		MessageGroupId:    aws.String(input.MessageGroupId),
+		MessageAttributes: buildMessageAttributes(tracing.InjectIntoAttributes(ctx, input.Attributes)),
 	}
 
 	var err error
 	var publishOutput *sns.PublishOutput
 	for tries := 1; tries < maxRetries; tries++ {
-		publishOutput, err = s.client.Publish(context.TODO(), publishInput)
+		requestStartedAt := time.Now()
+		publishOutput, err = s.client.Publish(ctx, publishInput)
+		s.metrics.ObserveSNSPublish("publish", requestStartedAt, err)
 
 		if err == nil && len(*publishOutput.MessageId) > 0 {
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Int("messaging.retry_count", tries-1))
+			input.Acknowledge(time.Now())
 			return &MessagePublishSuccess{
 				Id:               "msg",
 				MessagePublishId: *publishOutput.MessageId,
@@ -85,66 +115,83 @@ func (s SNSClient) publishMessage(topicArn string, input *xd_rsync.MessagePublis
 	}
 }
 
-func (s SNSClient) publishMessageList(topicArn string, messages *[]xd_rsync.MessagePublishInput, maxRetries int) (int, []error) {
-	allMessageIds := []string{
-		"http-request",
-	}
+func (s SNSClient) publishMessageList(ctx context.Context, topicArn string, messages *[]xd_rsync.MessagePublishInput, maxRetries int) (int, []error) {
+	ctx, span := tracing.StartSpan(ctx, "sns.publish_batch",
+		attribute.String("messaging.destination.name", topicArn),
+		attribute.Int("messaging.batch.message_count", len(*messages)),
+	)
+
 	allErrors := []error{}
 	errorsMapByMessageId := map[string]error{}
 
+	messageIndexesById := map[string]int{}
 	pendingMessages := []types.PublishBatchRequestEntry{}
 	for index, msg := range *messages {
+		messageIndexesById["msg-"+strconv.Itoa(index)] = index
 		pendingMessages = append(pendingMessages, types.PublishBatchRequestEntry{
-			Id:             aws.String("msg-" + strconv.Itoa(index)),
-			Message:        aws.String(msg.Message),
-			MessageGroupId: aws.String(msg.MessageGroupId),
+			Id:                aws.String("msg-" + strconv.Itoa(index)),
+			Message:           aws.String(msg.Message),
+			MessageGroupId:    aws.String(msg.MessageGroupId),
+			MessageAttributes: buildMessageAttributes(tracing.InjectIntoAttributes(ctx, msg.Attributes)),
 		})
-		allMessageIds = append(allMessageIds, "msg-"+strconv.Itoa(index))
 	}
 
 	batchPublishOutput := &sns.PublishBatchOutput{}
 	var batchRequestErr error
-	for tries := 0; tries < maxRetries; tries++ {
+	tries := 0
+	for ; tries < maxRetries && len(pendingMessages) > 0; tries++ {
 		publishInput := &sns.PublishBatchInput{
 			TopicArn:                   &topicArn,
 			PublishBatchRequestEntries: pendingMessages,
 		}
 
-		batchPublishOutput, batchRequestErr = s.client.PublishBatch(context.TODO(), publishInput)
+		requestStartedAt := time.Now()
+		batchPublishOutput, batchRequestErr = s.client.PublishBatch(ctx, publishInput)
+		s.metrics.ObserveSNSPublish("publish_batch", requestStartedAt, batchRequestErr)
 		if batchRequestErr != nil {
 			continue
 		}
 
-		if len(batchPublishOutput.Failed) == 0 {
-			return len(*messages), nil
-		}
+		acknowledgedAt := time.Now()
 
 		// Remove published messages from pending list
 		for _, publishedMsg := range batchPublishOutput.Successful {
-			for pendingMsgIndex, pendingMsg := range pendingMessages {
-				if pendingMsg.Id == publishedMsg.Id {
-					pendingMessages = append(pendingMessages[:pendingMsgIndex], pendingMessages[pendingMsgIndex+1:]...)
-					errorsMapByMessageId[*pendingMsg.Id] = nil
-					break
-				}
+			if index, ok := messageIndexesById[aws.ToString(publishedMsg.Id)]; ok {
+				(*messages)[index].Acknowledge(acknowledgedAt)
 			}
+
+			pendingMessages = slices.DeleteFunc(pendingMessages, func(pendingMsg types.PublishBatchRequestEntry) bool {
+				return aws.ToString(pendingMsg.Id) == aws.ToString(publishedMsg.Id)
+			})
+			delete(errorsMapByMessageId, aws.ToString(publishedMsg.Id))
 		}
 
 		// Add failed message error description to the map
 		for _, failedMsg := range batchPublishOutput.Failed {
-			for _, pendingMsg := range pendingMessages {
-				if pendingMsg.Id == failedMsg.Id {
-					errorsMapByMessageId[*pendingMsg.Id] = errors.New(*failedMsg.Message)
-					break
-				}
-			}
+			errorsMapByMessageId[aws.ToString(failedMsg.Id)] = errors.New(aws.ToString(failedMsg.Message))
+		}
+	}
+
+	for _, pendingMsg := range pendingMessages {
+		if err, ok := errorsMapByMessageId[aws.ToString(pendingMsg.Id)]; ok {
+			allErrors = append(allErrors, err)
+		} else if batchRequestErr != nil {
+			allErrors = append(allErrors, batchRequestErr)
 		}
 	}
 
-	for _, messageId := range allMessageIds {
-		allErrors = append(allErrors, errorsMapByMessageId[messageId], batchRequestErr)
+	span.SetAttributes(
+		attribute.Int("messaging.retry_count", max(tries-1, 0)),
+		attribute.Int("messaging.batch.failed_count", len(pendingMessages)),
+	)
+
+	var spanErr error
+	if len(allErrors) > 0 {
+		spanErr = allErrors[0]
 	}
-	return len(*messages), allErrors
+	tracing.EndSpan(span, spanErr)
+
+	return len(*messages) - len(pendingMessages), allErrors
 }
 
 func (s SNSClient) chunkMessages(messages *[]xd_rsync.MessagePublishInput) *map[int][]xd_rsync.MessagePublishInput {
@@ -165,12 +212,30 @@ func (s SNSClient) chunkMessages(messages *[]xd_rsync.MessagePublishInput) *map[
 	return chunks
 }
 
-func (s SNSClient) SendMessage(topicArn string, input *xd_rsync.MessagePublishInput) error {
+// Checks the topic exists and is reachable with the current credentials
+func (s SNSClient) CheckTopic(ctx context.Context, topicArn string) error {
+	_, err := s.client.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{
+		TopicArn: aws.String(topicArn),
+	})
+	if err != nil {
+		return fmt.Errorf("could not get SNS topic attributes: %w", err)
+	}
+
+	return nil
+}
+
+func (s SNSClient) SendMessage(ctx context.Context, topicArn string, input *xd_rsync.MessagePublishInput) error {
+	// Message bodies are never logged, as they can hold personal data
 	s.logger.Info("init_sns_message_send", "Start sending SNS message", &map[string]interface{}{
-		"message": input,
+		"messageGroupId": input.MessageGroupId,
 	})
-	MessagePublishSuccess, err := s.publishMessage(topicArn, input, 5)
+	ctx, span := tracing.StartSpan(ctx, "sns.publish",
+		attribute.String("messaging.destination.name", topicArn),
+	)
+	MessagePublishSuccess, err := s.publishMessage(ctx, topicArn, input, 5)
+	tracing.EndSpan(span, err)
 	if err != nil {
+		s.metrics.MessagesFailed.Inc(nil)
 		s.logger.Error("failed_sns_message_send", "Failed to send SNS message", &map[string]interface{}{
 			"error": err,
 		})
@@ -178,23 +243,32 @@ func (s SNSClient) SendMessage(topicArn string, input *xd_rsync.MessagePublishIn
 		return err
 	}
 
+	s.metrics.MessagesPublished.Inc(nil)
 	s.logger.Info("finished_sns_message_send", "Finished sending SNS message", &map[string]interface{}{
-		"message":          input,
+		"messageGroupId":   input.MessageGroupId,
 		"messagePublishId": MessagePublishSuccess.MessagePublishId,
 	})
 	return nil
 }
 
-func (s SNSClient) SendMessagesBatch(topicArn string, messages *[]xd_rsync.MessagePublishInput) (int, []error) {
+func (s SNSClient) SendMessagesBatch(ctx context.Context, topicArn string, messages *[]xd_rsync.MessagePublishInput) (int, []error) {
 	allErrors := []error{}
 	chunks := s.chunkMessages(messages)
 
+	ctx, span := tracing.StartSpan(ctx, "sns.send_messages_batch",
+		attribute.String("messaging.destination.name", topicArn),
+		attribute.Int("messaging.batch.message_count", len(*messages)),
+		attribute.Int("messaging.batch.chunks_count", len(*chunks)),
+	)
+	defer span.End()
+
 	s.logger.Info("init_sns_messages_batch_send", "Start sending batch of SNS messages", &map[string]interface{}{
 		"messagesCount": len(*messages),
 		"chunks":        len(*chunks),
 	})
 
 	wg := sync.WaitGroup{}
+	resultsMutex := sync.Mutex{}
 
 	totalSentMessages := 0
 	for chunkNumber := 0; chunkNumber < len(*chunks); chunkNumber++ {
@@ -202,7 +276,12 @@ func (s SNSClient) SendMessagesBatch(topicArn string, messages *[]xd_rsync.Messa
 
 		go func() {
 			currentChunk := (*chunks)[chunkNumber]
-			sentMessages, errs := s.publishMessageList(topicArn, &currentChunk, 5)
+			sentMessages, errs := s.publishMessageList(ctx, topicArn, &currentChunk, 5)
+			s.metrics.MessagesPublished.Add(float64(sentMessages), nil)
+			s.metrics.MessagesFailed.Add(float64(len(currentChunk)-sentMessages), nil)
+
+			resultsMutex.Lock()
+			defer resultsMutex.Unlock()
 			if len(errs) > 0 {
 				s.logger.Warn("sns_messages_batch_with_errors", "SNS messages batch publish with errors", &map[string]interface{}{
 					"errors": errs,
@@ -0,0 +1,220 @@
+package sns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Stand-in for the SNS query API, accepting PublishBatch requests. The first request of a batch whose first
+// message is in failFirstAttempt is rejected, so the client retries it.
+type testSNSServer struct {
+	server           *httptest.Server
+	mutex            sync.Mutex
+	failFirstAttempt map[string]bool
+	attempts         map[string]int
+	// Attributes of every published message, by message body
+	attributes map[string]map[string]string
+}
+
+func startTestSNSServer(t *testing.T, failFirstAttempt ...string) *testSNSServer {
+	server := &testSNSServer{
+		failFirstAttempt: map[string]bool{},
+		attempts:         map[string]int{},
+		attributes:       map[string]map[string]string{},
+	}
+	for _, message := range failFirstAttempt {
+		server.failFirstAttempt[message] = true
+	}
+
+	server.server = httptest.NewServer(http.HandlerFunc(server.handlePublishBatch))
+	t.Cleanup(server.server.Close)
+
+	return server
+}
+
+func (s *testSNSServer) handlePublishBatch(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil || r.PostForm.Get("Action") != "PublishBatch" {
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	firstMessage := r.PostForm.Get("PublishBatchRequestEntries.member.1.Message")
+	s.attempts[firstMessage]++
+	if s.failFirstAttempt[firstMessage] && s.attempts[firstMessage] == 1 {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `<ErrorResponse><Error><Type>Receiver</Type><Code>InternalError</Code><Message>unavailable</Message></Error><RequestId>1</RequestId></ErrorResponse>`)
+		return
+	}
+
+	successful := []string{}
+	for member := 1; ; member++ {
+		prefix := "PublishBatchRequestEntries.member." + strconv.Itoa(member) + "."
+		id := r.PostForm.Get(prefix + "Id")
+		if len(id) == 0 {
+			break
+		}
+
+		attributes := map[string]string{}
+		for entry := 1; ; entry++ {
+			entryPrefix := prefix + "MessageAttributes.entry." + strconv.Itoa(entry) + "."
+			name := r.PostForm.Get(entryPrefix + "Name")
+			if len(name) == 0 {
+				break
+			}
+			attributes[name] = r.PostForm.Get(entryPrefix + "Value.StringValue")
+		}
+		s.attributes[r.PostForm.Get(prefix+"Message")] = attributes
+
+		successful = append(successful, "<member><Id>"+id+"</Id><MessageId>sns-"+id+"</MessageId></member>")
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, `<PublishBatchResponse xmlns="http://sns.amazonaws.com/doc/2010-03-31/"><PublishBatchResult><Successful>`+
+		strings.Join(successful, "")+
+		`</Successful><Failed></Failed></PublishBatchResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></PublishBatchResponse>`)
+}
+
+func createTestClient(t *testing.T, server *testSNSServer) *SNSClient {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction:  true,
+		InitialFields: map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	testMetrics, err := metrics.CreateMetrics(&metrics.MetricsCreationInput{Logger: testLogger})
+	if err != nil {
+		t.Fatalf("could not create metrics: %v", err)
+	}
+
+	return &SNSClient{
+		client: sns.New(sns.Options{
+			Region:       "eu-west-1",
+			BaseEndpoint: aws.String(server.server.URL),
+			Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+			}),
+			// Retries are left to the client, so they show in its spans
+			Retryer: aws.NopRetryer{},
+		}),
+		logger:  testLogger,
+		metrics: testMetrics,
+	}
+}
+
+func getSpanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, keyValue := range span.Attributes {
+		if keyValue.Key == key {
+			return keyValue.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestSendMessagesBatchTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	// The second chunk starts with the eleventh message
+	server := startTestSNSServer(t, "event-10")
+	client := createTestClient(t, server)
+
+	messages := []xd_rsync.MessagePublishInput{}
+	for index := 0; index < 12; index++ {
+		messages = append(messages, xd_rsync.MessagePublishInput{
+			Message:        "event-" + strconv.Itoa(index),
+			MessageGroupId: strconv.Itoa(index),
+			Attributes:     map[string]string{"tenantId": "store-a"},
+		})
+	}
+
+	ctx, runSpan := tracing.StartSpan(context.Background(), "sync_run")
+	sentMessages, errs := client.SendMessagesBatch(ctx, "arn:aws:sns:eu-west-1:123456789012:products.fifo", &messages)
+	tracing.EndSpan(runSpan, nil)
+	if sentMessages != 12 || len(errs) > 0 {
+		t.Fatalf("expected 12 messages to be sent, got %d and errors %v", sentMessages, errs)
+	}
+
+	var run, send tracetest.SpanStub
+	batches := map[int64]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		switch span.Name {
+		case "sync_run":
+			run = span
+		case "sns.send_messages_batch":
+			send = span
+		case "sns.publish_batch":
+			batches[getSpanAttribute(span, "messaging.batch.message_count").AsInt64()] = span
+		}
+	}
+
+	if send.Parent.SpanID() != run.SpanContext.SpanID() {
+		t.Errorf("expected sns.send_messages_batch to be a child of sync_run")
+	}
+	if getSpanAttribute(send, "messaging.batch.chunks_count").AsInt64() != 2 {
+		t.Errorf("expected 2 chunks, got %v", send.Attributes)
+	}
+
+	if len(batches) != 2 {
+		t.Fatalf("expected a sns.publish_batch span per chunk of 10 and 2 messages, got %v", batches)
+	}
+
+	expectedRetries := map[int64]int64{10: 0, 2: 1}
+	for messageCount, batch := range batches {
+		if batch.Parent.SpanID() != send.SpanContext.SpanID() || batch.SpanContext.TraceID() != run.SpanContext.TraceID() {
+			t.Errorf("expected the batch of %d messages to be a child of sns.send_messages_batch", messageCount)
+		}
+
+		if getSpanAttribute(batch, "messaging.retry_count").AsInt64() != expectedRetries[messageCount] {
+			t.Errorf("expected %d retries for the batch of %d messages, got %v", expectedRetries[messageCount], messageCount, batch.Attributes)
+		}
+
+		if getSpanAttribute(batch, "messaging.batch.failed_count").AsInt64() != 0 {
+			t.Errorf("expected no failed messages in the batch of %d messages, got %v", messageCount, batch.Attributes)
+		}
+	}
+
+	// Consumers continue the trace from the batch that published each message
+	for index, message := range messages {
+		batch := batches[10]
+		if index >= 10 {
+			batch = batches[2]
+		}
+
+		attributes := server.attributes[message.Message]
+		expectedTraceparent := fmt.Sprintf("00-%s-%s-01", batch.SpanContext.TraceID(), batch.SpanContext.SpanID())
+		if attributes["traceparent"] != expectedTraceparent || attributes["tenantId"] != "store-a" {
+			t.Errorf("expected message %s to have traceparent %s, got attributes %v", message.Message, expectedTraceparent, attributes)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package xd_rsync
+
+import (
+	"time"
+)
+
+const (
+	// Changes are not published until they are confirmed through the admin API
+	MASS_CHANGE_ACTION_HOLD = "hold"
+	// Changes are published to the bulk topic, throttled
+	MASS_CHANGE_ACTION_BULK = "bulk"
+)
+
+var MASS_CHANGE_ACTIONS = []string{MASS_CHANGE_ACTION_HOLD, MASS_CHANGE_ACTION_BULK}
+
+// Limits the records of a stream changed in a single run, above which the run is a mass change, e.g. after XD is
+// reindexed or restored
+type MassChangeConfig struct {
+	// Changed records above which a run is a mass change. Disabled when 0
+	MaxChangedCount int `json:"maxChangedCount"`
+	// Share of the records changed above which a run is a mass change, in percentage. Disabled when 0
+	MaxChangedPercentage float64       `json:"maxChangedPercentage"`
+	Action               string        `json:"action"`
+	BulkBatchSize        int           `json:"bulkBatchSize"`
+	BulkBatchInterval    time.Duration `json:"bulkBatchInterval"`
+}
+
+func (c *MassChangeConfig) IsEnabled() bool {
+	return c != nil && (c.MaxChangedCount > 0 || c.MaxChangedPercentage > 0)
+}
+
+// Whether the changed records are a mass change. The total count is only used by the percentage limit.
+func (c *MassChangeConfig) IsMassChange(changedCount int, totalCount int) bool {
+	if !c.IsEnabled() || changedCount == 0 {
+		return false
+	}
+
+	if c.MaxChangedCount > 0 && changedCount > c.MaxChangedCount {
+		return true
+	}
+
+	return c.MaxChangedPercentage > 0 && totalCount > 0 && float64(changedCount)/float64(totalCount)*100 > c.MaxChangedPercentage
+}
+
+// Mass change held back until it is confirmed
+type MassChangeHold struct {
+	Entity       string    `json:"entity"`
+	ChangedCount int       `json:"changedCount"`
+	TotalCount   int       `json:"totalCount"`
+	DetectedAt   time.Time `json:"detectedAt"`
+	IsConfirmed  bool      `json:"isConfirmed"`
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/aws/sns"
+	"github.com/fabiofcferreira/xd-rsync/database"
+	"github.com/fabiofcferreira/xd-rsync/encoders"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+	"github.com/fabiofcferreira/xd-rsync/store"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+)
+
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
+type ShutdownFunc func(ctx context.Context)
+
+// Creates the application instance and all of its services from the given configuration. Sources without an
+// initial checkpoint start from the one kept in the state store, or from xd_rsync.INITIAL_CHECKPOINT.
+func createApp(cfg *xd_rsync.Config, initialCheckpoints map[string]time.Time) (*xd_rsync.XdRsyncInstance, ShutdownFunc) {
+	logger, err := logger.CreateLogger(
+		&logger.LoggerOptions{
+			IsProduction:      cfg.IsProductionMode,
+			Level:             cfg.LogLevel,
+			InitialFields:     *cfg.DatadogConfig.EventBaseFields,
+			DatadogIngestHost: cfg.DatadogConfig.IngestHost,
+			DatadogApiKey:     cfg.DatadogConfig.ApiKey,
+		})
+	if err != nil {
+		panic(fmt.Errorf("logger error: %w", err))
+	}
+
+	app := &xd_rsync.XdRsyncInstance{
+		Config:    cfg,
+		Logger:    logger,
+		Services:  &xd_rsync.XdRsyncServices{},
+		StartedAt: time.Now(),
+	}
+
+	statsdAddress := ""
+	if cfg.DatadogConfig.StatsdAddress != nil {
+		statsdAddress = *cfg.DatadogConfig.StatsdAddress
+	}
+
+	app.Metrics, err = metrics.CreateMetrics(&metrics.MetricsCreationInput{
+		DogStatsdAddress: statsdAddress,
+		ConstantTags: map[string]string{
+			"app_name":    "xd_rsync",
+			"environment": cfg.Environment,
+		},
+		Logger: logger,
+	})
+	if err != nil {
+		app.Logger.Fatal("failed_to_create_metrics", "Failed to create metrics", &map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	shutdownTracerProvider, err := tracing.CreateTracerProvider(&tracing.TracerProviderCreationInput{
+		OtlpEndpoint: cfg.Tracing.OtlpEndpoint,
+		Insecure:     cfg.Tracing.Insecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+		Environment:  cfg.Environment,
+		Logger:       logger,
+	})
+	if err != nil {
+		app.Logger.Fatal("failed_to_create_tracer_provider", "Failed to create tracer provider", &map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	for _, sourceConfig := range cfg.Sources {
+		initialCheckpoint, hasCheckpoint := initialCheckpoints[sourceConfig.Id]
+		if !hasCheckpoint {
+			initialCheckpoint = xd_rsync.INITIAL_CHECKPOINT
+		}
+
+		source := createSource(app, sourceConfig, initialCheckpoint)
+		for key, checkpoint := range initialCheckpoints {
+			sourceId, stream, isStreamCheckpoint := strings.Cut(key, STREAM_CHECKPOINT_SEPARATOR)
+			if isStreamCheckpoint && sourceId == sourceConfig.Id {
+				source.State.SetStreamCheckpoint(stream, checkpoint)
+			}
+		}
+
+		app.Sources = append(app.Sources, source)
+	}
+
+	var schemaRegistryClient *encoders.SchemaRegistryClient
+	if cfg.Encoding.SchemaRegistry != nil {
+		schemaRegistryClient, err = encoders.CreateSchemaRegistryClient(&encoders.SchemaRegistryClientCreationInput{
+			Url:      cfg.Encoding.SchemaRegistry.Url,
+			Username: cfg.Encoding.SchemaRegistry.Username,
+			Password: cfg.Encoding.SchemaRegistry.Password,
+		})
+		if err != nil {
+			app.Logger.Fatal("failed_to_create_schema_registry_client", "Failed to create schema registry client", &map[string]interface{}{
+				"error": err,
+			})
+		}
+	}
+
+	stateStore, err := store.CreateFileStore(&store.FileStoreCreationInput{
+		Path: cfg.StateFile,
+	})
+	if err != nil {
+		app.Logger.Fatal("failed_to_create_state_store", "Failed to create state store", &map[string]interface{}{
+			"error": err,
+		})
+	} else {
+		app.Services.Store = stateStore
+	}
+
+	encoder, err := encoders.CreateEncoder(&encoders.EncoderCreationInput{
+		Format:   cfg.Encoding.Format,
+		Registry: schemaRegistryClient,
+		Logger:   logger,
+	})
+	if err != nil {
+		app.Logger.Fatal("failed_to_create_encoder", "Failed to create message encoder", &map[string]interface{}{
+			"error": err,
+		})
+	} else {
+		app.Services.Encoder = encoder
+	}
+
+	shutdown := func(ctx context.Context) {
+		err := shutdownTracerProvider(ctx)
+		if err != nil {
+			app.Logger.Error("failed_tracer_provider_shutdown", "Failed to flush pending spans", &map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+
+		app.Metrics.Close()
+		app.Logger.Flush()
+	}
+
+	return app, shutdown
+}
+
+func createSource(app *xd_rsync.XdRsyncInstance, sourceConfig *xd_rsync.SourceConfig, initialCheckpoint time.Time) *xd_rsync.XdRsyncSource {
+	source := &xd_rsync.XdRsyncSource{
+		Id: sourceConfig.Id,
+		Logger: app.Logger.WithFields(map[string]interface{}{
+			"tenantId": sourceConfig.Id,
+		}),
+		Metrics: app.Metrics.WithTenant(sourceConfig.Id),
+		State:   xd_rsync.CreateSyncState(initialCheckpoint),
+	}
+
+	dbService, err := database.CreateClient(&database.DatabaseClientCreationInput{
+		DSN:     sourceConfig.DSN,
+		Logger:  source.Logger,
+		Metrics: source.Metrics,
+	})
+	if err != nil {
+		source.Logger.Fatal("failed_to_create__client", "Failed to create  client", &map[string]interface{}{
+			"error": err,
+		})
+	} else {
+		source.Database = dbService
+	}
+
+	snsClient, err := sns.CreateClient(&sns.SNSClientCreationInput{
+		Region:  app.Config.AwsRegion,
+		Logger:  source.Logger,
+		Metrics: source.Metrics,
+	})
+	if err != nil {
+		source.Logger.Fatal("failed_to_create__client", "Failed to create  client", &map[string]interface{}{
+			"error": err,
+		})
+	} else {
+		source.SNS = snsClient
+	}
+
+	return source
+}
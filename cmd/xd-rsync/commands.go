@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/encoders"
+	"github.com/fabiofcferreira/xd-rsync/pipeline"
+	"github.com/fabiofcferreira/xd-rsync/server"
+	"github.com/fabiofcferreira/xd-rsync/tickers"
+)
+
+// Creates the flags of a command, which also accept the global -config flag after the command name
+func newCommandFlags(name string, configPath *string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(configPath, "config", *configPath, "path to the configuration file")
+
+	return flags
+}
+
+func loadCommandConfig(configPath string) (*xd_rsync.Config, bool) {
+	cfg, err := GetConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+		return nil, false
+	}
+
+	return cfg, true
+}
+
+// Parses an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration relative to now (e.g. 24h)
+func parseTimeArgument(value string) (*time.Time, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return &ts, nil
+	}
+
+	if ts, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return &ts, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		ts := time.Now().Add(-duration)
+		return &ts, nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a valid timestamp, date or duration", value)
+}
+
+// Keeps only the given source, when set
+func selectSource(cfg *xd_rsync.Config, sourceId string) bool {
+	if len(sourceId) == 0 {
+		return true
+	}
+
+	source := cfg.GetSource(sourceId)
+	if source == nil {
+		fmt.Fprintf(os.Stderr, "❌ Source '%s' was not found\n", sourceId)
+		return false
+	}
+
+	cfg.Sources = []*xd_rsync.SourceConfig{source}
+	return true
+}
+
+// Separates the source ID from the stream name in the keys of the stream checkpoints, e.g. "default/stock_movements".
+// Source IDs cannot contain it, so they never clash with a stream checkpoint.
+const STREAM_CHECKPOINT_SEPARATOR = "/"
+
+func getStreamCheckpointKey(sourceId string, stream string) string {
+	return sourceId + STREAM_CHECKPOINT_SEPARATOR + stream
+}
+
+// Reads the checkpoint of each source. Files holding a single timestamp are read as the checkpoint of the
+// default source.
+func readCheckpointFile(path string) (map[string]time.Time, error) {
+	checkpoints := map[string]time.Time{}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint file: %w", err)
+	}
+
+	checkpoint, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(content)))
+	if err == nil {
+		checkpoints[xd_rsync.DEFAULT_SOURCE_ID] = checkpoint
+		return checkpoints, nil
+	}
+
+	err = json.Unmarshal(content, &checkpoints)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse checkpoint file: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+// Replaces the checkpoint file atomically, so an interrupted write never leaves it corrupted
+func writeCheckpointFile(path string, checkpoints map[string]time.Time) error {
+	content, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not serialise checkpoints: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create checkpoint file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(append(content, '\n'))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write checkpoint file: %w", err)
+	}
+
+	err = os.Rename(tempFile.Name(), path)
+	if err != nil {
+		return fmt.Errorf("could not replace checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+func createPipelines(app *xd_rsync.XdRsyncInstance) []*pipeline.Pipeline {
+	pipelines := []*pipeline.Pipeline{}
+	for _, source := range app.Sources {
+		pipelines = append(pipelines, pipeline.CreatePipeline(&pipeline.PipelineCreationInput{
+			App:    app,
+			Source: source,
+		}))
+	}
+
+	return pipelines
+}
+
+func runDaemonCommand(configPath string, args []string) int {
+	flags := newCommandFlags("run", &configPath)
+	flags.Parse(args)
+
+	cfg, isValid := loadCommandConfig(configPath)
+	if !isValid {
+		return EXIT_CODE_INVALID_CONFIG
+	}
+
+	app, shutdownApp := createApp(cfg, nil)
+
+	schedulers := []*tickers.Scheduler{}
+	adminSources := map[string]*server.AdminSource{}
+	syncPipelines := createPipelines(app)
+	for _, syncPipeline := range syncPipelines {
+		scheduler := tickers.CreateScheduler(app.Config.SyncFrequency, syncPipeline.Run)
+		schedulers = append(schedulers, scheduler)
+		adminSources[syncPipeline.GetSource().Id] = &server.AdminSource{
+			Pipeline:  syncPipeline,
+			Scheduler: scheduler,
+		}
+	}
+
+	var httpServer *server.Server
+	if len(cfg.Http.ListenAddress) > 0 {
+		httpServer = server.CreateServer(&server.ServerCreationInput{
+			ListenAddress: cfg.Http.ListenAddress,
+			App:           app,
+		})
+		httpServer.Start()
+	}
+
+	var adminServer *server.Server
+	if len(cfg.Admin.Token) > 0 {
+		var err error
+		adminServer, err = server.CreateAdminServer(&server.AdminServerCreationInput{
+			ListenAddress: cfg.Admin.ListenAddress,
+			Token:         cfg.Admin.Token,
+			App:           app,
+			Sources:       adminSources,
+		})
+		if err != nil {
+			app.Logger.Error("failed_admin_server", "Failed to create admin server", &map[string]interface{}{
+				"error": err.Error(),
+			})
+			shutdownApp(context.Background())
+			return EXIT_CODE_FAILURE
+		}
+		adminServer.Start()
+	}
+
+	app.Logger.Info("startup_complete", "XD Rsync startup completed", &map[string]interface{}{
+		"sourcesCount": len(app.Sources),
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watchConfig(ctx, app, schedulers)
+
+	wg := sync.WaitGroup{}
+	for _, scheduler := range schedulers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scheduler.Run(ctx)
+		}()
+	}
+
+	// Promotions are watched even when disabled, so enabling them does not require a restart
+	for index, syncPipeline := range syncPipelines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			syncPipeline.WatchPromotions(ctx, schedulers[index].IsPaused)
+		}()
+	}
+	wg.Wait()
+
+	app.Logger.Info("init_shutdown", "XD Rsync shutdown", nil)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancelShutdown()
+	if httpServer != nil {
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			app.Logger.Error("failed_http_server_shutdown", "Failed to shut down HTTP server", &map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if adminServer != nil {
+		err := adminServer.Shutdown(shutdownCtx)
+		if err != nil {
+			app.Logger.Error("failed_admin_server_shutdown", "Failed to shut down admin server", &map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	shutdownApp(shutdownCtx)
+
+	return EXIT_CODE_SUCCESS
+}
+
+func runOnceCommand(configPath string, args []string) int {
+	flags := newCommandFlags("once", &configPath)
+	sourceId := flags.String("source", "", "only synchronise this source")
+	checkpointFile := flags.String("checkpoint-file", "", "file where the checkpoints are read from and stored after a successful run")
+	since := flags.String("since", "", "capture changes since this time instead of the stored checkpoint (RFC 3339, YYYY-MM-DD or a duration such as 24h)")
+	flags.Parse(args)
+
+	cfg, isValid := loadCommandConfig(configPath)
+	if !isValid {
+		return EXIT_CODE_INVALID_CONFIG
+	}
+
+	if !selectSource(cfg, *sourceId) {
+		return EXIT_CODE_USAGE
+	}
+
+	sinceCheckpoint, err := parseTimeArgument(*since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -since: %s\n", err)
+		return EXIT_CODE_USAGE
+	}
+
+	checkpoints := map[string]time.Time{}
+	if len(*checkpointFile) > 0 {
+		checkpoints, err = readCheckpointFile(*checkpointFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+			return EXIT_CODE_FAILURE
+		}
+	}
+
+	initialCheckpoints := maps.Clone(checkpoints)
+	if sinceCheckpoint != nil {
+		// Streams without a checkpoint start from the source checkpoint
+		maps.DeleteFunc(initialCheckpoints, func(key string, _ time.Time) bool {
+			return strings.Contains(key, STREAM_CHECKPOINT_SEPARATOR)
+		})
+		for _, source := range cfg.Sources {
+			initialCheckpoints[source.Id] = *sinceCheckpoint
+		}
+	}
+
+	app, shutdownApp := createApp(cfg, initialCheckpoints)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := EXIT_CODE_SUCCESS
+	for _, syncPipeline := range createPipelines(app) {
+		source := syncPipeline.GetSource()
+		errs := syncPipeline.Run(ctx)
+
+		// Checkpoints only move forward when their streams were published, even if other streams failed
+		status := source.State.GetStatus()
+		// Streams held back by a mass change share the source checkpoint again once released
+		maps.DeleteFunc(checkpoints, func(key string, _ time.Time) bool {
+			return strings.HasPrefix(key, source.Id+STREAM_CHECKPOINT_SEPARATOR)
+		})
+		for stream, checkpoint := range status.StreamCheckpoints {
+			checkpoints[getStreamCheckpointKey(source.Id, stream)] = checkpoint
+		}
+		if status.LastRun.Checkpoint.Equal(status.LastRun.StartedAt) {
+			checkpoints[source.Id] = status.Checkpoint
+		}
+
+		if len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "❌ Sync of '%s' failed: %s\n", source.Id, errors.Join(errs...))
+			exitCode = EXIT_CODE_FAILURE
+			continue
+		}
+
+		fmt.Printf("✅ Synchronised %d changed products from '%s'\n", status.LastRun.ChangedProducts, source.Id)
+	}
+
+	if len(*checkpointFile) > 0 {
+		err = writeCheckpointFile(*checkpointFile, checkpoints)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+			exitCode = EXIT_CODE_FAILURE
+		}
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancelShutdown()
+	shutdownApp(shutdownCtx)
+
+	return exitCode
+}
+
+func runResyncCommand(configPath string, args []string) int {
+	flags := newCommandFlags("resync", &configPath)
+	sourceId := flags.String("source", "", "only republish the products of this source")
+	fromValue := flags.String("from", "", "only republish products changed after this time (RFC 3339, YYYY-MM-DD or a duration such as 24h)")
+	toValue := flags.String("to", "", "only republish products changed before this time (RFC 3339, YYYY-MM-DD or a duration such as 24h)")
+	flags.Parse(args)
+
+	from, err := parseTimeArgument(*fromValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -from: %s\n", err)
+		return EXIT_CODE_USAGE
+	}
+
+	to, err := parseTimeArgument(*toValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -to: %s\n", err)
+		return EXIT_CODE_USAGE
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		fmt.Fprintln(os.Stderr, "❌ -from must be before -to")
+		return EXIT_CODE_USAGE
+	}
+
+	cfg, isValid := loadCommandConfig(configPath)
+	if !isValid {
+		return EXIT_CODE_INVALID_CONFIG
+	}
+
+	if !selectSource(cfg, *sourceId) {
+		return EXIT_CODE_USAGE
+	}
+
+	app, shutdownApp := createApp(cfg, nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := EXIT_CODE_SUCCESS
+	for _, syncPipeline := range createPipelines(app) {
+		sourceId := syncPipeline.GetSource().Id
+		publishedCount, errs := syncPipeline.Resync(ctx, from, to)
+		if len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "❌ Resync of '%s' failed after publishing %d products: %s\n", sourceId, publishedCount, errors.Join(errs...))
+			exitCode = EXIT_CODE_FAILURE
+			continue
+		}
+
+		fmt.Printf("✅ Republished %d products from '%s'\n", publishedCount, sourceId)
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancelShutdown()
+	shutdownApp(shutdownCtx)
+
+	return exitCode
+}
+
+func runProductCommand(configPath string, args []string) int {
+	flags := newCommandFlags("product", &configPath)
+	sourceId := flags.String("source", "", "source to read the product from (required when there are several)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: xd-rsync product [-source id] <sku>")
+		return EXIT_CODE_USAGE
+	}
+	sku := flags.Arg(0)
+
+	cfg, isValid := loadCommandConfig(configPath)
+	if !isValid {
+		return EXIT_CODE_INVALID_CONFIG
+	}
+
+	if !selectSource(cfg, *sourceId) {
+		return EXIT_CODE_USAGE
+	}
+
+	if len(cfg.Sources) > 1 {
+		fmt.Fprintln(os.Stderr, "❌ -source is required when there are several sources")
+		return EXIT_CODE_USAGE
+	}
+
+	app, shutdownApp := createApp(cfg, nil)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancelShutdown()
+	defer shutdownApp(shutdownCtx)
+
+	product, err := createPipelines(app)[0].PreviewProduct(context.Background(), sku)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(os.Stderr, "❌ Product '%s' was not found or is left out by the product filters\n", sku)
+		return EXIT_CODE_NOT_FOUND
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+		return EXIT_CODE_FAILURE
+	}
+
+	encodedProduct, err := app.Services.Encoder.Encode(product)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+		return EXIT_CODE_FAILURE
+	}
+
+	for key, value := range encodedProduct.Attributes {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", key, value)
+	}
+
+	if cfg.Encoding.Format != encoders.FORMAT_JSON {
+		fmt.Println(encodedProduct.Body)
+		return EXIT_CODE_SUCCESS
+	}
+
+	indentedBody := &bytes.Buffer{}
+	err = json.Indent(indentedBody, []byte(encodedProduct.Body), "", "  ")
+	if err != nil {
+		fmt.Println(encodedProduct.Body)
+		return EXIT_CODE_SUCCESS
+	}
+
+	fmt.Println(indentedBody.String())
+	return EXIT_CODE_SUCCESS
+}
+
+func runCountCommand(configPath string, args []string) int {
+	flags := newCommandFlags("count", &configPath)
+	sourceId := flags.String("source", "", "only count the products of this source")
+	sinceValue := flags.String("since", "", "count products changed since this time (RFC 3339, YYYY-MM-DD or a duration such as 24h). Defaults to the sync frequency")
+	flags.Parse(args)
+
+	cfg, isValid := loadCommandConfig(configPath)
+	if !isValid {
+		return EXIT_CODE_INVALID_CONFIG
+	}
+
+	if !selectSource(cfg, *sourceId) {
+		return EXIT_CODE_USAGE
+	}
+
+	since, err := parseTimeArgument(*sinceValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -since: %s\n", err)
+		return EXIT_CODE_USAGE
+	}
+
+	if since == nil {
+		defaultSince := time.Now().Add(-cfg.SyncFrequency)
+		since = &defaultSince
+	}
+
+	app, shutdownApp := createApp(cfg, nil)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancelShutdown()
+	defer shutdownApp(shutdownCtx)
+
+	for _, source := range app.Sources {
+		// Counted with the product filter rules of the source, as in the sync runs
+		query := &xd_rsync.EntityQuery{
+			Entity:     &xd_rsync.XdProduct{},
+			Conditions: cfg.GetSource(source.Id).Filters.ProductConditions,
+		}
+		pipeline.PRODUCTS_STREAM.ExtendQuery(cfg, query)
+		pricedProductsCount, err := source.Database.GetEntitiesCount(context.Background(), query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Could not count priced products of '%s': %s\n", source.Id, err)
+			return EXIT_CODE_FAILURE
+		}
+
+		query.ChangedAfter = since
+		changedProductsCount, err := source.Database.GetEntitiesCount(context.Background(), query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Could not count changed products of '%s': %s\n", source.Id, err)
+			return EXIT_CODE_FAILURE
+		}
+
+		if len(app.Sources) > 1 {
+			fmt.Printf("🗄️  Source: %s\n", source.Id)
+		}
+		fmt.Printf("📦 Priced products: %d\n", pricedProductsCount)
+		fmt.Printf("🔄 Changed since %s: %d\n", since.Format(time.RFC3339), changedProductsCount)
+	}
+
+	return EXIT_CODE_SUCCESS
+}
+
+func runValidateConfigCommand(configPath string, args []string) int {
+	flags := newCommandFlags("validate-config", &configPath)
+	flags.Parse(args)
+
+	_, isValid := loadCommandConfig(configPath)
+	if !isValid {
+		return EXIT_CODE_INVALID_CONFIG
+	}
+
+	return EXIT_CODE_SUCCESS
+}
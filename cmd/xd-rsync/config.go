@@ -1,80 +1,513 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/encoders"
+	"github.com/fabiofcferreira/xd-rsync/pii"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
 )
 
 var ENVIRONMENTS = []string{"development", "staging", "production"}
 
-func loadConfig() error {
-	viper.SetConfigName("config")
-	viper.SetConfigType("json")
-	viper.AddConfigPath(".")
+// Directories searched for config.json, config.yaml or config.toml when no path is given
+var CONFIG_SEARCH_PATHS = []string{".", "/etc/xd-rsync"}
+
+var SOURCE_ID_PATTERN = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+var CURRENCY_CODE_PATTERN = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Digits after the decimal point above which amounts are no longer meaningful
+const MAX_MONEY_SCALE = 8
+
+type ConfigValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ConfigValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Every problem found while validating the configuration
+type ConfigValidationErrors []ConfigValidationError
+
+func (e *ConfigValidationErrors) Add(field string, message string) {
+	*e = append(*e, ConfigValidationError{Field: field, Message: message})
+}
+
+func (e ConfigValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, "\n  - "+err.Error())
+	}
+
+	return "configuration is invalid:" + strings.Join(messages, "")
+}
+
+func loadConfig(path string) error {
+	applyConfigSchema()
+
+	if len(path) > 0 {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		for _, searchPath := range CONFIG_SEARCH_PATHS {
+			viper.AddConfigPath(searchPath)
+		}
+	}
 
 	err := viper.ReadInConfig()
 	if err != nil {
 		if _, isNotFoundError := err.(viper.ConfigFileNotFoundError); isNotFoundError {
-			panic(fmt.Errorf("config file (config.json) was not found"))
+			fmt.Fprintln(os.Stderr, "🫣 Config file not found. Using environment variables and defaults")
+			return nil
+		}
+
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("config file (%s) was not found", path)
 		}
 
-		panic(fmt.Errorf("unknown error: %w", err))
+		return fmt.Errorf("could not read config file: %w", err)
 	}
 
 	return nil
 }
 
-func GetConfig() (*xd_rsync.Config, error) {
-	err := loadConfig()
+// Reads the configuration from the given path, or from the search paths when empty.
+// Environment variables take precedence over the file.
+func GetConfig(path string) (*xd_rsync.Config, error) {
+	err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := parseConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	fmt.Fprintln(os.Stderr, "✅ Configuration validated!")
+	fmt.Fprintf(os.Stderr, "⏳ Synchronisation configured to run every %s\n", cfg.SyncFrequency.String())
+
+	return cfg, nil
+}
+
+// Reads the sources list, or creates a single source from the top-level settings when there is none
+func parseSources(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	err := viper.UnmarshalKey("sources", &cfg.Sources)
+	if err != nil {
+		errs.Add("sources", fmt.Sprintf("could not be parsed: %s", err))
+		return
+	}
+
+	isDefaultSource := len(cfg.Sources) == 0
+	if isDefaultSource {
+		if len(cfg.DSN) == 0 {
+			errs.Add("dsn", "is required")
+		}
+
+		cfg.Sources = []*xd_rsync.SourceConfig{
+			{
+				Id:     xd_rsync.DEFAULT_SOURCE_ID,
+				DSN:    cfg.DSN,
+				Queues: cfg.Queues,
+				Stock:  cfg.Stock,
+			},
+		}
+	}
+
+	sourceIds := []string{}
+	for index, source := range cfg.Sources {
+		key := fmt.Sprintf("sources[%d]", index)
+		if !SOURCE_ID_PATTERN.MatchString(source.Id) {
+			errs.Add(key+".id", fmt.Sprintf("'%s' must only contain lowercase letters, numbers, '-' and '_'", source.Id))
+		} else if slices.Contains(sourceIds, source.Id) {
+			errs.Add(key+".id", fmt.Sprintf("'%s' is used by another source", source.Id))
+		}
+		sourceIds = append(sourceIds, source.Id)
+
+		if dsn, isSet := lookupConfigEnv(key+".dsn", getSourceFieldEnvName(source.Id, "dsn"), errs); isSet {
+			source.DSN = dsn
+		}
+
+		if len(source.DSN) == 0 {
+			errs.Add(key+".dsn", "is required")
+		}
+
+		if source.Queues == nil {
+			source.Queues = &xd_rsync.QueuesConfig{}
+		}
+
+		if len(source.Queues.ProductUpdatesSnsQueueArn) == 0 {
+			fmt.Fprintf(os.Stderr, "🫣 Product updates SNS queue ARN not specified for source '%s'.\n", source.Id)
+		}
+
+		if cfg.MassChange.Action == xd_rsync.MASS_CHANGE_ACTION_BULK && len(source.Queues.ProductBulkUpdatesSnsQueueArn) == 0 {
+			queuesKey := key + ".queues"
+			if isDefaultSource {
+				queuesKey = "queues"
+			}
+			errs.Add(queuesKey+".productBulkUpdatesSnsQueueArn", "is required when massChange.action is bulk")
+		}
+
+		if source.Filters == nil {
+			source.Filters = &xd_rsync.SourceFiltersConfig{}
+		}
+
+		if source.Filters.Products == nil {
+			source.Filters.Products = cfg.ProductFilters
+			// Errors of the top-level rules were already reported
+			source.Filters.ProductConditions = compileProductFilters("productFilters", cfg.ProductFilters, &ConfigValidationErrors{})
+		} else {
+			source.Filters.ProductConditions = compileProductFilters(key+".filters.products", source.Filters.Products, errs)
+		}
+		source.Filters.ProductConditions = append(
+			source.Filters.ProductConditions,
+			xd_rsync.CompileSkuFilters(source.Filters.SkuPrefixes, source.Filters.ExcludedSkus)...,
+		)
+
+		if source.Stock == nil {
+			source.Stock = cfg.Stock
+		} else {
+			validateStock(key+".stock", source.Stock, errs)
+		}
+
+		if source.Money == nil {
+			source.Money = cfg.Money
+		} else {
+			validateMoney(key+".money", source.Money, errs)
+		}
+	}
+}
+
+// Reads the stock settings shared by the sources that do not have their own
+func parseStock(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.Stock = &xd_rsync.StockConfig{
+		SellableWarehouseIds: viper.GetStringSlice("stock.sellableWarehouseIds"),
+		Thresholds: &xd_rsync.StockThresholdsConfig{
+			OutOfStockQuantity: viper.GetFloat64("stock.thresholds.outOfStockQuantity"),
+			LowStockQuantity:   viper.GetFloat64("stock.thresholds.lowStockQuantity"),
+		},
+	}
+
+	err := viper.UnmarshalKey("stock.familyThresholds", &cfg.Stock.FamilyThresholds)
+	if err != nil {
+		errs.Add("stock.familyThresholds", fmt.Sprintf("could not be parsed: %s", err))
+		return
+	}
+
+	validateStock("stock", cfg.Stock, errs)
+}
+
+// Checks that the low stock levels are above the out of stock levels. Family codes are made lowercase, as keys are
+// case insensitive.
+func validateStock(key string, stock *xd_rsync.StockConfig, errs *ConfigValidationErrors) {
+	if stock.Thresholds == nil {
+		stock.Thresholds = &xd_rsync.StockThresholdsConfig{}
+	}
+	validateStockThresholds(key+".thresholds", stock.Thresholds, errs)
+
+	families := []string{}
+	for family := range stock.FamilyThresholds {
+		families = append(families, family)
+	}
+	slices.Sort(families)
+
+	familyThresholds := map[string]*xd_rsync.StockThresholdsConfig{}
+	for _, family := range families {
+		thresholds := stock.FamilyThresholds[family]
+		if thresholds == nil {
+			thresholds = &xd_rsync.StockThresholdsConfig{}
+		}
+
+		validateStockThresholds(key+".familyThresholds."+family, thresholds, errs)
+		familyThresholds[strings.ToLower(family)] = thresholds
+	}
+	stock.FamilyThresholds = familyThresholds
+}
+
+func validateStockThresholds(key string, thresholds *xd_rsync.StockThresholdsConfig, errs *ConfigValidationErrors) {
+	if thresholds.LowStockQuantity != 0 && thresholds.LowStockQuantity <= thresholds.OutOfStockQuantity {
+		errs.Add(key+".lowStockQuantity", fmt.Sprintf("must be above outOfStockQuantity (%g)", thresholds.OutOfStockQuantity))
+	}
+}
+
+// Reads the price guard rules, checking that the percentages are within range
+func parsePriceGuard(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.PriceGuard = &xd_rsync.PriceGuardConfig{
+		MaxDropPercentage:        viper.GetFloat64("priceGuard.maxDropPercentage"),
+		MaxRisePercentage:        viper.GetFloat64("priceGuard.maxRisePercentage"),
+		BlockBelowCost:           viper.GetBool("priceGuard.blockBelowCost"),
+		BlockCompareAtBelowPrice: viper.GetBool("priceGuard.blockCompareAtBelowPrice"),
+	}
+
+	if cfg.PriceGuard.MaxDropPercentage < 0 || cfg.PriceGuard.MaxDropPercentage > 100 {
+		errs.Add("priceGuard.maxDropPercentage", "must be between 0 and 100")
+	}
+
+	if cfg.PriceGuard.MaxRisePercentage < 0 {
+		errs.Add("priceGuard.maxRisePercentage", "must not be negative")
+	}
+}
+
+// Reads how the prices are published by the sources that do not have their own settings
+func parseMoney(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.Money = &xd_rsync.MoneyConfig{
+		Currency:         viper.GetString("money.currency"),
+		Scale:            viper.GetInt("money.scale"),
+		Format:           viper.GetString("money.format"),
+		PricesIncludeVat: viper.GetBool("money.pricesIncludeVat"),
+	}
+
+	validateMoney("money", cfg.Money, errs)
+}
+
+func validateMoney(key string, money *xd_rsync.MoneyConfig, errs *ConfigValidationErrors) {
+	if !CURRENCY_CODE_PATTERN.MatchString(money.Currency) {
+		errs.Add(key+".currency", fmt.Sprintf("'%s' must be an ISO 4217 code, e.g. EUR", money.Currency))
+	}
+
+	if money.Scale < 0 || money.Scale > MAX_MONEY_SCALE {
+		errs.Add(key+".scale", fmt.Sprintf("must be between 0 and %d", MAX_MONEY_SCALE))
+	}
+
+	if !slices.Contains(xd_rsync.MONEY_FORMATS, money.Format) {
+		errs.Add(key+".format", fmt.Sprintf("'%s' is not supported", money.Format))
+	}
+}
+
+// Reads the limits above which a run is a mass change, and what is done with it
+func parseMassChange(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.MassChange = &xd_rsync.MassChangeConfig{
+		MaxChangedCount:      viper.GetInt("massChange.maxChangedCount"),
+		MaxChangedPercentage: viper.GetFloat64("massChange.maxChangedPercentage"),
+		Action:               viper.GetString("massChange.action"),
+		BulkBatchSize:        viper.GetInt("massChange.bulkBatchSize"),
+		BulkBatchInterval:    parseDurationSetting("massChange.bulkBatchInterval", errs),
+	}
+
+	if cfg.MassChange.MaxChangedCount < 0 {
+		errs.Add("massChange.maxChangedCount", "must not be negative")
+	}
+
+	if cfg.MassChange.MaxChangedPercentage < 0 || cfg.MassChange.MaxChangedPercentage > 100 {
+		errs.Add("massChange.maxChangedPercentage", "must be between 0 and 100")
+	}
+
+	if !slices.Contains(xd_rsync.MASS_CHANGE_ACTIONS, cfg.MassChange.Action) {
+		errs.Add("massChange.action", fmt.Sprintf("'%s' is not supported", cfg.MassChange.Action))
+	}
+
+	if cfg.MassChange.BulkBatchSize <= 0 {
+		errs.Add("massChange.bulkBatchSize", "must be above 0")
+	}
+}
+
+// Reads the rules selecting the synchronised products, which only select priced products when they are not set
+func parseProductFilters(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.ProductFilters = xd_rsync.DEFAULT_PRODUCT_FILTER_RULES
+	if viper.IsSet("productFilters") {
+		cfg.ProductFilters = []*xd_rsync.ProductFilterRule{}
+		err := viper.UnmarshalKey("productFilters", &cfg.ProductFilters)
+		if err != nil {
+			errs.Add("productFilters", fmt.Sprintf("could not be parsed: %s", err))
+			return
+		}
+	}
+
+	compileProductFilters("productFilters", cfg.ProductFilters, errs)
+}
+
+// Reads the price lists published besides the retail prices, checking that each one has a single source of prices
+func parsePriceLists(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.PriceLists = []*xd_rsync.PriceListConfig{}
+	err := viper.UnmarshalKey("priceLists", &cfg.PriceLists)
+	if err != nil {
+		errs.Add("priceLists", fmt.Sprintf("could not be parsed: %s", err))
+		return
+	}
+
+	names := []string{}
+	for index, priceList := range cfg.PriceLists {
+		key := fmt.Sprintf("priceLists[%d]", index)
+		if priceList == nil {
+			errs.Add(key, "must not be empty")
+			continue
+		}
+
+		if !xd_rsync.PRICE_LIST_NAME_PATTERN.MatchString(priceList.Name) {
+			errs.Add(key+".name", fmt.Sprintf("'%s' must start with a letter and only contain letters, numbers, '-' and '_'", priceList.Name))
+		} else if slices.Contains(names, priceList.Name) {
+			errs.Add(key+".name", fmt.Sprintf("'%s' is used by another price list", priceList.Name))
+		}
+		names = append(names, priceList.Name)
+
+		hasColumn := len(priceList.Column) > 0
+		if hasColumn == priceList.IsPriceTable() {
+			errs.Add(key, "must have either a column or a priceTableId")
+		} else if _, err := priceList.GetColumn(); hasColumn && err != nil {
+			errs.Add(key+".column", fmt.Sprintf("'%s' is not a valid column name", priceList.Column))
+		}
+	}
+}
+
+// Compiles the product filter rules into SQL conditions, checking that every rule is valid
+func compileProductFilters(key string, rules []*xd_rsync.ProductFilterRule, errs *ConfigValidationErrors) []xd_rsync.Condition {
+	conditions := []xd_rsync.Condition{}
+	for index, rule := range rules {
+		ruleKey := fmt.Sprintf("%s[%d]", key, index)
+		if rule == nil {
+			errs.Add(ruleKey, "must not be empty")
+			continue
+		}
+
+		condition, err := rule.Compile()
+		if err != nil {
+			errs.Add(ruleKey, err.Error())
+			continue
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return conditions
+}
+
+// Reads the personal data policies, checking that they only refer to personal data fields of the published events
+func parsePii(cfg *xd_rsync.Config, errs *ConfigValidationErrors) {
+	cfg.Pii = &xd_rsync.PiiConfig{
+		HashKey: viper.GetString("pii.hashKey"),
+	}
+
+	err := viper.UnmarshalKey("pii.sinks", &cfg.Pii.Sinks)
+	if err != nil {
+		errs.Add("pii.sinks", fmt.Sprintf("could not be parsed: %s", err))
+		return
+	}
+
+	knownFields := []string{}
+	hashableFields := []string{}
+	for _, event := range PUBLISHED_EVENTS {
+		knownFields = append(knownFields, pii.GetFields(event.record)...)
+		hashableFields = append(hashableFields, pii.GetHashableFields(event.record)...)
+	}
+
+	sinks := []string{}
+	for sink := range cfg.Pii.Sinks {
+		sinks = append(sinks, sink)
+	}
+	slices.Sort(sinks)
+
+	for _, sink := range sinks {
+		sinkConfig := cfg.Pii.Sinks[sink]
+		key := "pii.sinks." + sink
+		if !slices.Contains(pii.SUPPORTED_SINKS, sink) {
+			errs.Add(key, fmt.Sprintf("'%s' is not a supported sink", sink))
+			continue
+		}
+
+		if sinkConfig == nil {
+			cfg.Pii.Sinks[sink] = &xd_rsync.PiiSinkConfig{}
+			continue
+		}
+
+		for _, field := range append(sinkConfig.HashedFields, sinkConfig.OmittedFields...) {
+			if !slices.Contains(knownFields, field) {
+				errs.Add(key, fmt.Sprintf("'%s' is not a personal data field", field))
+			}
+		}
+
+		for _, field := range sinkConfig.HashedFields {
+			if slices.Contains(sinkConfig.OmittedFields, field) {
+				errs.Add(key, fmt.Sprintf("'%s' cannot be both hashed and omitted", field))
+			} else if slices.Contains(knownFields, field) && !slices.Contains(hashableFields, field) {
+				errs.Add(key, fmt.Sprintf("'%s' is not text, so it can only be omitted", field))
+			}
+		}
+
+		if len(sinkConfig.HashedFields) > 0 && len(cfg.Pii.HashKey) == 0 {
+			errs.Add("pii.hashKey", "is required to hash personal data fields")
+		}
+	}
+}
+
+func parseDurationSetting(key string, errs *ConfigValidationErrors) time.Duration {
+	value := viper.GetString(key)
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		errs.Add(key, fmt.Sprintf("'%s' is not a valid duration", value))
+	}
+
+	return duration
+}
+
+// Builds the configuration from the loaded settings, collecting every validation error
+func parseConfig() (*xd_rsync.Config, error) {
+	errs := applyConfigFileEnvs()
+
 	cfg := &xd_rsync.Config{
 		Queues:        &xd_rsync.QueuesConfig{},
 		DatadogConfig: &xd_rsync.DatadogConfig{},
+		Encoding:      &xd_rsync.EncodingConfig{},
+		Http:          &xd_rsync.HttpConfig{},
+		Health:        &xd_rsync.HealthConfig{},
+		Admin:         &xd_rsync.AdminConfig{},
+		Tracing:       &xd_rsync.TracingConfig{},
 	}
 
 	environment := viper.GetString("environment")
 	if len(environment) == 0 {
-		return nil, fmt.Errorf("environment not specified")
-	}
-
-	if environment != "development" && environment != "staging" && environment != "production" {
-		return nil, fmt.Errorf("environment '%s' not supported", environment)
+		errs.Add("environment", "is required")
+	} else if !slices.Contains(ENVIRONMENTS, environment) {
+		errs.Add("environment", fmt.Sprintf("'%s' is not supported", environment))
 	}
 
 	cfg.Environment = environment
 	cfg.IsProductionMode = environment == "staging" || environment == "production"
 
-	awsRegion := viper.GetString("awsRegion")
-	if len(awsRegion) == 0 {
-		fmt.Println("🫣 AWS region not specified. Defaulting to 'eu-west-2'")
+	cfg.LogLevel = viper.GetString("logLevel")
+	if len(cfg.LogLevel) > 0 {
+		_, err := zapcore.ParseLevel(cfg.LogLevel)
+		if err != nil {
+			errs.Add("logLevel", fmt.Sprintf("'%s' is not a valid level", cfg.LogLevel))
+		}
 	}
-	cfg.AwsRegion = awsRegion
 
-	dsn := viper.GetString("dsn")
-	if len(dsn) == 0 {
-		return nil, fmt.Errorf("database URI not specified")
-	}
-	cfg.DSN = dsn
+	cfg.AwsRegion = viper.GetString("awsRegion")
 
-	productUpdatesSnsArn := viper.GetString("queues.productUpdatesSnsQueueArn")
-	if len(productUpdatesSnsArn) == 0 {
-		fmt.Println("🫣 Product updates SNS queue ARN not specified.")
+	cfg.DSN = viper.GetString("dsn")
+	cfg.Queues.ProductUpdatesSnsQueueArn = viper.GetString("queues.productUpdatesSnsQueueArn")
+	cfg.Queues.CustomerUpdatesSnsQueueArn = viper.GetString("queues.customerUpdatesSnsQueueArn")
+	cfg.Queues.SalesDocumentUpdatesSnsQueueArn = viper.GetString("queues.salesDocumentUpdatesSnsQueueArn")
+	cfg.Queues.StockMovementsSnsQueueArn = viper.GetString("queues.stockMovementsSnsQueueArn")
+	cfg.Queues.StockTransitionsSnsQueueArn = viper.GetString("queues.stockTransitionsSnsQueueArn")
+	cfg.Queues.PriceAlertsSnsQueueArn = viper.GetString("queues.priceAlertsSnsQueueArn")
+	cfg.Queues.ProductBulkUpdatesSnsQueueArn = viper.GetString("queues.productBulkUpdatesSnsQueueArn")
+	parseStock(cfg, &errs)
+	parsePriceGuard(cfg, &errs)
+	parseMassChange(cfg, &errs)
+	parseMoney(cfg, &errs)
+	parseProductFilters(cfg, &errs)
+	parsePriceLists(cfg, &errs)
+	cfg.Promotions = &xd_rsync.PromotionsConfig{
+		Enabled: viper.GetBool("promotions.enabled"),
 	}
-	cfg.Queues.ProductUpdatesSnsQueueArn = productUpdatesSnsArn
+	parseSources(cfg, &errs)
 
-	parsedSyncFrequency, err := time.ParseDuration(viper.GetString("syncFrequency"))
-	if err == nil {
-		cfg.SyncFrequency = parsedSyncFrequency
-	} else {
-		cfg.SyncFrequency = 5 * time.Minute
-		fmt.Println("🫣 Sync frequency is invalid. Defaulting to 5 minutes")
-	}
+	cfg.StateFile = viper.GetString("stateFile")
+	cfg.SyncFrequency = parseDurationSetting("syncFrequency", &errs)
+	cfg.ReplicationLagSlo = parseDurationSetting("replicationLagSlo", &errs)
 
 	ingestHost := viper.GetString("datadog.ingestHost")
 	if len(ingestHost) > 0 {
@@ -86,6 +519,11 @@ func GetConfig() (*xd_rsync.Config, error) {
 		cfg.DatadogConfig.ApiKey = &datadogApiKey
 	}
 
+	statsdAddress := viper.GetString("datadog.statsdAddress")
+	if len(statsdAddress) > 0 {
+		cfg.DatadogConfig.StatsdAddress = &statsdAddress
+	}
+
 	parsedEventBaseFields := viper.GetStringMapString("datadog.eventBaseFields")
 	cfg.DatadogConfig.EventBaseFields = &map[string]interface{}{
 		"app_name":    "xd_rsync",
@@ -96,8 +534,56 @@ func GetConfig() (*xd_rsync.Config, error) {
 		(*cfg.DatadogConfig.EventBaseFields)[key] = value
 	}
 
-	fmt.Println("✅ Configuration validated!")
-	fmt.Printf("⏳ Synchronisation configured to run every %s\n", cfg.SyncFrequency.String())
+	cfg.Encoding.Format = viper.GetString("encoding.format")
+	if !slices.Contains(encoders.SUPPORTED_FORMATS, cfg.Encoding.Format) {
+		errs.Add("encoding.format", fmt.Sprintf("'%s' is not supported", cfg.Encoding.Format))
+	}
+
+	schemaRegistryUrl := viper.GetString("encoding.schemaRegistry.url")
+	if len(schemaRegistryUrl) > 0 {
+		cfg.Encoding.SchemaRegistry = &xd_rsync.SchemaRegistryConfig{
+			Url:      schemaRegistryUrl,
+			Username: viper.GetString("encoding.schemaRegistry.username"),
+			Password: viper.GetString("encoding.schemaRegistry.password"),
+		}
+	}
+
+	cfg.Http.ListenAddress = viper.GetString("http.listenAddress")
+	if len(cfg.Http.ListenAddress) == 0 {
+		fmt.Fprintln(os.Stderr, "🫣 HTTP listen address not specified. Metrics and health endpoints are disabled")
+	}
+
+	maxMissedRuns, err := cast.ToIntE(viper.Get("health.maxMissedRuns"))
+	if err != nil || maxMissedRuns <= 0 {
+		errs.Add("health.maxMissedRuns", "must be a positive number")
+	}
+	cfg.Health.MaxMissedRuns = maxMissedRuns
+
+	cfg.Admin.Token = viper.GetString("admin.token")
+	cfg.Admin.ListenAddress = viper.GetString("admin.listenAddress")
+	if len(cfg.Admin.Token) == 0 {
+		fmt.Fprintln(os.Stderr, "🫣 Admin token not specified. Admin API is disabled")
+	}
+
+	cfg.Tracing.OtlpEndpoint = viper.GetString("tracing.otlpEndpoint")
+
+	isTracingInsecure, err := cast.ToBoolE(viper.Get("tracing.insecure"))
+	if err != nil {
+		errs.Add("tracing.insecure", "must be true or false")
+	}
+	cfg.Tracing.Insecure = isTracingInsecure
+
+	sampleRatio, err := cast.ToFloat64E(viper.Get("tracing.sampleRatio"))
+	if err != nil || sampleRatio < 0 || sampleRatio > 1 {
+		errs.Add("tracing.sampleRatio", "must be a number between 0 and 1")
+	}
+	cfg.Tracing.SampleRatio = sampleRatio
+
+	parsePii(cfg, &errs)
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
 
 	return cfg, nil
 }
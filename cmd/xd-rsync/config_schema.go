@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+const CONFIG_ENV_PREFIX = "XDRSYNC"
+
+// Suffix of the environment variables pointing to a file holding the value, e.g. XDRSYNC_DSN_FILE
+const CONFIG_FILE_ENV_SUFFIX = "_FILE"
+
+type configField struct {
+	Key         string
+	Default     interface{}
+	Description string
+	IsSecret    bool
+}
+
+// Single source of the supported settings and their defaults, documented in the README
+var CONFIG_SCHEMA = []configField{
+	{Key: "environment", Description: "Environment name: development, staging or production (required)"},
+	{Key: "logLevel", Description: "Minimum level of the logged events: debug, info, warn or error. Defaults to info in staging and production, and debug otherwise"},
+	{Key: "awsRegion", Default: "eu-west-2", Description: "AWS region of the SNS topics"},
+	{Key: "dsn", Description: "XD database connection string (required)", IsSecret: true},
+	{Key: "queues.productUpdatesSnsQueueArn", Description: "SNS topic ARN where product updates are published"},
+	{Key: "queues.customerUpdatesSnsQueueArn", Description: "SNS topic ARN where customer updates are published. Leave empty to disable them"},
+	{Key: "queues.salesDocumentUpdatesSnsQueueArn", Description: "SNS topic ARN where sales documents are published. Leave empty to disable them"},
+	{Key: "queues.stockMovementsSnsQueueArn", Description: "SNS topic ARN where stock movements are published. Leave empty to disable them"},
+	{Key: "queues.stockTransitionsSnsQueueArn", Description: "SNS topic ARN where stock transitions are published. Leave empty to disable them"},
+	{Key: "queues.priceAlertsSnsQueueArn", Description: "SNS topic ARN where alerts about products quarantined by the price guard are published. Leave empty to only log them"},
+	{Key: "queues.productBulkUpdatesSnsQueueArn", Description: "SNS topic ARN where product updates are published, throttled, when a run is a mass change and massChange.action is bulk"},
+	{Key: "stock.sellableWarehouseIds", Description: "Warehouses whose stock counts towards the available quantity of the products. Every warehouse counts when empty"},
+	{Key: "stock.thresholds.outOfStockQuantity", Default: 0, Description: "Available quantity at or below which products are out of stock"},
+	{Key: "stock.thresholds.lowStockQuantity", Default: 0, Description: "Available quantity at or below which products are low on stock. Disabled when 0"},
+	{Key: "priceGuard.maxDropPercentage", Default: 0, Description: "Maximum drop of a product base price since it was last published, in percentage. Disabled when 0"},
+	{Key: "priceGuard.maxRisePercentage", Default: 0, Description: "Maximum rise of a product base price since it was last published, in percentage. Disabled when 0"},
+	{Key: "priceGuard.blockBelowCost", Default: false, Description: "Quarantines products priced below their cost"},
+	{Key: "priceGuard.blockCompareAtBelowPrice", Default: false, Description: "Quarantines products whose compare-at price is below their price"},
+	{Key: "massChange.maxChangedCount", Default: 0, Description: "Changed products above which a run is a mass change. Disabled when 0"},
+	{Key: "massChange.maxChangedPercentage", Default: 0, Description: "Share of the synchronised products changed above which a run is a mass change, in percentage. Disabled when 0"},
+	{Key: "massChange.action", Default: "hold", Description: "What is done with a mass change: hold, until it is confirmed through the admin API, or bulk, publishing it to the bulk topic"},
+	{Key: "massChange.bulkBatchSize", Default: 100, Description: "Products published at a time to the bulk topic"},
+	{Key: "massChange.bulkBatchInterval", Default: "1s", Description: "Time between the batches published to the bulk topic"},
+	{Key: "money.currency", Default: "EUR", Description: "ISO 4217 code of the currency of the XD prices"},
+	{Key: "money.scale", Default: 2, Description: "Digits after the decimal point of the published prices"},
+	{Key: "money.format", Default: "string", Description: "How the published prices are written in JSON: string, e.g. \"19.90\", or number, e.g. 19.90"},
+	{Key: "money.pricesIncludeVat", Default: true, Description: "Whether the XD retail prices include VAT"},
+	{Key: "promotions.enabled", Default: false, Description: "Publishes the active promotion of each product, and publishes products again when their promotion starts or ends"},
+	{Key: "stateFile", Description: "File where the state kept between runs is stored, e.g. the last published stock levels. Leave empty to only keep it in memory"},
+	{Key: "syncFrequency", Default: "5m", Description: "Time between sync runs"},
+	{Key: "replicationLagSlo", Default: "15m", Description: "Maximum expected time between a change in XD and its publication"},
+	{Key: "datadog.ingestHost", Description: "Datadog logs ingest host. Leave empty to disable log shipping"},
+	{Key: "datadog.apiKey", Description: "Datadog API key", IsSecret: true},
+	{Key: "datadog.statsdAddress", Description: "DogStatsD agent address to push metrics to"},
+	{Key: "encoding.format", Default: "json", Description: "Message encoding: json, protobuf or avro"},
+	{Key: "encoding.schemaRegistry.url", Description: "Confluent-compatible schema registry URL"},
+	{Key: "encoding.schemaRegistry.username", Description: "Schema registry username"},
+	{Key: "encoding.schemaRegistry.password", Description: "Schema registry password", IsSecret: true},
+	{Key: "http.listenAddress", Description: "Address of the metrics and health endpoints. Leave empty to disable them"},
+	{Key: "health.maxMissedRuns", Default: 3, Description: "Sync runs that can be missed before the service is no longer ready"},
+	{Key: "admin.listenAddress", Default: "127.0.0.1:9091", Description: "Address of the admin API"},
+	{Key: "admin.token", Description: "Bearer token required by the admin API. Leave empty to disable it", IsSecret: true},
+	{Key: "tracing.otlpEndpoint", Description: "OTLP/HTTP collector endpoint (host:port). Leave empty to disable tracing"},
+	{Key: "tracing.insecure", Default: false, Description: "Send spans over plain HTTP instead of HTTPS"},
+	{Key: "tracing.sampleRatio", Default: 1.0, Description: "Ratio of sync runs to trace, between 0 and 1"},
+	{Key: "pii.hashKey", Description: "Secret key of the HMAC-SHA256 hashes of personal data fields", IsSecret: true},
+}
+
+// Converts a config key into its environment variable, e.g. datadog.apiKey becomes XDRSYNC_DATADOG_API_KEY
+func getConfigFieldEnvName(key string) string {
+	envName := strings.Builder{}
+	envName.WriteString(CONFIG_ENV_PREFIX + "_")
+
+	var previous rune
+	for _, char := range key {
+		if char == '.' {
+			envName.WriteRune('_')
+		} else if unicode.IsUpper(char) && unicode.IsLower(previous) {
+			envName.WriteRune('_')
+			envName.WriteRune(char)
+		} else {
+			envName.WriteRune(unicode.ToUpper(char))
+		}
+
+		previous = char
+	}
+
+	return envName.String()
+}
+
+// Registers the defaults and environment variables of every setting
+func applyConfigSchema() {
+	for _, field := range CONFIG_SCHEMA {
+		if field.Default != nil {
+			viper.SetDefault(field.Key, field.Default)
+		}
+
+		viper.BindEnv(field.Key, getConfigFieldEnvName(field.Key))
+	}
+}
+
+// Reads the value of an environment variable, or of the file given by its _FILE variant
+func lookupConfigEnv(key string, envName string, errs *ConfigValidationErrors) (string, bool) {
+	filePath, isFileSet := os.LookupEnv(envName + CONFIG_FILE_ENV_SUFFIX)
+	value, isValueSet := os.LookupEnv(envName)
+	if !isFileSet {
+		return value, isValueSet
+	}
+
+	if isValueSet {
+		errs.Add(key, fmt.Sprintf("both %s and %s%s are set", envName, envName, CONFIG_FILE_ENV_SUFFIX))
+		return "", false
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		errs.Add(key, fmt.Sprintf("could not read %s%s: %s", envName, CONFIG_FILE_ENV_SUFFIX, err))
+		return "", false
+	}
+
+	return strings.TrimSpace(string(content)), true
+}
+
+// Reads the settings whose value is stored in a file, such as a mounted secret
+func applyConfigFileEnvs() ConfigValidationErrors {
+	errs := ConfigValidationErrors{}
+	for _, field := range CONFIG_SCHEMA {
+		envName := getConfigFieldEnvName(field.Key)
+		if _, isFileSet := os.LookupEnv(envName + CONFIG_FILE_ENV_SUFFIX); !isFileSet {
+			continue
+		}
+
+		value, isSet := lookupConfigEnv(field.Key, envName, &errs)
+		if isSet {
+			viper.Set(field.Key, value)
+		}
+	}
+
+	return errs
+}
+
+// Converts a source setting into its environment variable, e.g. the DSN of the "north-shop" source becomes
+// XDRSYNC_SOURCES_NORTH_SHOP_DSN
+func getSourceFieldEnvName(sourceId string, key string) string {
+	return getConfigFieldEnvName("sources." + strings.ReplaceAll(sourceId, "-", "_") + "." + key)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/spf13/viper"
+)
+
+const TEST_CONFIG_YAML = `
+environment: development
+dsn: user:password@tcp(localhost:3306)/xd
+queues:
+  productUpdatesSnsQueueArn: arn:aws:sns:eu-west-1:000000000000:products.fifo
+`
+
+// Reads the configuration from a file with the given name and content, as on startup
+func loadTestConfig(t *testing.T, name string, content string) (*xd_rsync.Config, error) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+
+	return GetConfig(path)
+}
+
+// Returns the fields of the validation errors, failing when the error is not a validation error
+func getValidationErrorFields(t *testing.T, err error) []string {
+	validationErrs := ConfigValidationErrors{}
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	fields := []string{}
+	for _, validationErr := range validationErrs {
+		fields = append(fields, validationErr.Field)
+	}
+
+	return fields
+}
+
+func TestConfigRejectsNonScalarProductFilterValues(t *testing.T) {
+	_, err := loadTestConfig(t, "config.yaml", TEST_CONFIG_YAML+`
+productFilters:
+  - field: price
+    operator: gt
+    value: 0
+  - field: family
+    operator: eq
+    value: [DRINKS, FOOD]
+  - field: family
+    operator: in
+    values: [DRINKS, [FOOD]]
+  - field: Brand
+    operator: notIn
+    values: [{name: ACME}]
+`)
+
+	fields := getValidationErrorFields(t, err)
+	expectedFields := []string{"productFilters[1]", "productFilters[2]", "productFilters[3]"}
+	if !slices.Equal(fields, expectedFields) {
+		t.Errorf("expected errors of %v, got %v", expectedFields, fields)
+	}
+}
+
+func TestConfigRejectsPriceListColumnsThatAreNotIdentifiers(t *testing.T) {
+	_, err := loadTestConfig(t, "config.yaml", TEST_CONFIG_YAML+`
+priceLists:
+  - name: wholesale
+    column: RetailPrice3
+  - name: resellers
+    column: "RetailPrice4 FROM items; DROP TABLE items; --"
+  - name: outlet
+    column: i.RetailPrice5
+`)
+
+	fields := getValidationErrorFields(t, err)
+	expectedFields := []string{"priceLists[1].column", "priceLists[2].column"}
+	if !slices.Equal(fields, expectedFields) {
+		t.Errorf("expected errors of %v, got %v", expectedFields, fields)
+	}
+}
+
+func TestLookupConfigEnv(t *testing.T) {
+	secretPath := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(secretPath, []byte("  s3cret\n\n"), 0600)
+	if err != nil {
+		t.Fatalf("could not write secret file: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		envs          map[string]string
+		expectedValue string
+		expectedIsSet bool
+		expectedErrs  int
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:          "value",
+			envs:          map[string]string{"XDRSYNC_TEST_SECRET": "value"},
+			expectedValue: "value",
+			expectedIsSet: true,
+		},
+		{
+			name:          "empty value",
+			envs:          map[string]string{"XDRSYNC_TEST_SECRET": ""},
+			expectedIsSet: true,
+		},
+		{
+			name:          "file trimming the trailing newlines",
+			envs:          map[string]string{"XDRSYNC_TEST_SECRET_FILE": secretPath},
+			expectedValue: "s3cret",
+			expectedIsSet: true,
+		},
+		{
+			name:         "unreadable file",
+			envs:         map[string]string{"XDRSYNC_TEST_SECRET_FILE": filepath.Join(t.TempDir(), "missing")},
+			expectedErrs: 1,
+		},
+		{
+			name:         "both value and file",
+			envs:         map[string]string{"XDRSYNC_TEST_SECRET": "value", "XDRSYNC_TEST_SECRET_FILE": secretPath},
+			expectedErrs: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.envs {
+				t.Setenv(name, value)
+			}
+
+			errs := ConfigValidationErrors{}
+			value, isSet := lookupConfigEnv("test.secret", "XDRSYNC_TEST_SECRET", &errs)
+			if value != test.expectedValue || isSet != test.expectedIsSet {
+				t.Errorf("expected '%s' set %v, got '%s' set %v", test.expectedValue, test.expectedIsSet, value, isSet)
+			}
+
+			if len(errs) != test.expectedErrs {
+				t.Errorf("expected %d errors, got %v", test.expectedErrs, errs)
+			}
+			for _, err := range errs {
+				if err.Field != "test.secret" {
+					t.Errorf("expected the error to be reported on test.secret, got %s", err.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	dsnPath := filepath.Join(dir, "dsn")
+	err := os.WriteFile(dsnPath, []byte("user:secret@tcp(db:3306)/xd\n"), 0600)
+	if err != nil {
+		t.Fatalf("could not write secret file: %v", err)
+	}
+
+	t.Setenv("XDRSYNC_AWS_REGION", "eu-west-1")
+	t.Setenv("XDRSYNC_SYNC_FREQUENCY", "30s")
+	t.Setenv("XDRSYNC_DSN_FILE", dsnPath)
+	t.Setenv("XDRSYNC_ADMIN_TOKEN", "token")
+
+	cfg, err := loadTestConfig(t, "config.yaml", TEST_CONFIG_YAML+`
+awsRegion: eu-central-1
+syncFrequency: 1m
+`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	settings := map[string][2]string{
+		"awsRegion":     {"eu-west-1", cfg.AwsRegion},
+		"syncFrequency": {"30s", cfg.SyncFrequency.String()},
+		"dsn":           {"user:secret@tcp(db:3306)/xd", cfg.DSN},
+		"admin.token":   {"token", cfg.Admin.Token},
+	}
+	for key, setting := range settings {
+		if setting[0] != setting[1] {
+			t.Errorf("expected %s %s, got %s", key, setting[0], setting[1])
+		}
+	}
+}
+
+func TestConfigRejectsUnreadableSecretFiles(t *testing.T) {
+	t.Setenv("XDRSYNC_DATADOG_API_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("XDRSYNC_ADMIN_TOKEN", "token")
+	t.Setenv("XDRSYNC_ADMIN_TOKEN_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := loadTestConfig(t, "config.yaml", TEST_CONFIG_YAML)
+
+	fields := getValidationErrorFields(t, err)
+	expectedFields := []string{"datadog.apiKey", "admin.token"}
+	if !slices.Equal(fields, expectedFields) {
+		t.Errorf("expected errors of %v, got %v", expectedFields, fields)
+	}
+}
+
+func TestConfigFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "config.yaml",
+			content: `
+environment: production
+dsn: user:password@tcp(localhost:3306)/xd
+syncFrequency: 2m
+queues:
+  productUpdatesSnsQueueArn: arn:aws:sns:eu-west-1:000000000000:products.fifo
+stock:
+  sellableWarehouseIds: ["1", "2"]
+`,
+		},
+		{
+			name: "config.toml",
+			content: `
+environment = "production"
+dsn = "user:password@tcp(localhost:3306)/xd"
+syncFrequency = "2m"
+
+[queues]
+productUpdatesSnsQueueArn = "arn:aws:sns:eu-west-1:000000000000:products.fifo"
+
+[stock]
+sellableWarehouseIds = ["1", "2"]
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := loadTestConfig(t, test.name, test.content)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !cfg.IsProductionMode || cfg.DSN != "user:password@tcp(localhost:3306)/xd" || cfg.SyncFrequency.String() != "2m0s" {
+				t.Errorf("expected the top-level settings to be read, got %s, %s and %s", cfg.Environment, cfg.DSN, cfg.SyncFrequency)
+			}
+
+			if cfg.Queues.ProductUpdatesSnsQueueArn != "arn:aws:sns:eu-west-1:000000000000:products.fifo" {
+				t.Errorf("expected the products queue to be read, got %s", cfg.Queues.ProductUpdatesSnsQueueArn)
+			}
+
+			if cfg.Stock == nil || !slices.Equal(cfg.Stock.SellableWarehouseIds, []string{"1", "2"}) {
+				t.Errorf("expected the sellable warehouses to be read, got %+v", cfg.Stock)
+			}
+		})
+	}
+}
+
+func TestConfigReportsEveryValidationError(t *testing.T) {
+	_, err := loadTestConfig(t, "config.yaml", `
+environment: qa
+logLevel: loud
+syncFrequency: often
+productFilters:
+  - field: family
+    operator: like
+    value: DRINKS
+`)
+
+	fields := getValidationErrorFields(t, err)
+	expectedFields := []string{"environment", "logLevel", "dsn", "syncFrequency", "productFilters[0]"}
+	for _, field := range expectedFields {
+		if !slices.Contains(fields, field) {
+			t.Errorf("expected an error of %s, got %v", field, fields)
+		}
+	}
+
+	for _, field := range expectedFields {
+		if !strings.Contains(err.Error(), "\n  - "+field+": ") {
+			t.Errorf("expected the message to list %s, got %s", field, err)
+		}
+	}
+}
+
+func TestConfigSchemaIsDocumentedInOrder(t *testing.T) {
+	readme, err := os.ReadFile(filepath.Join("..", "..", "README.MD"))
+	if err != nil {
+		t.Fatalf("could not read README: %v", err)
+	}
+
+	previousIndex := -1
+	for _, field := range CONFIG_SCHEMA {
+		index := strings.Index(string(readme), "| `"+field.Key+"` ")
+		if index < 0 {
+			t.Errorf("expected %s to be documented", field.Key)
+			continue
+		}
+
+		if index < previousIndex {
+			t.Errorf("expected %s to be documented in the order of the schema", field.Key)
+		}
+		previousIndex = index
+	}
+}
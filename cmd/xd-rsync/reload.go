@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync"
+	"syscall"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/tickers"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+type configReloader struct {
+	app        *xd_rsync.XdRsyncInstance
+	schedulers []*tickers.Scheduler
+	// Viper is not safe for concurrent use, and reloads can be requested by file changes and signals at once
+	mutex sync.Mutex
+}
+
+// Settings that can only be changed by restarting, as the services using them are created on startup
+func getRestartRequiredChanges(current *xd_rsync.Config, updated *xd_rsync.Config) []string {
+	changes := []string{}
+	if current.Environment != updated.Environment {
+		changes = append(changes, "environment")
+	}
+
+	if current.AwsRegion != updated.AwsRegion {
+		changes = append(changes, "awsRegion")
+	}
+
+	if !slices.Equal(getSourceIds(current), getSourceIds(updated)) {
+		changes = append(changes, "sources")
+	} else {
+		for index, source := range updated.Sources {
+			if source.DSN != current.Sources[index].DSN {
+				changes = append(changes, "sources."+source.Id+".dsn")
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(current.DatadogConfig.StatsdAddress, updated.DatadogConfig.StatsdAddress) {
+		changes = append(changes, "datadog.statsdAddress")
+	}
+
+	if !reflect.DeepEqual(current.Encoding, updated.Encoding) {
+		changes = append(changes, "encoding")
+	}
+
+	if *current.Http != *updated.Http {
+		changes = append(changes, "http")
+	}
+
+	if *current.Admin != *updated.Admin {
+		changes = append(changes, "admin")
+	}
+
+	if *current.Tracing != *updated.Tracing {
+		changes = append(changes, "tracing")
+	}
+
+	if current.StateFile != updated.StateFile {
+		changes = append(changes, "stateFile")
+	}
+
+	return changes
+}
+
+func getSourceIds(cfg *xd_rsync.Config) []string {
+	sourceIds := []string{}
+	for _, source := range cfg.Sources {
+		sourceIds = append(sourceIds, source.Id)
+	}
+
+	return sourceIds
+}
+
+// Keeps the current value of the settings that require a restart. Sources are only kept as a whole when they
+// were added, removed or renamed.
+func keepRestartRequiredSettings(current *xd_rsync.Config, updated *xd_rsync.Config) {
+	updated.Environment = current.Environment
+	updated.IsProductionMode = current.IsProductionMode
+	updated.AwsRegion = current.AwsRegion
+	updated.DSN = current.DSN
+	if !slices.Equal(getSourceIds(current), getSourceIds(updated)) {
+		updated.Sources = current.Sources
+	} else {
+		for index, source := range updated.Sources {
+			source.DSN = current.Sources[index].DSN
+		}
+	}
+	updated.DatadogConfig.StatsdAddress = current.DatadogConfig.StatsdAddress
+	updated.Encoding = current.Encoding
+	updated.Http = current.Http
+	updated.Admin = current.Admin
+	updated.Tracing = current.Tracing
+	updated.StateFile = current.StateFile
+}
+
+func (r *configReloader) reload(trigger string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.app.Logger.Info("init_config_reload", "Reloading configuration", &map[string]interface{}{
+		"trigger": trigger,
+	})
+
+	if trigger == "signal" {
+		err := viper.ReadInConfig()
+		if err != nil {
+			r.app.Logger.Error("failed_config_reload", "Failed to read configuration file, keeping the current configuration", &map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	updated, err := parseConfig()
+	if err != nil {
+		r.app.Logger.Error("failed_config_reload", "Configuration is invalid, keeping the current configuration", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	current := r.app.GetConfig()
+	rejectedSettings := getRestartRequiredChanges(current, updated)
+	if len(rejectedSettings) > 0 {
+		r.app.Logger.Warn("rejected_config_change", "Settings that require a restart were changed and will not be applied until then", &map[string]interface{}{
+			"settings": rejectedSettings,
+		})
+		keepRestartRequiredSettings(current, updated)
+	}
+
+	appliedSettings := []string{}
+	if current.LogLevel != updated.LogLevel {
+		level := updated.LogLevel
+		if len(level) == 0 {
+			level = "debug"
+			if updated.IsProductionMode {
+				level = "info"
+			}
+		}
+
+		err = r.app.Logger.SetLevel(level)
+		if err != nil {
+			r.app.Logger.Error("failed_config_reload", "Failed to change log level", &map[string]interface{}{
+				"error": err.Error(),
+			})
+			updated.LogLevel = current.LogLevel
+		} else {
+			appliedSettings = append(appliedSettings, "logLevel")
+		}
+	}
+
+	if !reflect.DeepEqual(current.DatadogConfig.IngestHost, updated.DatadogConfig.IngestHost) || !reflect.DeepEqual(current.DatadogConfig.ApiKey, updated.DatadogConfig.ApiKey) {
+		err = r.app.Logger.SetDatadogIngestion(updated.DatadogConfig.IngestHost, updated.DatadogConfig.ApiKey)
+		if err != nil {
+			r.app.Logger.Error("failed_config_reload", "Failed to change Datadog ingestion", &map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			appliedSettings = append(appliedSettings, "datadog.ingestHost", "datadog.apiKey")
+		}
+	}
+
+	if !maps.Equal(*current.DatadogConfig.EventBaseFields, *updated.DatadogConfig.EventBaseFields) {
+		r.app.Logger.SetEventBaseFields(*updated.DatadogConfig.EventBaseFields)
+		appliedSettings = append(appliedSettings, "datadog.eventBaseFields")
+	}
+
+	if current.SyncFrequency != updated.SyncFrequency {
+		for _, scheduler := range r.schedulers {
+			scheduler.SetFrequency(updated.SyncFrequency)
+		}
+		appliedSettings = append(appliedSettings, "syncFrequency")
+	}
+
+	for index, source := range updated.Sources {
+		currentSource := current.Sources[index]
+		if *currentSource.Queues != *source.Queues {
+			appliedSettings = append(appliedSettings, "sources."+source.Id+".queues")
+		}
+
+		if !reflect.DeepEqual(currentSource.Filters, source.Filters) {
+			appliedSettings = append(appliedSettings, "sources."+source.Id+".filters")
+		}
+
+		if !reflect.DeepEqual(currentSource.Stock, source.Stock) {
+			appliedSettings = append(appliedSettings, "sources."+source.Id+".stock")
+		}
+
+		if !reflect.DeepEqual(currentSource.Money, source.Money) {
+			appliedSettings = append(appliedSettings, "sources."+source.Id+".money")
+		}
+	}
+
+	if *current.MassChange != *updated.MassChange {
+		appliedSettings = append(appliedSettings, "massChange")
+	}
+
+	if !reflect.DeepEqual(current.PriceLists, updated.PriceLists) {
+		appliedSettings = append(appliedSettings, "priceLists")
+	}
+
+	if *current.Promotions != *updated.Promotions {
+		appliedSettings = append(appliedSettings, "promotions")
+	}
+
+	if !reflect.DeepEqual(current.PriceGuard, updated.PriceGuard) {
+		appliedSettings = append(appliedSettings, "priceGuard")
+	}
+
+	if !reflect.DeepEqual(current.Pii, updated.Pii) {
+		appliedSettings = append(appliedSettings, "pii")
+	}
+
+	if current.ReplicationLagSlo != updated.ReplicationLagSlo {
+		appliedSettings = append(appliedSettings, "replicationLagSlo")
+	}
+
+	if current.Health.MaxMissedRuns != updated.Health.MaxMissedRuns {
+		appliedSettings = append(appliedSettings, "health.maxMissedRuns")
+	}
+
+	r.app.SetConfig(updated)
+	r.app.Logger.Info("finished_config_reload", "Reloaded configuration", &map[string]interface{}{
+		"appliedSettings": appliedSettings,
+		"syncFrequency":   updated.SyncFrequency.String(),
+	})
+}
+
+// Reloads the configuration when the file changes or a SIGHUP is received, until the context is done
+func watchConfig(ctx context.Context, app *xd_rsync.XdRsyncInstance, schedulers []*tickers.Scheduler) {
+	reloader := &configReloader{
+		app:        app,
+		schedulers: schedulers,
+	}
+
+	if len(viper.ConfigFileUsed()) > 0 {
+		viper.OnConfigChange(func(event fsnotify.Event) {
+			reloader.reload("file_change")
+		})
+		viper.WatchConfig()
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hangups)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+				reloader.reload("signal")
+			}
+		}
+	}()
+}
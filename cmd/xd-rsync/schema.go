@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/encoders"
+)
+
+type publishedEvent struct {
+	name   string
+	record interface{}
+}
+
+var PUBLISHED_EVENTS = []publishedEvent{
+	{name: "product", record: &xd_rsync.XdProduct{}},
+	{name: "customer", record: &xd_rsync.XdCustomer{}},
+	{name: "document", record: &xd_rsync.XdSalesDocument{}},
+	{name: "stock-movement", record: &xd_rsync.XdStockMovement{}},
+	{name: "stock-transition", record: &xd_rsync.XdStockTransition{}},
+	{name: "price-alert", record: &xd_rsync.XdPriceAlert{}},
+}
+
+func getSchemaFilePath(dir string, eventName string) string {
+	return filepath.Join(dir, eventName+".schema.json")
+}
+
+func readJsonSchemaFile(path string) (*encoders.JsonSchema, []byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schema := &encoders.JsonSchema{}
+	err = json.Unmarshal(content, schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse schema file %s: %w", path, err)
+	}
+
+	return schema, content, nil
+}
+
+func runSchemaCommand(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	dir := flags.String("dir", "schemas", "directory where the event schemas are stored")
+	previousDir := flags.String("previous", "", "directory with the previous schema versions (defaults to -dir)")
+	checkOnly := flags.Bool("check", false, "only compare schemas, failing if they are incompatible or out of date")
+	force := flags.Bool("force", false, "write schemas even if they are incompatible with the previous version")
+	flags.Parse(args)
+
+	if len(*previousDir) == 0 {
+		previousDir = dir
+	}
+
+	exitCode := 0
+	for _, event := range PUBLISHED_EVENTS {
+		currentSchema, err := encoders.GenerateJsonSchema(event.record)
+		if err != nil {
+			fmt.Printf("❌ Could not generate schema for '%s' event: %s\n", event.name, err)
+			return 1
+		}
+
+		renderedSchema, err := json.MarshalIndent(currentSchema, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Could not render schema for '%s' event: %s\n", event.name, err)
+			return 1
+		}
+		renderedSchema = append(renderedSchema, '\n')
+
+		isBreaking := false
+		isOutdated := true
+		previousSchema, previousContent, err := readJsonSchemaFile(getSchemaFilePath(*previousDir, event.name))
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("🆕 No previous schema found for '%s' event\n", event.name)
+		} else if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			return 1
+		} else {
+			isOutdated = !bytes.Equal(previousContent, renderedSchema)
+
+			changes := encoders.CompareJsonSchemas(previousSchema, currentSchema)
+			if len(changes) == 0 {
+				fmt.Printf("✅ '%s' event schema has no changes\n", event.name)
+			}
+
+			for _, change := range changes {
+				if change.IsBreaking() {
+					isBreaking = true
+					fmt.Printf("❌ '%s' event: %s\n", event.name, change.String())
+				} else {
+					fmt.Printf("ℹ️ '%s' event: %s\n", event.name, change.String())
+				}
+			}
+		}
+
+		if isBreaking {
+			exitCode = 1
+		}
+
+		if *checkOnly {
+			if isOutdated {
+				fmt.Printf("❌ '%s' event schema is out of date\n", event.name)
+				exitCode = 1
+			}
+			continue
+		}
+
+		if isBreaking && !*force {
+			fmt.Printf("⚠️ '%s' event schema was not written as it is incompatible. Use -force to overwrite it\n", event.name)
+			continue
+		}
+
+		if !isOutdated {
+			continue
+		}
+
+		err = os.MkdirAll(*dir, 0755)
+		if err != nil {
+			fmt.Printf("❌ Could not create schemas directory: %s\n", err)
+			return 1
+		}
+
+		err = os.WriteFile(getSchemaFilePath(*dir, event.name), renderedSchema, 0644)
+		if err != nil {
+			fmt.Printf("❌ Could not write schema for '%s' event: %s\n", event.name, err)
+			return 1
+		}
+
+		fmt.Printf("📝 Wrote '%s' event schema to %s\n", event.name, getSchemaFilePath(*dir, event.name))
+	}
+
+	if *force && !*checkOnly {
+		return 0
+	}
+
+	return exitCode
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/fabiofcferreira/xd-rsync/encoders"
+)
+
+// Replaces the stored product schema with the given change applied to it
+func changeStoredProductSchema(t *testing.T, dir string, change func(schema *encoders.JsonSchema)) {
+	path := getSchemaFilePath(dir, "product")
+	schema, _, err := readJsonSchemaFile(path)
+	if err != nil {
+		t.Fatalf("could not read schema: %v", err)
+	}
+
+	change(schema)
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		t.Fatalf("could not render schema: %v", err)
+	}
+
+	err = os.WriteFile(path, append(content, '\n'), 0644)
+	if err != nil {
+		t.Fatalf("could not write schema: %v", err)
+	}
+}
+
+func TestSchemaCheckExitCode(t *testing.T) {
+	tests := []struct {
+		name             string
+		change           func(schema *encoders.JsonSchema)
+		expectedExitCode int
+	}{
+		{
+			name:             "up to date",
+			change:           func(schema *encoders.JsonSchema) {},
+			expectedExitCode: EXIT_CODE_SUCCESS,
+		},
+		{
+			name: "compatible change out of date",
+			change: func(schema *encoders.JsonSchema) {
+				delete(schema.Properties, "family")
+			},
+			expectedExitCode: EXIT_CODE_FAILURE,
+		},
+		{
+			name: "retyped field",
+			change: func(schema *encoders.JsonSchema) {
+				schema.Properties["sku"].Type = encoders.JsonSchemaTypes{"integer"}
+			},
+			expectedExitCode: EXIT_CODE_FAILURE,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if exitCode := runSchemaCommand([]string{"-dir", dir}); exitCode != EXIT_CODE_SUCCESS {
+				t.Fatalf("expected the schemas to be written, got exit code %d", exitCode)
+			}
+
+			changeStoredProductSchema(t, dir, test.change)
+
+			exitCode := runSchemaCommand([]string{"-dir", dir, "-check"})
+			if exitCode != test.expectedExitCode {
+				t.Errorf("expected exit code %d, got %d", test.expectedExitCode, exitCode)
+			}
+		})
+	}
+}
+
+func TestSchemaWriteSkipsIncompatibleChanges(t *testing.T) {
+	dir := t.TempDir()
+	if exitCode := runSchemaCommand([]string{"-dir", dir}); exitCode != EXIT_CODE_SUCCESS {
+		t.Fatalf("expected the schemas to be written, got exit code %d", exitCode)
+	}
+
+	changeStoredProductSchema(t, dir, func(schema *encoders.JsonSchema) {
+		schema.Properties["sku"].Type = encoders.JsonSchemaTypes{"integer"}
+	})
+
+	if exitCode := runSchemaCommand([]string{"-dir", dir}); exitCode != EXIT_CODE_FAILURE {
+		t.Errorf("expected an incompatible change to fail, got exit code %d", exitCode)
+	}
+
+	if exitCode := runSchemaCommand([]string{"-dir", dir, "-force"}); exitCode != EXIT_CODE_SUCCESS {
+		t.Errorf("expected a forced write to succeed, got exit code %d", exitCode)
+	}
+
+	if exitCode := runSchemaCommand([]string{"-dir", dir, "-check"}); exitCode != EXIT_CODE_SUCCESS {
+		t.Errorf("expected the forced schema to be up to date, got exit code %d", exitCode)
+	}
+}
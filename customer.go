@@ -0,0 +1,69 @@
+package xd_rsync
+
+import (
+	"time"
+)
+
+// Personal data is tagged with `pii:"true"`, so it can be hashed or left out per sink and is never logged
+type XdCustomerContacts struct {
+	Email       *string `db:"email" dbSelector:"c.Email as 'contacts.email'" json:"email,omitempty" pii:"true"`
+	Phone       *string `db:"phone" dbSelector:"c.Phone as 'contacts.phone'" json:"phone,omitempty" pii:"true"`
+	MobilePhone *string `db:"mobilePhone" dbSelector:"c.MobilePhone as 'contacts.mobilePhone'" json:"mobilePhone,omitempty" pii:"true"`
+}
+
+type XdCustomerBillingAddress struct {
+	Street  *string `db:"street" dbSelector:"c.Address as 'billingAddress.street'" json:"street,omitempty" pii:"true"`
+	ZipCode *string `db:"zipCode" dbSelector:"c.ZipCode as 'billingAddress.zipCode'" json:"zipCode,omitempty" pii:"true"`
+	City    *string `db:"city" dbSelector:"c.City as 'billingAddress.city'" json:"city,omitempty"`
+	Country *string `db:"country" dbSelector:"c.Country as 'billingAddress.country'" json:"country,omitempty"`
+}
+
+type XdCustomerShippingAddress struct {
+	Street  *string `db:"street" dbSelector:"c.DeliveryAddress as 'shippingAddress.street'" json:"street,omitempty" pii:"true"`
+	ZipCode *string `db:"zipCode" dbSelector:"c.DeliveryZipCode as 'shippingAddress.zipCode'" json:"zipCode,omitempty" pii:"true"`
+	City    *string `db:"city" dbSelector:"c.DeliveryCity as 'shippingAddress.city'" json:"city,omitempty"`
+	Country *string `db:"country" dbSelector:"c.DeliveryCountry as 'shippingAddress.country'" json:"country,omitempty"`
+}
+
+type XdCustomer struct {
+	Id              string                    `db:"KeyId" dbSelector:"c.KeyId" json:"id"`
+	Name            *string                   `db:"Name" dbSelector:"c.Name" json:"name,omitempty" pii:"true"`
+	TaxId           *string                   `db:"TaxId" dbSelector:"c.TaxId" json:"taxId,omitempty" pii:"true"`
+	PriceTier       int                       `db:"PriceLine" dbSelector:"IFNULL(c.PriceLine, 1) as PriceLine" json:"priceTier"`
+	Contacts        XdCustomerContacts        `db:"contacts" json:"contacts"`
+	BillingAddress  XdCustomerBillingAddress  `db:"billingAddress" json:"billingAddress"`
+	ShippingAddress XdCustomerShippingAddress `db:"shippingAddress" json:"shippingAddress"`
+	SyncStamp       *time.Time                `db:"SyncStamp" dbSelector:"c.SyncStamp as SyncStamp" json:"syncStamp"`
+}
+
+func (c *XdCustomer) GetEntityName() string {
+	return "customers"
+}
+
+func (c *XdCustomer) GetTableName() string {
+	return "customers c"
+}
+
+func (c *XdCustomer) GetJoinExpressions() []string {
+	return nil
+}
+
+func (c *XdCustomer) GetConditions() []Condition {
+	return nil
+}
+
+func (c *XdCustomer) GetChangeColumns() []string {
+	return []string{"c.SyncStamp"}
+}
+
+func (c *XdCustomer) GetPrimaryKeyColumnName() string {
+	return "c.KeyId"
+}
+
+func (c *XdCustomer) GetKey() string {
+	return c.Id
+}
+
+func (c *XdCustomer) GetLastChangedAt() *time.Time {
+	return c.SyncStamp
+}
@@ -1,12 +1,20 @@
 package xd_rsync
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type DatabaseService interface {
-	GetProductByReferece(id string) (*XdProduct, error)
-	GetProductsByReferece(ids []string) (*XdProducts, error)
-	GetPricedProductsCount(ts *time.Time) (int, error)
-	GetPaginatedPricedProducts(ts *time.Time, limit int, offset int) (*XdProducts, error)
-	GetPricedProducts() (*XdProducts, error)
-	GetPricedProductsSinceTimestamp(ts *time.Time) (*XdProducts, error)
+	Ping(ctx context.Context) error
+	GetEntitiesCount(ctx context.Context, query *EntityQuery) (int, error)
+	GetPaginatedEntities(ctx context.Context, query *EntityQuery, limit int, afterKey *string) ([]Entity, error)
+	GetEntities(ctx context.Context, query *EntityQuery) ([]Entity, error)
+	GetEntitiesByKey(ctx context.Context, query *EntityQuery, keys []string) ([]Entity, error)
+	GetPriceListPrices(ctx context.Context, priceList *PriceListConfig, skus []string) ([]*XdPriceListPrice, error)
+	GetActivePromotions(ctx context.Context, skus []string, at time.Time) ([]*XdPromotion, error)
+	GetPromotionsBetween(ctx context.Context, from time.Time, to time.Time) ([]*XdPromotion, error)
+	GetNextPromotionBoundary(ctx context.Context, after time.Time) (*time.Time, error)
+	GetProductByReferece(ctx context.Context, id string, conditions []Condition) (*XdProduct, error)
+	GetProductsByReferece(ctx context.Context, ids []string, conditions []Condition) (*XdProducts, error)
 }
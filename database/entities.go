@@ -0,0 +1,326 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const PAGE_SIZE = 200
+
+// Maximum number of parent keys per query when reading the embedded records of an entity
+const MAX_PARENT_KEYS_PER_QUERY = 1000
+
+// Matches the records with any change tracking column after the time of the query
+func getChangedAfterCondition(query *xd_rsync.EntityQuery) xd_rsync.Condition {
+	expressions := []string{}
+	args := []interface{}{}
+	for _, column := range append(query.Entity.GetChangeColumns(), query.ChangeColumns...) {
+		expressions = append(expressions, column+" > ?")
+		args = append(args, formatTimestampToRFC3339(query.ChangedAfter))
+	}
+
+	return xd_rsync.NewCondition(strings.Join(expressions, " OR "), args...)
+}
+
+// Returns the conditions of the entity and of the query
+func getQueryConditions(query *xd_rsync.EntityQuery) []xd_rsync.Condition {
+	conditions := append([]xd_rsync.Condition{}, query.Entity.GetConditions()...)
+	conditions = append(conditions, query.Conditions...)
+	if query.ChangedAfter != nil {
+		conditions = append(conditions, getChangedAfterCondition(query))
+	}
+
+	return conditions
+}
+
+// Selects the given columns from the table of the entity and its joins, including the ones of the query
+func selectFromEntity(query *xd_rsync.EntityQuery, columns string) *SelectQuery {
+	entity := query.Entity
+	return Select(columns).From(entity.GetTableName()).Join(entity.GetJoinExpressions()...).Join(query.Joins...)
+}
+
+// Builds the query with the placeholders of the database
+func (s DatabaseClient) bindQuery(query *SelectQuery) (string, []interface{}, error) {
+	sqlQuery, args, err := query.Build()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s.db.Rebind(sqlQuery), args, nil
+}
+
+// Creates a pointer to an empty slice of the record's struct, where query results can be scanned into
+func newRecordList(record interface{}) reflect.Value {
+	return reflect.New(reflect.SliceOf(reflect.TypeOf(record).Elem()))
+}
+
+func toEntities(list reflect.Value) []xd_rsync.Entity {
+	items := list.Elem()
+	entities := make([]xd_rsync.Entity, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		entities = append(entities, items.Index(i).Addr().Interface().(xd_rsync.Entity))
+	}
+
+	return entities
+}
+
+func toChildEntities(list reflect.Value) []xd_rsync.ChildEntity {
+	items := list.Elem()
+	children := make([]xd_rsync.ChildEntity, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		children = append(children, items.Index(i).Addr().Interface().(xd_rsync.ChildEntity))
+	}
+
+	return children
+}
+
+func (s DatabaseClient) GetEntitiesCount(ctx context.Context, query *xd_rsync.EntityQuery) (int, error) {
+	entity := query.Entity
+	entityName := entity.GetEntityName()
+	s.logger.Info("init_count_entities", "Fetching count of entities", &map[string]interface{}{
+		"entity":       entityName,
+		"updatedAfter": query.ChangedAfter,
+	})
+
+	sqlQuery, args, err := s.bindQuery(
+		selectFromEntity(query, "count("+entity.GetPrimaryKeyColumnName()+")").
+			WhereConditions(getQueryConditions(query)...),
+	)
+	if err != nil {
+		return -1, err
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "db.count_"+entityName)
+
+	count := 0
+	queryStartedAt := time.Now()
+	err = s.db.GetContext(ctx, &count, sqlQuery, args...)
+	s.metrics.ObserveDatabaseQuery("count_"+entityName, queryStartedAt, 0, err)
+	span.SetAttributes(attribute.Int("db.entities_count", count))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		s.logger.Error("failed_count_entities", "Failed fetching count of entities", &map[string]interface{}{
+			"entity": entityName,
+			"error":  err.Error(),
+		})
+		return -1, fmt.Errorf("could not count %s: %w", entityName, err)
+	}
+
+	return count, nil
+}
+
+// Fetches a page of the entities selected by the query, in primary key order, after the given key or from the first
+// one when there is none. Pages start after the last key of the previous one, so they stay stable while rows change
+// and deep pages do not scan the rows before them.
+func (s DatabaseClient) GetPaginatedEntities(ctx context.Context, query *xd_rsync.EntityQuery, limit int, afterKey *string) ([]xd_rsync.Entity, error) {
+	entity := query.Entity
+	entityName := entity.GetEntityName()
+	s.logger.Info("init_get_paginated_entities", "Fetching paginated entities", &map[string]interface{}{
+		"entity":   entityName,
+		"limit":    limit,
+		"afterKey": afterKey,
+	})
+
+	selectQuery := selectFromEntity(query, xd_rsync.GetEntityColumnsQuerySelectors(entity)).
+		WhereConditions(getQueryConditions(query)...).
+		OrderBy(entity.GetPrimaryKeyColumnName()).
+		Limit(limit)
+	if afterKey != nil {
+		selectQuery.Where(entity.GetPrimaryKeyColumnName()+" > ?", *afterKey)
+	}
+
+	sqlQuery, args, err := s.bindQuery(selectQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	spanAttributes := []attribute.KeyValue{attribute.Int("db.page.limit", limit)}
+	if afterKey != nil {
+		spanAttributes = append(spanAttributes, attribute.String("db.page.after_key", *afterKey))
+	}
+	ctx, span := tracing.StartSpan(ctx, "db.paginated_"+entityName, spanAttributes...)
+
+	list := newRecordList(entity)
+	queryStartedAt := time.Now()
+	err = s.db.SelectContext(ctx, list.Interface(), sqlQuery, args...)
+	entities := toEntities(list)
+	s.metrics.ObserveDatabaseQuery("paginated_"+entityName, queryStartedAt, len(entities), err)
+	span.SetAttributes(attribute.Int("db.entities_count", len(entities)))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		s.logger.Error("failed_get_paginated_entities", "Failed fetching paginated entities", &map[string]interface{}{
+			"entity":   entityName,
+			"limit":    limit,
+			"afterKey": afterKey,
+			"error":    err.Error(),
+		})
+		return nil, fmt.Errorf("could not get %s page: %w", entityName, err)
+	}
+
+	err = s.attachChildren(ctx, entity, entities)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("finished_get_paginated_entities", "Fetched paginated entities", &map[string]interface{}{
+		"entity":   entityName,
+		"limit":    limit,
+		"afterKey": afterKey,
+	})
+	return entities, nil
+}
+
+// Fetches every entity selected by the query, a page at a time
+func (s DatabaseClient) GetEntities(ctx context.Context, query *xd_rsync.EntityQuery) ([]xd_rsync.Entity, error) {
+	entityName := query.Entity.GetEntityName()
+	s.logger.Info("init_get_entities", "Fetching all entities", &map[string]interface{}{
+		"entity":           entityName,
+		"minimumTimestamp": query.ChangedAfter,
+	})
+
+	entities := []xd_rsync.Entity{}
+	var afterKey *string
+	for {
+		page, err := s.GetPaginatedEntities(ctx, query, PAGE_SIZE, afterKey)
+		if err != nil {
+			s.logger.Error("failed_get_entities", "Failed fetching entities", &map[string]interface{}{
+				"entity":           entityName,
+				"minimumTimestamp": query.ChangedAfter,
+				"error":            err.Error(),
+			})
+			return nil, err
+		}
+
+		entities = append(entities, page...)
+		if len(page) < PAGE_SIZE {
+			break
+		}
+
+		lastKey := page[len(page)-1].GetKey()
+		afterKey = &lastKey
+	}
+
+	s.logger.Info("finished_get_entities", "Fetched all entities", &map[string]interface{}{
+		"entity":           entityName,
+		"entitiesCount":    len(entities),
+		"minimumTimestamp": query.ChangedAfter,
+	})
+	return entities, nil
+}
+
+// Fetches the entities with the given primary keys, regardless of whether they changed
+func (s DatabaseClient) GetEntitiesByKey(ctx context.Context, query *xd_rsync.EntityQuery, keys []string) ([]xd_rsync.Entity, error) {
+	entity := query.Entity
+	entityName := entity.GetEntityName()
+	s.logger.Info("init_get_entities_by_key", "Fetching entities by key", &map[string]interface{}{
+		"entity": entityName,
+		"keys":   keys,
+	})
+
+	sqlQuery, args, err := s.bindQuery(
+		selectFromEntity(query, xd_rsync.GetEntityColumnsQuerySelectors(entity)).
+			WhereConditions(getQueryConditions(query)...).
+			Where(entity.GetPrimaryKeyColumnName()+" IN (?)", keys),
+	)
+	if err != nil {
+		s.logger.Error("failed_get_entities_by_key_query_build", "Failed to build query to fetch entities by key", &map[string]interface{}{
+			"entity": entityName,
+			"error":  err.Error(),
+		})
+		return nil, err
+	}
+
+	list := newRecordList(entity)
+	queryStartedAt := time.Now()
+	err = s.db.SelectContext(ctx, list.Interface(), sqlQuery, args...)
+	entities := toEntities(list)
+	s.metrics.ObserveDatabaseQuery(entityName+"_by_key", queryStartedAt, len(entities), err)
+	if err != nil {
+		s.logger.Error("failed_get_entities_by_key", "Failed fetching entities by key", &map[string]interface{}{
+			"entity": entityName,
+			"error":  err.Error(),
+		})
+		return nil, fmt.Errorf("could not get %s: %w", entityName, err)
+	}
+
+	err = s.attachChildren(ctx, entity, entities)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("finished_get_entities_by_key", "Fetched entities by key", &map[string]interface{}{
+		"entity":        entityName,
+		"keys":          keys,
+		"entitiesCount": len(entities),
+	})
+	return entities, nil
+}
+
+// Reads the embedded records of the given entities, when their entity has them
+func (s DatabaseClient) attachChildren(ctx context.Context, entity xd_rsync.Entity, entities []xd_rsync.Entity) error {
+	parentEntity, isParent := entity.(xd_rsync.ParentEntity)
+	if !isParent || len(entities) == 0 {
+		return nil
+	}
+
+	entityName := entity.GetEntityName()
+	parentKeys := []string{}
+	for _, parent := range entities {
+		parentKeys = append(parentKeys, parent.GetKey())
+	}
+
+	childrenByParentKey := map[string][]xd_rsync.ChildEntity{}
+	for start := 0; start < len(parentKeys); start += MAX_PARENT_KEYS_PER_QUERY {
+		end := min(start+MAX_PARENT_KEYS_PER_QUERY, len(parentKeys))
+		children, err := s.getChildren(ctx, entityName, parentEntity.GetChildEntity(), parentKeys[start:end])
+		if err != nil {
+			s.logger.Error("failed_get_child_entities", "Failed fetching embedded records", &map[string]interface{}{
+				"entity": entityName,
+				"error":  err.Error(),
+			})
+			return fmt.Errorf("could not get %s embedded records: %w", entityName, err)
+		}
+
+		for _, child := range children {
+			childrenByParentKey[child.GetParentKey()] = append(childrenByParentKey[child.GetParentKey()], child)
+		}
+	}
+
+	for _, parent := range entities {
+		parent.(xd_rsync.ParentEntity).SetChildren(childrenByParentKey[parent.GetKey()])
+	}
+
+	return nil
+}
+
+func (s DatabaseClient) getChildren(ctx context.Context, entityName string, child xd_rsync.ChildEntity, parentKeys []string) ([]xd_rsync.ChildEntity, error) {
+	sqlQuery, args, err := s.bindQuery(
+		Select(xd_rsync.GetEntityColumnsQuerySelectors(child)).
+			From(child.GetTableName()).
+			Join(child.GetJoinExpressions()...).
+			WhereConditions(child.GetConditions()...).
+			Where(child.GetParentKeyColumnName()+" IN (?)", parentKeys).
+			OrderBy(child.GetParentKeyColumnName(), child.GetOrderColumnName()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	list := newRecordList(child)
+	queryStartedAt := time.Now()
+	err = s.db.SelectContext(ctx, list.Interface(), sqlQuery, args...)
+	children := toChildEntities(list)
+	s.metrics.ObserveDatabaseQuery(entityName+"_children", queryStartedAt, len(children), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
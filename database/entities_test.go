@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testEntity struct {
+	Id        string     `db:"KeyId" dbSelector:"t.KeyId"`
+	SyncStamp *time.Time `db:"SyncStamp" dbSelector:"t.SyncStamp"`
+}
+
+func (e *testEntity) GetEntityName() string               { return "tests" }
+func (e *testEntity) GetTableName() string                { return "tests t" }
+func (e *testEntity) GetJoinExpressions() []string        { return nil }
+func (e *testEntity) GetConditions() []xd_rsync.Condition { return nil }
+func (e *testEntity) GetChangeColumns() []string          { return []string{"t.SyncStamp"} }
+func (e *testEntity) GetPrimaryKeyColumnName() string     { return "t.KeyId" }
+func (e *testEntity) GetKey() string                      { return e.Id }
+func (e *testEntity) GetLastChangedAt() *time.Time        { return e.SyncStamp }
+
+var TEST_PAGE_PATTERN = regexp.MustCompile(`LIMIT (\d+)$`)
+
+// Stand-in for the database, answering the count and page queries of the test entities, whose keys are sorted
+type testConnector struct {
+	keys []string
+	// Page queries received, in order
+	queries []string
+}
+
+func (c *testConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &testConn{connector: c}, nil
+}
+func (c *testConnector) Driver() driver.Driver { return nil }
+
+type testConn struct {
+	connector *testConnector
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testConn) Close() error                              { return nil }
+func (c *testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c *testConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	keys := c.connector.keys
+	if strings.HasPrefix(query, "SELECT count(") {
+		return &testRows{columns: []string{"count"}, values: [][]driver.Value{{int64(len(keys))}}}, nil
+	}
+
+	c.connector.queries = append(c.connector.queries, query)
+	page := TEST_PAGE_PATTERN.FindStringSubmatch(query)
+	limit, _ := strconv.Atoi(page[1])
+	start := 0
+	if strings.Contains(query, "t.KeyId > ?") {
+		afterKey := args[len(args)-1].Value.(string)
+		start, _ = slices.BinarySearch(keys, afterKey+"\x00")
+	}
+
+	rows := &testRows{columns: []string{"KeyId", "SyncStamp"}}
+	for _, key := range keys[start:min(start+limit, len(keys))] {
+		rows.values = append(rows.values, []driver.Value{key, time.Now()})
+	}
+
+	return rows, nil
+}
+
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func createTestClient(t *testing.T, connector *testConnector) DatabaseClient {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction: true,
+		Level:        "error",
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	testMetrics, err := metrics.CreateMetrics(&metrics.MetricsCreationInput{Logger: testLogger})
+	if err != nil {
+		t.Fatalf("could not create metrics: %v", err)
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector), "mysql")
+	t.Cleanup(func() { db.Close() })
+
+	return DatabaseClient{
+		db:      db,
+		logger:  testLogger,
+		metrics: testMetrics,
+	}
+}
+
+func getSpanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, keyValue := range span.Attributes {
+		if keyValue.Key == key {
+			return keyValue.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+// Creates the keys of the test entities in sorted order, e.g. A000, A001, ...
+func createTestKeys(count int) []string {
+	keys := []string{}
+	for index := 0; index < count; index++ {
+		keys = append(keys, fmt.Sprintf("A%03d", index))
+	}
+
+	return keys
+}
+
+func TestGetEntitiesTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+
+	keys := createTestKeys(450)
+	client := createTestClient(t, &testConnector{keys: keys})
+
+	changedAfter := time.Now().Add(-time.Hour)
+	ctx, runSpan := tracing.StartSpan(context.Background(), "sync_run")
+	entities, err := client.GetEntities(ctx, &xd_rsync.EntityQuery{
+		Entity:       &testEntity{},
+		ChangedAfter: &changedAfter,
+	})
+	tracing.EndSpan(runSpan, err)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entities) != len(keys) {
+		t.Fatalf("expected %d entities, got %d", len(keys), len(entities))
+	}
+
+	var run tracetest.SpanStub
+	pages := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		switch span.Name {
+		case "sync_run":
+			run = span
+		case "db.paginated_tests":
+			pages[getSpanAttribute(span, "db.page.after_key").AsString()] = span
+		}
+	}
+
+	// The first page starts from the first key, so it has no key to start after
+	expectedCounts := map[string]int64{"": 200, "A199": 200, "A399": 50}
+	if len(pages) != len(expectedCounts) {
+		t.Fatalf("expected a span per page, got %d", len(pages))
+	}
+
+	for afterKey, expectedCount := range expectedCounts {
+		page := pages[afterKey]
+		if page.Parent.SpanID() != run.SpanContext.SpanID() || page.SpanContext.TraceID() != run.SpanContext.TraceID() {
+			t.Errorf("expected the page after '%s' to be a child of sync_run", afterKey)
+		}
+
+		if getSpanAttribute(page, "db.page.limit").AsInt64() != PAGE_SIZE || getSpanAttribute(page, "db.entities_count").AsInt64() != expectedCount {
+			t.Errorf("expected the page after '%s' to have %d entities, got %v", afterKey, expectedCount, page.Attributes)
+		}
+	}
+}
+
+func TestGetEntitiesPagesAfterTheLastKey(t *testing.T) {
+	tests := []struct {
+		name            string
+		keysCount       int
+		expectedQueries []string
+	}{
+		{
+			name:      "single page",
+			keysCount: 3,
+			expectedQueries: []string{
+				"SELECT t.KeyId, t.SyncStamp FROM tests t ORDER BY t.KeyId LIMIT 200",
+			},
+		},
+		{
+			name:      "full last page",
+			keysCount: 400,
+			expectedQueries: []string{
+				"SELECT t.KeyId, t.SyncStamp FROM tests t ORDER BY t.KeyId LIMIT 200",
+				"SELECT t.KeyId, t.SyncStamp FROM tests t WHERE (t.KeyId > ?) ORDER BY t.KeyId LIMIT 200",
+				"SELECT t.KeyId, t.SyncStamp FROM tests t WHERE (t.KeyId > ?) ORDER BY t.KeyId LIMIT 200",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys := createTestKeys(test.keysCount)
+			connector := &testConnector{keys: keys}
+			entities, err := createTestClient(t, connector).GetEntities(context.Background(), &xd_rsync.EntityQuery{Entity: &testEntity{}})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !slices.Equal(connector.queries, test.expectedQueries) {
+				t.Errorf("expected queries\n%s\ngot\n%s", strings.Join(test.expectedQueries, "\n"), strings.Join(connector.queries, "\n"))
+			}
+
+			entityKeys := []string{}
+			for _, entity := range entities {
+				entityKeys = append(entityKeys, entity.GetKey())
+			}
+			if !slices.Equal(entityKeys, keys) {
+				t.Errorf("expected every entity once in key order, got %d entities", len(entityKeys))
+			}
+		})
+	}
+}
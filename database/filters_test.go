@@ -0,0 +1,170 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Builds the products query selecting the products meeting the given conditions
+func buildFilteredQuery(t *testing.T, conditions ...xd_rsync.Condition) (string, []interface{}) {
+	sqlQuery, args, err := Select("i.KeyId").From("items i").WhereConditions(conditions...).Build()
+	if err != nil {
+		t.Fatalf("could not build query: %v", err)
+	}
+
+	return sqlQuery, args
+}
+
+func TestProductFilterRuleCompile(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         *xd_rsync.ProductFilterRule
+		expectedSql  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "comparison of a known field",
+			rule:         &xd_rsync.ProductFilterRule{Field: "price", Operator: xd_rsync.FILTER_OPERATOR_GT, Value: 0},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.RetailPrice2 > ?)",
+			expectedArgs: []interface{}{0},
+		},
+		{
+			name:         "comparison of an items column",
+			rule:         &xd_rsync.ProductFilterRule{Field: "Brand", Operator: xd_rsync.FILTER_OPERATOR_NE, Value: "ACME"},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.Brand <> ?)",
+			expectedArgs: []interface{}{"ACME"},
+		},
+		{
+			name:         "in",
+			rule:         &xd_rsync.ProductFilterRule{Field: "family", Operator: xd_rsync.FILTER_OPERATOR_IN, Values: []interface{}{"DRINKS", "FOOD"}},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.Family IN (?, ?))",
+			expectedArgs: []interface{}{"DRINKS", "FOOD"},
+		},
+		{
+			name:         "not in",
+			rule:         &xd_rsync.ProductFilterRule{Field: "sku", Operator: xd_rsync.FILTER_OPERATOR_NOT_IN, Values: []interface{}{"A1", 2}},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId NOT IN (?, ?))",
+			expectedArgs: []interface{}{"A1", 2},
+		},
+		{
+			name:         "starts with escaping the wildcards",
+			rule:         &xd_rsync.ProductFilterRule{Field: "sku", Operator: xd_rsync.FILTER_OPERATOR_STARTS_WITH, Value: `10%_OFF\`},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId LIKE ?)",
+			expectedArgs: []interface{}{`10\%\_OFF\\%`},
+		},
+		{
+			name:         "not starts with",
+			rule:         &xd_rsync.ProductFilterRule{Field: "sku", Operator: xd_rsync.FILTER_OPERATOR_NOT_STARTS_WITH, Value: "TMP_"},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId NOT LIKE ?)",
+			expectedArgs: []interface{}{`TMP\_%`},
+		},
+		{
+			name:         "is null",
+			rule:         &xd_rsync.ProductFilterRule{Field: "family", Operator: xd_rsync.FILTER_OPERATOR_IS_NULL},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.Family IS NULL)",
+			expectedArgs: []interface{}{},
+		},
+		{
+			name:         "is not null",
+			rule:         &xd_rsync.ProductFilterRule{Field: "costPrice", Operator: xd_rsync.FILTER_OPERATOR_IS_NOT_NULL},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.CostPrice IS NOT NULL)",
+			expectedArgs: []interface{}{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition, err := test.rule.Compile()
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			sqlQuery, args := buildFilteredQuery(t, condition)
+			if sqlQuery != test.expectedSql {
+				t.Errorf("expected SQL\n%s\ngot\n%s", test.expectedSql, sqlQuery)
+			}
+
+			if !reflect.DeepEqual(args, test.expectedArgs) {
+				t.Errorf("expected args %#v, got %#v", test.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestProductFilterRuleCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *xd_rsync.ProductFilterRule
+	}{
+		{name: "column that is not an identifier", rule: &xd_rsync.ProductFilterRule{Field: "KeyId; DROP", Operator: xd_rsync.FILTER_OPERATOR_EQ, Value: 1}},
+		{name: "unknown operator", rule: &xd_rsync.ProductFilterRule{Field: "sku", Operator: "like", Value: "A"}},
+		{name: "comparison without a value", rule: &xd_rsync.ProductFilterRule{Field: "price", Operator: xd_rsync.FILTER_OPERATOR_GT}},
+		{name: "comparison with a list", rule: &xd_rsync.ProductFilterRule{Field: "price", Operator: xd_rsync.FILTER_OPERATOR_EQ, Value: []interface{}{1, 2}}},
+		{name: "comparison with a map", rule: &xd_rsync.ProductFilterRule{Field: "price", Operator: xd_rsync.FILTER_OPERATOR_EQ, Value: map[string]interface{}{"min": 1}}},
+		{name: "in without values", rule: &xd_rsync.ProductFilterRule{Field: "family", Operator: xd_rsync.FILTER_OPERATOR_IN}},
+		{name: "in with an empty value", rule: &xd_rsync.ProductFilterRule{Field: "family", Operator: xd_rsync.FILTER_OPERATOR_IN, Values: []interface{}{"DRINKS", nil}}},
+		{name: "in with a nested list", rule: &xd_rsync.ProductFilterRule{Field: "family", Operator: xd_rsync.FILTER_OPERATOR_IN, Values: []interface{}{[]interface{}{"DRINKS"}}}},
+		{name: "not in with a nested map", rule: &xd_rsync.ProductFilterRule{Field: "family", Operator: xd_rsync.FILTER_OPERATOR_NOT_IN, Values: []interface{}{"FOOD", map[string]interface{}{"a": 1}}}},
+		{name: "starts with a number", rule: &xd_rsync.ProductFilterRule{Field: "sku", Operator: xd_rsync.FILTER_OPERATOR_STARTS_WITH, Value: 10}},
+		{name: "starts with an empty prefix", rule: &xd_rsync.ProductFilterRule{Field: "sku", Operator: xd_rsync.FILTER_OPERATOR_STARTS_WITH, Value: ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.rule.Compile()
+			if err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestCompileSkuFilters(t *testing.T) {
+	tests := []struct {
+		name         string
+		skuPrefixes  []string
+		excludedSkus []string
+		expectedSql  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "no filters",
+			expectedSql:  "SELECT i.KeyId FROM items i",
+			expectedArgs: []interface{}{},
+		},
+		{
+			name:         "excluded SKUs",
+			excludedSkus: []string{"A1", "A2"},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId NOT IN (?, ?))",
+			expectedArgs: []interface{}{"A1", "A2"},
+		},
+		{
+			name:         "prefixes escaping the wildcards",
+			skuPrefixes:  []string{"PT_", "100%"},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId LIKE ? OR i.KeyId LIKE ?)",
+			expectedArgs: []interface{}{`PT\_%`, `100\%%`},
+		},
+		{
+			name:         "prefixes and excluded SKUs",
+			skuPrefixes:  []string{"PT"},
+			excludedSkus: []string{"PT1"},
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId NOT IN (?)) AND (i.KeyId LIKE ?)",
+			expectedArgs: []interface{}{"PT1", "PT%"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sqlQuery, args := buildFilteredQuery(t, xd_rsync.CompileSkuFilters(test.skuPrefixes, test.excludedSkus)...)
+			if sqlQuery != test.expectedSql {
+				t.Errorf("expected SQL\n%s\ngot\n%s", test.expectedSql, sqlQuery)
+			}
+
+			if !reflect.DeepEqual(args, test.expectedArgs) {
+				t.Errorf("expected args %#v, got %#v", test.expectedArgs, args)
+			}
+		})
+	}
+}
@@ -1,23 +1,28 @@
 package database
 
 import (
+	"context"
 	_ "database/sql"
 	"errors"
+	"fmt"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 
 	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
 )
 
 type DatabaseClient struct {
-	db     *sqlx.DB
-	logger *logger.Logger
+	db      *sqlx.DB
+	logger  *logger.Logger
+	metrics *metrics.Metrics
 }
 
 type DatabaseClientCreationInput struct {
-	DSN    string
-	Logger *logger.Logger
+	DSN     string
+	Logger  *logger.Logger
+	Metrics *metrics.Metrics
 }
 
 func CreateClient(input *DatabaseClientCreationInput) (*DatabaseClient, error) {
@@ -25,7 +30,8 @@ func CreateClient(input *DatabaseClientCreationInput) (*DatabaseClient, error) {
 	var dbConnection *sqlx.DB
 
 	service := &DatabaseClient{
-		logger: input.Logger,
+		logger:  input.Logger,
+		metrics: input.Metrics,
 	}
 
 	// Setup database connection
@@ -51,3 +57,12 @@ func CreateClient(input *DatabaseClientCreationInput) (*DatabaseClient, error) {
 	service.logger.Info("finished_init_db_connection", "Established DB connection successfully", nil)
 	return service, nil
 }
+
+func (s DatabaseClient) Ping(ctx context.Context) error {
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+func TestGetPriceListQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		priceList    *xd_rsync.PriceListConfig
+		expectedSql  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "column",
+			priceList:    &xd_rsync.PriceListConfig{Name: "wholesale", Column: "RetailPrice3"},
+			expectedSql:  "SELECT i.KeyId as ItemKeyId, i.RetailPrice3 as Price FROM items i WHERE (i.KeyId IN (?, ?)) AND (i.RetailPrice3 IS NOT NULL)",
+			expectedArgs: []interface{}{"A1", "A2"},
+		},
+		{
+			name:         "price table",
+			priceList:    &xd_rsync.PriceListConfig{Name: "resellers", PriceTableId: "2"},
+			expectedSql:  "SELECT ip.ItemKeyId, ip.RetailPrice as Price, ip.SyncStamp FROM itemprices ip WHERE (ip.PriceTableKeyId = ?) AND (ip.ItemKeyId IN (?, ?)) AND (ip.RetailPrice IS NOT NULL)",
+			expectedArgs: []interface{}{"2", "A1", "A2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := getPriceListQuery(test.priceList, []string{"A1", "A2"})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			sqlQuery, args, err := query.Build()
+			if err != nil {
+				t.Fatalf("could not build query: %v", err)
+			}
+
+			if sqlQuery != test.expectedSql {
+				t.Errorf("expected SQL\n%s\ngot\n%s", test.expectedSql, sqlQuery)
+			}
+
+			if !reflect.DeepEqual(args, test.expectedArgs) {
+				t.Errorf("expected args %#v, got %#v", test.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestGetPriceListQueryRejectsColumnsThatAreNotIdentifiers(t *testing.T) {
+	columns := []string{"", "i.RetailPrice3", "RetailPrice3 FROM items; --", "1RetailPrice"}
+	for _, column := range columns {
+		_, err := getPriceListQuery(&xd_rsync.PriceListConfig{Name: "wholesale", Column: column}, []string{"A1"})
+		if err == nil {
+			t.Errorf("expected column '%s' to be rejected", column)
+		}
+	}
+}
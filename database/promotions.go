@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Columns whose times are the boundaries of a promotion
+var PROMOTION_BOUNDARY_COLUMNS = []string{"pr.StartDate", "pr.EndDate"}
+
+func (s DatabaseClient) selectPromotions(ctx context.Context, queryName string, query *SelectQuery) ([]*xd_rsync.XdPromotion, error) {
+	sqlQuery, args, err := s.bindQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	promotions := []*xd_rsync.XdPromotion{}
+	queryStartedAt := time.Now()
+	err = s.db.SelectContext(ctx, &promotions, sqlQuery, args...)
+	s.metrics.ObserveDatabaseQuery(queryName, queryStartedAt, len(promotions), err)
+	if err != nil {
+		s.logger.Error("failed_get_promotions", "Failed fetching promotions", &map[string]interface{}{
+			"query": queryName,
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("could not get promotions: %w", err)
+	}
+
+	return promotions, nil
+}
+
+func selectPromotionColumns() *SelectQuery {
+	promotion := &xd_rsync.XdPromotion{}
+	return Select(xd_rsync.GetEntityColumnsQuerySelectors(promotion)).From(promotion.GetTableName())
+}
+
+// Fetches the promotions of the given products valid at the given time
+func (s DatabaseClient) GetActivePromotions(ctx context.Context, skus []string, at time.Time) ([]*xd_rsync.XdPromotion, error) {
+	promotions := []*xd_rsync.XdPromotion{}
+	for start := 0; start < len(skus); start += MAX_PARENT_KEYS_PER_QUERY {
+		end := min(start+MAX_PARENT_KEYS_PER_QUERY, len(skus))
+		page, err := s.selectPromotions(ctx, "active_promotions", selectPromotionColumns().
+			Where("pr.ItemKeyId IN (?)", skus[start:end]).
+			Where("pr.StartDate <= ?", formatTimestampToRFC3339(&at)).
+			Where("pr.EndDate IS NULL OR pr.EndDate > ?", formatTimestampToRFC3339(&at)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		promotions = append(promotions, page...)
+	}
+
+	return promotions, nil
+}
+
+// Fetches the promotions starting or ending after the first time, until the second one
+func (s DatabaseClient) GetPromotionsBetween(ctx context.Context, from time.Time, to time.Time) ([]*xd_rsync.XdPromotion, error) {
+	fromTimestamp, toTimestamp := formatTimestampToRFC3339(&from), formatTimestampToRFC3339(&to)
+	return s.selectPromotions(ctx, "promotions_between", selectPromotionColumns().Where(
+		"(pr.StartDate > ? AND pr.StartDate <= ?) OR (pr.EndDate > ? AND pr.EndDate <= ?)",
+		fromTimestamp, toTimestamp, fromTimestamp, toTimestamp,
+	))
+}
+
+// Returns the first time after the given one at which a promotion starts or ends, or nil when there is none
+func (s DatabaseClient) GetNextPromotionBoundary(ctx context.Context, after time.Time) (*time.Time, error) {
+	var nextBoundary *time.Time
+	for _, column := range PROMOTION_BOUNDARY_COLUMNS {
+		sqlQuery, args, err := s.bindQuery(
+			Select("MIN("+column+")").
+				From((&xd_rsync.XdPromotion{}).GetTableName()).
+				Where(column+" > ?", formatTimestampToRFC3339(&after)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var boundary *time.Time
+		queryStartedAt := time.Now()
+		err = s.db.GetContext(ctx, &boundary, sqlQuery, args...)
+		s.metrics.ObserveDatabaseQuery("next_promotion_boundary", queryStartedAt, 0, err)
+		if err != nil {
+			s.logger.Error("failed_get_next_promotion_boundary", "Failed fetching the next promotion boundary", &map[string]interface{}{
+				"error": err.Error(),
+			})
+			return nil, fmt.Errorf("could not get next promotion boundary: %w", err)
+		}
+
+		nextBoundary = xd_rsync.GetEarliestTimestamp(nextBoundary, boundary)
+	}
+
+	return nextBoundary, nil
+}
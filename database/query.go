@@ -1,46 +1,112 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/jmoiron/sqlx"
 )
 
-const COUNT_DEFAULT_EXPRESSION = "count(*)"
+// SELECT statement whose values are always bound as "?" parameters, e.g.
+// Select("i.KeyId").From("items i").Where("i.RetailPrice2 > ?", 0).OrderBy("i.KeyId").Limit(200)
+type SelectQuery struct {
+	columns    []string
+	table      string
+	joins      []string
+	conditions []xd_rsync.Condition
+	orderBy    []string
+	limit      int
+	offset     int
+}
 
-func buildCountExpression(columnName string) string {
-	return "count(" + columnName + ")"
+func Select(columns ...string) *SelectQuery {
+	return &SelectQuery{
+		columns: columns,
+	}
 }
 
-func buildSelectTableExpression(fieldsList string, tableName string) string {
-	return strings.Join([]string{
-		"SELECT",
-		fieldsList,
-		"FROM",
-		tableName,
-	}, " ")
+// Table expression including its alias, e.g. "items i"
+func (q *SelectQuery) From(table string) *SelectQuery {
+	q.table = table
+	return q
 }
 
-func buildWhereExpression(conditions []string) string {
-	if len(conditions) > 0 {
-		encapsulatedConditions := []string{}
-		for _, singleCondition := range conditions {
-			encapsulatedConditions = append(encapsulatedConditions, "("+singleCondition+")")
-		}
+// Join expressions, e.g. "LEFT JOIN itemstock s ON s.ItemKeyId = i.KeyId"
+func (q *SelectQuery) Join(expressions ...string) *SelectQuery {
+	q.joins = append(q.joins, expressions...)
+	return q
+}
 
-		return "WHERE " + strings.Join(encapsulatedConditions, " AND ")
-	}
+// Adds a condition every row must meet. Slice arguments are expanded, so they can be used with IN (?).
+func (q *SelectQuery) Where(expression string, args ...interface{}) *SelectQuery {
+	return q.WhereConditions(xd_rsync.NewCondition(expression, args...))
+}
 
-	return ""
+func (q *SelectQuery) WhereConditions(conditions ...xd_rsync.Condition) *SelectQuery {
+	q.conditions = append(q.conditions, conditions...)
+	return q
 }
 
-func buildLimitExpression(limit int) string {
-	return "LIMIT " + strconv.Itoa(limit)
+func (q *SelectQuery) OrderBy(columns ...string) *SelectQuery {
+	q.orderBy = append(q.orderBy, columns...)
+	return q
 }
 
-func buildLimitOffsetExpression(limit int, offset int) string {
-	return "LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+// Maximum number of rows. Unlimited when 0
+func (q *SelectQuery) Limit(limit int) *SelectQuery {
+	q.limit = limit
+	return q
 }
 
-func joinAllExpressions(expressions []string) string {
-	return strings.Join(expressions, " ")
+// Number of rows skipped, which requires a limit
+func (q *SelectQuery) Offset(offset int) *SelectQuery {
+	q.offset = offset
+	return q
+}
+
+// Returns the SQL with "?" placeholders and its arguments, with the slice arguments expanded
+func (q *SelectQuery) Build() (string, []interface{}, error) {
+	if len(q.columns) == 0 || len(q.table) == 0 {
+		return "", nil, errors.New("could not build query: columns and table are required")
+	}
+
+	if q.limit < 0 || q.offset < 0 || (q.offset > 0 && q.limit == 0) {
+		return "", nil, fmt.Errorf("could not build query: limit %d and offset %d are not valid", q.limit, q.offset)
+	}
+
+	expressions := []string{"SELECT", strings.Join(q.columns, ", "), "FROM", q.table}
+	expressions = append(expressions, q.joins...)
+
+	args := []interface{}{}
+	if len(q.conditions) > 0 {
+		conditionExpressions := []string{}
+		for _, condition := range q.conditions {
+			conditionExpressions = append(conditionExpressions, "("+condition.Expression+")")
+			args = append(args, condition.Args...)
+		}
+
+		expressions = append(expressions, "WHERE", strings.Join(conditionExpressions, " AND "))
+	}
+
+	if len(q.orderBy) > 0 {
+		expressions = append(expressions, "ORDER BY", strings.Join(q.orderBy, ", "))
+	}
+
+	if q.limit > 0 {
+		expressions = append(expressions, "LIMIT", strconv.Itoa(q.limit))
+	}
+
+	if q.offset > 0 {
+		expressions = append(expressions, "OFFSET", strconv.Itoa(q.offset))
+	}
+
+	sqlQuery, expandedArgs, err := sqlx.In(strings.Join(expressions, " "), args...)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not build query: %w", err)
+	}
+
+	return sqlQuery, expandedArgs, nil
 }
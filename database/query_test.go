@@ -0,0 +1,134 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+func TestSelectQueryBuild(t *testing.T) {
+	changedAfter := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	productsChangedAfter := &xd_rsync.EntityQuery{
+		Entity:       &xd_rsync.XdProduct{},
+		ChangedAfter: &changedAfter,
+	}
+
+	tests := []struct {
+		name         string
+		query        *SelectQuery
+		expectedSql  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "columns and table",
+			query:        Select("i.KeyId", "i.Description").From("items i"),
+			expectedSql:  "SELECT i.KeyId, i.Description FROM items i",
+			expectedArgs: []interface{}{},
+		},
+		{
+			name: "joins and conditions",
+			query: Select("i.KeyId").
+				From("items i").
+				Join("LEFT JOIN itemstock s ON s.ItemKeyId = i.KeyId").
+				Where("i.RetailPrice2 > ?", 0).
+				Where("s.WarehouseId = ? OR s.WarehouseId = ?", "1", "2"),
+			expectedSql:  "SELECT i.KeyId FROM items i LEFT JOIN itemstock s ON s.ItemKeyId = i.KeyId WHERE (i.RetailPrice2 > ?) AND (s.WarehouseId = ? OR s.WarehouseId = ?)",
+			expectedArgs: []interface{}{0, "1", "2"},
+		},
+		{
+			name: "slice arguments expanded",
+			query: Select("i.KeyId").
+				From("items i").
+				Where("i.KeyId IN (?)", []string{"A1", "A2", "A3"}).
+				Where("i.RetailPrice2 > ?", 0),
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId IN (?, ?, ?)) AND (i.RetailPrice2 > ?)",
+			expectedArgs: []interface{}{"A1", "A2", "A3", 0},
+		},
+		{
+			name: "keyset page",
+			query: Select("i.KeyId").
+				From("items i").
+				Where("i.KeyId > ?", "A200").
+				OrderBy("i.KeyId").
+				Limit(200),
+			expectedSql:  "SELECT i.KeyId FROM items i WHERE (i.KeyId > ?) ORDER BY i.KeyId LIMIT 200",
+			expectedArgs: []interface{}{"A200"},
+		},
+		{
+			name:         "offset page",
+			query:        Select("i.KeyId").From("items i").OrderBy("i.KeyId").Limit(200).Offset(400),
+			expectedSql:  "SELECT i.KeyId FROM items i ORDER BY i.KeyId LIMIT 200 OFFSET 400",
+			expectedArgs: []interface{}{},
+		},
+		{
+			name:  "changed after every change column",
+			query: Select("count(i.KeyId)").From("items i").WhereConditions(getChangedAfterCondition(productsChangedAfter)),
+			expectedSql: "SELECT count(i.KeyId) FROM items i WHERE " +
+				"(i.SyncStamp > ? OR istock.SyncStamp > ? OR istock.LastEntrance > ? OR istock.LastExit > ?)",
+			expectedArgs: []interface{}{
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+			},
+		},
+		{
+			name: "changed after the query change columns",
+			query: Select("count(i.KeyId)").From("items i").WhereConditions(getChangedAfterCondition(&xd_rsync.EntityQuery{
+				Entity:        &xd_rsync.XdProduct{},
+				ChangedAfter:  &changedAfter,
+				ChangeColumns: []string{"iprice.SyncStamp"},
+			})),
+			expectedSql: "SELECT count(i.KeyId) FROM items i WHERE " +
+				"(i.SyncStamp > ? OR istock.SyncStamp > ? OR istock.LastEntrance > ? OR istock.LastExit > ? OR iprice.SyncStamp > ?)",
+			expectedArgs: []interface{}{
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+				"2024-05-01 10:30:00",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sqlQuery, args, err := test.query.Build()
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if sqlQuery != test.expectedSql {
+				t.Errorf("expected SQL\n%s\ngot\n%s", test.expectedSql, sqlQuery)
+			}
+
+			if !reflect.DeepEqual(args, test.expectedArgs) {
+				t.Errorf("expected args %#v, got %#v", test.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestSelectQueryBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *SelectQuery
+	}{
+		{name: "no columns", query: Select().From("items i")},
+		{name: "no table", query: Select("i.KeyId")},
+		{name: "negative limit", query: Select("i.KeyId").From("items i").Limit(-1)},
+		{name: "offset without limit", query: Select("i.KeyId").From("items i").Offset(200)},
+		{name: "empty slice argument", query: Select("i.KeyId").From("items i").Where("i.KeyId IN (?)", []string{})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := test.query.Build()
+			if err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
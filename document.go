@@ -0,0 +1,98 @@
+package xd_rsync
+
+import (
+	"time"
+)
+
+type XdSalesDocumentLine struct {
+	DocumentNumber string  `db:"DocumentNumber" dbSelector:"l.DocumentNumber" json:"-"`
+	LineNumber     int     `db:"LineNumber" dbSelector:"l.LineNumber" json:"lineNumber"`
+	SKU            *string `db:"ItemKeyId" dbSelector:"l.ItemKeyId" json:"sku,omitempty"`
+	Description    string  `db:"Description" dbSelector:"IFNULL(l.Description, '') as Description" json:"description"`
+	Quantity       float64 `db:"Quantity" dbSelector:"l.Quantity" json:"quantity"`
+	UnitPrice      float64 `db:"UnitPrice" dbSelector:"l.UnitPrice" json:"unitPrice"`
+	DiscountRate   float64 `db:"DiscountRate" dbSelector:"IFNULL(l.DiscountRate, 0) as DiscountRate" json:"discountRate"`
+	TaxRate        float64 `db:"TaxRate" dbSelector:"IFNULL(l.TaxRate, 0) as TaxRate" json:"taxRate"`
+	NetTotal       float64 `db:"NetTotal" dbSelector:"l.NetTotal" json:"netTotal"`
+}
+
+func (l *XdSalesDocumentLine) GetTableName() string {
+	return "salesdocumentlines l"
+}
+
+func (l *XdSalesDocumentLine) GetJoinExpressions() []string {
+	return nil
+}
+
+func (l *XdSalesDocumentLine) GetConditions() []Condition {
+	return nil
+}
+
+func (l *XdSalesDocumentLine) GetParentKeyColumnName() string {
+	return "l.DocumentNumber"
+}
+
+func (l *XdSalesDocumentLine) GetOrderColumnName() string {
+	return "l.LineNumber"
+}
+
+func (l *XdSalesDocumentLine) GetParentKey() string {
+	return l.DocumentNumber
+}
+
+// Invoice, credit note, order or any other sales document, published with its lines
+type XdSalesDocument struct {
+	Number     string                `db:"Number" dbSelector:"d.Number" json:"number"`
+	Type       string                `db:"DocType" dbSelector:"d.DocType" json:"type"`
+	Date       *time.Time            `db:"DocDate" dbSelector:"d.DocDate" json:"date"`
+	CustomerId *string               `db:"CustomerKeyId" dbSelector:"d.CustomerKeyId" json:"customerId,omitempty"`
+	NetTotal   float64               `db:"NetTotal" dbSelector:"d.NetTotal" json:"netTotal"`
+	TaxTotal   float64               `db:"TaxTotal" dbSelector:"d.TaxTotal" json:"taxTotal"`
+	Total      float64               `db:"Total" dbSelector:"d.Total" json:"total"`
+	SyncStamp  *time.Time            `db:"SyncStamp" dbSelector:"d.SyncStamp as SyncStamp" json:"syncStamp"`
+	Lines      []XdSalesDocumentLine `db:"-" json:"lines"`
+}
+
+func (d *XdSalesDocument) GetEntityName() string {
+	return "documents"
+}
+
+func (d *XdSalesDocument) GetTableName() string {
+	return "salesdocuments d"
+}
+
+func (d *XdSalesDocument) GetJoinExpressions() []string {
+	return nil
+}
+
+func (d *XdSalesDocument) GetConditions() []Condition {
+	return nil
+}
+
+func (d *XdSalesDocument) GetChangeColumns() []string {
+	return []string{"d.SyncStamp"}
+}
+
+func (d *XdSalesDocument) GetPrimaryKeyColumnName() string {
+	return "d.Number"
+}
+
+// Documents are grouped by their number, so the events of a document keep their order on FIFO topics
+func (d *XdSalesDocument) GetKey() string {
+	return d.Number
+}
+
+func (d *XdSalesDocument) GetLastChangedAt() *time.Time {
+	return d.SyncStamp
+}
+
+func (d *XdSalesDocument) GetChildEntity() ChildEntity {
+	return &XdSalesDocumentLine{}
+}
+
+func (d *XdSalesDocument) SetChildren(children []ChildEntity) {
+	d.Lines = make([]XdSalesDocumentLine, 0, len(children))
+	for _, child := range children {
+		d.Lines = append(d.Lines, *child.(*XdSalesDocumentLine))
+	}
+}
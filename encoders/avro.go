@@ -0,0 +1,178 @@
+package encoders
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+type avroCodec struct{}
+
+func (c avroCodec) Name() string {
+	return FORMAT_AVRO
+}
+
+func (c avroCodec) ContentType() string {
+	return "avro/binary"
+}
+
+func (c avroCodec) SchemaType() string {
+	return "AVRO"
+}
+
+func (c avroCodec) IsBinary() bool {
+	return true
+}
+
+func (c avroCodec) RenderSchema(record *recordSchema) (string, error) {
+	bytes, err := json.Marshal(c.renderRecord(record, map[string]bool{}))
+	if err != nil {
+		return "", fmt.Errorf("could not render avro schema: %w", err)
+	}
+
+	return string(bytes), nil
+}
+
+func (c avroCodec) renderRecord(record *recordSchema, defined map[string]bool) interface{} {
+	// Named types can only be defined once, any further usage references them by name
+	if defined[record.FullName()] {
+		return record.FullName()
+	}
+	defined[record.FullName()] = true
+
+	fields := []map[string]interface{}{}
+	for _, field := range record.fields {
+		renderedField := map[string]interface{}{
+			"name": field.name,
+			"type": c.renderType(&field.fieldType, defined),
+		}
+
+		if field.nullable {
+			renderedField["default"] = nil
+		}
+
+		fields = append(fields, renderedField)
+	}
+
+	return map[string]interface{}{
+		"type":      "record",
+		"name":      record.name,
+		"namespace": record.namespace,
+		"fields":    fields,
+	}
+}
+
+func (c avroCodec) renderType(t *fieldType, defined map[string]bool) interface{} {
+	var rendered interface{}
+	switch t.kind {
+	case kindString, kindText, kindDecimal:
+		rendered = "string"
+	case kindDouble:
+		rendered = "double"
+	case kindLong:
+		rendered = "long"
+	case kindBoolean:
+		rendered = "boolean"
+	case kindTimestamp:
+		rendered = map[string]interface{}{
+			"type":        "long",
+			"logicalType": "timestamp-millis",
+		}
+	case kindRecord:
+		rendered = c.renderRecord(t.record, defined)
+	case kindArray:
+		rendered = map[string]interface{}{
+			"type":  "array",
+			"items": c.renderType(t.items, defined),
+		}
+	}
+
+	if t.nullable {
+		return []interface{}{"null", rendered}
+	}
+
+	return rendered
+}
+
+func (c avroCodec) Encode(record *recordSchema, value reflect.Value) ([]byte, error) {
+	return c.encodeRecord([]byte{}, record, value)
+}
+
+func (c avroCodec) encodeRecord(buffer []byte, record *recordSchema, value reflect.Value) ([]byte, error) {
+	var err error
+	for _, field := range record.fields {
+		buffer, err = c.encodeValue(buffer, &field.fieldType, value.FieldByIndex(field.index))
+		if err != nil {
+			return nil, fmt.Errorf("could not encode field %s: %w", field.name, err)
+		}
+	}
+
+	return buffer, nil
+}
+
+func (c avroCodec) encodeValue(buffer []byte, t *fieldType, value reflect.Value) ([]byte, error) {
+	resolved, ok := resolveValue(value)
+	if t.nullable {
+		// Union branch index: 0 is null, 1 is the actual type
+		if !ok {
+			return appendAvroLong(buffer, 0), nil
+		}
+		buffer = appendAvroLong(buffer, 1)
+	} else if !ok {
+		return nil, fmt.Errorf("nil value for non-nullable type")
+	}
+
+	switch t.kind {
+	case kindString:
+		return appendAvroString(buffer, resolved.String()), nil
+	case kindText, kindDecimal:
+		text, err := getTextValue(resolved)
+		if err != nil {
+			return nil, err
+		}
+		return appendAvroString(buffer, text), nil
+	case kindDouble:
+		return binary.LittleEndian.AppendUint64(buffer, math.Float64bits(resolved.Float())), nil
+	case kindLong:
+		if resolved.CanUint() {
+			return appendAvroLong(buffer, int64(resolved.Uint())), nil
+		}
+		return appendAvroLong(buffer, resolved.Int()), nil
+	case kindBoolean:
+		if resolved.Bool() {
+			return append(buffer, 1), nil
+		}
+		return append(buffer, 0), nil
+	case kindTimestamp:
+		return appendAvroLong(buffer, resolved.Interface().(time.Time).UnixMilli()), nil
+	case kindRecord:
+		return c.encodeRecord(buffer, t.record, resolved)
+	case kindArray:
+		var err error
+		if resolved.Len() > 0 {
+			buffer = appendAvroLong(buffer, int64(resolved.Len()))
+			for i := 0; i < resolved.Len(); i++ {
+				buffer, err = c.encodeValue(buffer, t.items, resolved.Index(i))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		// Arrays are terminated by an empty block
+		return appendAvroLong(buffer, 0), nil
+	}
+
+	return nil, fmt.Errorf("unknown field kind %d", t.kind)
+}
+
+func appendAvroLong(buffer []byte, value int64) []byte {
+	return binary.AppendUvarint(buffer, uint64((value<<1)^(value>>63)))
+}
+
+func appendAvroString(buffer []byte, value string) []byte {
+	buffer = appendAvroLong(buffer, int64(len(value)))
+	return append(buffer, value...)
+}
@@ -0,0 +1,144 @@
+package encoders
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Backward incompatible changes prevent consumers using the new schema from reading events written with the
+// previous one. Forward incompatible changes prevent consumers still using the previous schema from reading new events.
+type SchemaChange struct {
+	Path           string
+	Description    string
+	BreaksBackward bool
+	BreaksForward  bool
+}
+
+func (c SchemaChange) IsBreaking() bool {
+	return c.BreaksBackward || c.BreaksForward
+}
+
+func (c SchemaChange) String() string {
+	compatibility := "compatible"
+	if c.BreaksBackward && c.BreaksForward {
+		compatibility = "backward and forward incompatible"
+	} else if c.BreaksBackward {
+		compatibility = "backward incompatible"
+	} else if c.BreaksForward {
+		compatibility = "forward incompatible"
+	}
+
+	return fmt.Sprintf("%s: %s (%s)", c.Path, c.Description, compatibility)
+}
+
+func CompareJsonSchemas(previous *JsonSchema, current *JsonSchema) []SchemaChange {
+	changes := []SchemaChange{}
+	compareJsonSchemaNodes("$", previous, current, &changes)
+	return changes
+}
+
+func compareJsonSchemaNodes(path string, previous *JsonSchema, current *JsonSchema, changes *[]SchemaChange) {
+	removedTypes := getMissingTypes(previous.Type, current.Type)
+	if len(removedTypes) > 0 {
+		*changes = append(*changes, SchemaChange{
+			Path:           path,
+			Description:    "type no longer accepts " + strings.Join(removedTypes, ", "),
+			BreaksBackward: true,
+		})
+	}
+
+	addedTypes := getMissingTypes(current.Type, previous.Type)
+	if len(addedTypes) > 0 {
+		*changes = append(*changes, SchemaChange{
+			Path:          path,
+			Description:   "type now accepts " + strings.Join(addedTypes, ", "),
+			BreaksForward: true,
+		})
+	}
+
+	if previous.Format != current.Format {
+		*changes = append(*changes, SchemaChange{
+			Path:           path,
+			Description:    fmt.Sprintf("format changed from '%s' to '%s'", previous.Format, current.Format),
+			BreaksBackward: true,
+			BreaksForward:  true,
+		})
+	}
+
+	for _, property := range getSortedPropertyNames(previous.Properties) {
+		propertyPath := path + "." + property
+		currentProperty, ok := current.Properties[property]
+		if !ok {
+			*changes = append(*changes, SchemaChange{
+				Path:          propertyPath,
+				Description:   "property removed",
+				BreaksForward: previous.isRequired(property),
+			})
+			continue
+		}
+
+		if !previous.isRequired(property) && current.isRequired(property) {
+			*changes = append(*changes, SchemaChange{
+				Path:           propertyPath,
+				Description:    "property is now required",
+				BreaksBackward: true,
+			})
+		}
+
+		if previous.isRequired(property) && !current.isRequired(property) {
+			*changes = append(*changes, SchemaChange{
+				Path:          propertyPath,
+				Description:   "property is no longer required",
+				BreaksForward: true,
+			})
+		}
+
+		compareJsonSchemaNodes(propertyPath, previous.Properties[property], currentProperty, changes)
+	}
+
+	for _, property := range getSortedPropertyNames(current.Properties) {
+		if _, ok := previous.Properties[property]; ok {
+			continue
+		}
+
+		*changes = append(*changes, SchemaChange{
+			Path:           path + "." + property,
+			Description:    "property added",
+			BreaksBackward: current.isRequired(property),
+		})
+	}
+
+	if previous.Items != nil && current.Items != nil {
+		compareJsonSchemaNodes(path+"[]", previous.Items, current.Items, changes)
+	}
+}
+
+// Returns the types accepted by the source that the target does not accept. Integers are accepted by numbers.
+func getMissingTypes(source JsonSchemaTypes, target JsonSchemaTypes) []string {
+	missingTypes := []string{}
+	for _, sourceType := range source {
+		if slices.Contains(target, sourceType) {
+			continue
+		}
+
+		if sourceType == "integer" && slices.Contains(target, "number") {
+			continue
+		}
+
+		missingTypes = append(missingTypes, sourceType)
+	}
+
+	return missingTypes
+}
+
+func getSortedPropertyNames(properties map[string]*JsonSchema) []string {
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,126 @@
+package encoders
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Product schema with a required SKU and price, and an optional name
+func createTestProductSchema() *JsonSchema {
+	return &JsonSchema{
+		Type: JsonSchemaTypes{"object"},
+		Properties: map[string]*JsonSchema{
+			"sku":   {Type: JsonSchemaTypes{"string"}},
+			"price": {Type: JsonSchemaTypes{"number"}},
+			"name":  {Type: JsonSchemaTypes{"string"}},
+		},
+		Required: []string{"price", "sku"},
+	}
+}
+
+func TestCompareJsonSchemas(t *testing.T) {
+	tests := []struct {
+		name            string
+		change          func(schema *JsonSchema)
+		expectedChanges []SchemaChange
+		isBreaking      bool
+	}{
+		{
+			name:            "no change",
+			change:          func(schema *JsonSchema) {},
+			expectedChanges: []SchemaChange{},
+		},
+		{
+			name: "removed required field",
+			change: func(schema *JsonSchema) {
+				delete(schema.Properties, "price")
+				schema.Required = []string{"sku"}
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.price", Description: "property removed", BreaksForward: true},
+			},
+			isBreaking: true,
+		},
+		{
+			name: "retyped field",
+			change: func(schema *JsonSchema) {
+				schema.Properties["sku"].Type = JsonSchemaTypes{"integer"}
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.sku", Description: "type no longer accepts string", BreaksBackward: true},
+				{Path: "$.sku", Description: "type now accepts integer", BreaksForward: true},
+			},
+			isBreaking: true,
+		},
+		{
+			name: "new required field",
+			change: func(schema *JsonSchema) {
+				schema.Properties["currency"] = &JsonSchema{Type: JsonSchemaTypes{"string"}}
+				schema.Required = append(schema.Required, "currency")
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.currency", Description: "property added", BreaksBackward: true},
+			},
+			isBreaking: true,
+		},
+		{
+			name: "field made optional",
+			change: func(schema *JsonSchema) {
+				schema.Required = []string{"sku"}
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.price", Description: "property is no longer required", BreaksForward: true},
+			},
+			isBreaking: true,
+		},
+		{
+			name: "new optional field",
+			change: func(schema *JsonSchema) {
+				schema.Properties["currency"] = &JsonSchema{Type: JsonSchemaTypes{"string"}}
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.currency", Description: "property added"},
+			},
+		},
+		{
+			name: "removed optional field",
+			change: func(schema *JsonSchema) {
+				delete(schema.Properties, "name")
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.name", Description: "property removed"},
+			},
+		},
+		{
+			name: "field accepting null",
+			change: func(schema *JsonSchema) {
+				schema.Properties["name"].Type = JsonSchemaTypes{"string", "null"}
+			},
+			expectedChanges: []SchemaChange{
+				{Path: "$.name", Description: "type now accepts null", BreaksForward: true},
+			},
+			isBreaking: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := createTestProductSchema()
+			test.change(current)
+
+			changes := CompareJsonSchemas(createTestProductSchema(), current)
+			if !reflect.DeepEqual(changes, test.expectedChanges) {
+				t.Errorf("expected changes %+v, got %+v", test.expectedChanges, changes)
+			}
+
+			isBreaking := false
+			for _, change := range changes {
+				isBreaking = isBreaking || change.IsBreaking()
+			}
+
+			if isBreaking != test.isBreaking {
+				t.Errorf("expected breaking %v, got %v", test.isBreaking, isBreaking)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package encoders
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+)
+
+const (
+	FORMAT_JSON     = "json"
+	FORMAT_PROTOBUF = "protobuf"
+	FORMAT_AVRO     = "avro"
+)
+
+var SUPPORTED_FORMATS = []string{FORMAT_JSON, FORMAT_PROTOBUF, FORMAT_AVRO}
+
+const (
+	ATTRIBUTE_ENCODING          = "encoding"
+	ATTRIBUTE_CONTENT_TYPE      = "contentType"
+	ATTRIBUTE_CONTENT_ENCODING  = "contentEncoding"
+	ATTRIBUTE_SCHEMA_ID         = "schemaId"
+	ATTRIBUTE_SCHEMA_SUBJECT    = "schemaSubject"
+	REGISTRY_FRAMING_MAGIC_BYTE = 0
+)
+
+type codec interface {
+	Name() string
+	ContentType() string
+	SchemaType() string
+	IsBinary() bool
+	RenderSchema(record *recordSchema) (string, error)
+	Encode(record *recordSchema, value reflect.Value) ([]byte, error)
+}
+
+type registeredSchema struct {
+	record  *recordSchema
+	subject string
+	id      int
+}
+
+type Encoder struct {
+	codec    codec
+	registry *SchemaRegistryClient
+	logger   *logger.Logger
+
+	mutex   sync.Mutex
+	schemas map[reflect.Type]*registeredSchema
+}
+
+type EncoderCreationInput struct {
+	Format   string
+	Registry *SchemaRegistryClient
+	Logger   *logger.Logger
+}
+
+func getCodec(format string) (codec, error) {
+	switch format {
+	case "", FORMAT_JSON:
+		return jsonCodec{}, nil
+	case FORMAT_PROTOBUF:
+		return protobufCodec{}, nil
+	case FORMAT_AVRO:
+		return avroCodec{}, nil
+	}
+
+	return nil, fmt.Errorf("encoding format '%s' not supported", format)
+}
+
+func CreateEncoder(input *EncoderCreationInput) (*Encoder, error) {
+	selectedCodec, err := getCodec(input.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := &Encoder{
+		codec:    selectedCodec,
+		registry: input.Registry,
+		logger:   input.Logger,
+		schemas:  map[reflect.Type]*registeredSchema{},
+	}
+
+	encoder.logger.Info("created_message_encoder", "Created message encoder", &map[string]interface{}{
+		"format":           selectedCodec.Name(),
+		"isRegistryActive": encoder.isRegistryActive(),
+	})
+	return encoder, nil
+}
+
+func (e *Encoder) isRegistryActive() bool {
+	return e.registry != nil && len(e.codec.SchemaType()) > 0
+}
+
+func (e *Encoder) getSchema(t reflect.Type) (*registeredSchema, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if schema, ok := e.schemas[t]; ok {
+		return schema, nil
+	}
+
+	record, err := buildRecordSchema(t)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &registeredSchema{
+		record:  record,
+		subject: record.FullName(),
+	}
+
+	if e.isRegistryActive() {
+		renderedSchema, err := e.codec.RenderSchema(record)
+		if err != nil {
+			return nil, err
+		}
+
+		e.logger.Info("init_schema_registration", "Registering message schema", &map[string]interface{}{
+			"subject": schema.subject,
+			"format":  e.codec.Name(),
+		})
+
+		schema.id, err = e.registry.Register(schema.subject, e.codec.SchemaType(), renderedSchema)
+		if err != nil {
+			e.logger.Error("failed_schema_registration", "Failed to register message schema", &map[string]interface{}{
+				"subject": schema.subject,
+				"format":  e.codec.Name(),
+				"error":   err.Error(),
+			})
+			return nil, err
+		}
+
+		e.logger.Info("finished_schema_registration", "Registered message schema", &map[string]interface{}{
+			"subject":  schema.subject,
+			"format":   e.codec.Name(),
+			"schemaId": schema.id,
+		})
+	}
+
+	e.schemas[t] = schema
+	return schema, nil
+}
+
+func (e *Encoder) GetSchema(record interface{}) (string, error) {
+	recordSchema, err := buildRecordSchema(reflect.TypeOf(record))
+	if err != nil {
+		return "", err
+	}
+
+	return e.codec.RenderSchema(recordSchema)
+}
+
+func (e *Encoder) Encode(record interface{}) (*xd_rsync.EncodedMessage, error) {
+	value, ok := resolveValue(reflect.ValueOf(record))
+	if !ok {
+		return nil, fmt.Errorf("could not encode nil record")
+	}
+
+	schema, err := e.getSchema(value.Type())
+	if err != nil {
+		return nil, fmt.Errorf("could not get message schema: %w", err)
+	}
+
+	payload, err := e.codec.Encode(schema.record, value)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode message: %w", err)
+	}
+
+	attributes := map[string]string{
+		ATTRIBUTE_ENCODING:     e.codec.Name(),
+		ATTRIBUTE_CONTENT_TYPE: e.codec.ContentType(),
+	}
+
+	if e.isRegistryActive() {
+		payload = e.frame(schema.id, payload)
+		attributes[ATTRIBUTE_SCHEMA_ID] = strconv.Itoa(schema.id)
+		attributes[ATTRIBUTE_SCHEMA_SUBJECT] = schema.subject
+	}
+
+	// SNS messages must be valid UTF-8 text
+	if e.codec.IsBinary() {
+		attributes[ATTRIBUTE_CONTENT_ENCODING] = "base64"
+		return &xd_rsync.EncodedMessage{
+			Body:       base64.StdEncoding.EncodeToString(payload),
+			Attributes: attributes,
+		}, nil
+	}
+
+	return &xd_rsync.EncodedMessage{
+		Body:       string(payload),
+		Attributes: attributes,
+	}, nil
+}
+
+// Prefixes the payload with the Confluent wire format header so registry-aware consumers can decode it
+func (e *Encoder) frame(schemaId int, payload []byte) []byte {
+	framed := []byte{REGISTRY_FRAMING_MAGIC_BYTE}
+	framed = binary.BigEndian.AppendUint32(framed, uint32(schemaId))
+
+	if e.codec.Name() == FORMAT_PROTOBUF {
+		framed = append(framed, formatProtobufMessageIndexes()...)
+	}
+
+	return append(framed, payload...)
+}
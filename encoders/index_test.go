@@ -0,0 +1,429 @@
+package encoders
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type testWarehouse struct {
+	Id       string  `json:"id"`
+	Quantity float64 `json:"quantity"`
+}
+
+type testProduct struct {
+	Id             string           `json:"id"`
+	Price          xd_rsync.Decimal `json:"price"`
+	Quantity       float64          `json:"quantity"`
+	Count          int              `json:"count"`
+	IsActive       bool             `json:"isActive"`
+	UpdatedAt      *time.Time       `json:"updatedAt"`
+	DiscontinuedAt *time.Time       `json:"discontinuedAt"`
+	Tags           []string         `json:"tags"`
+	Warehouse      *testWarehouse   `json:"warehouse"`
+	Internal       string           `json:"-"`
+}
+
+var testUpdatedAt = time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+func newTestProduct(t *testing.T) *testProduct {
+	price, err := xd_rsync.ParseDecimal("19.90")
+	if err != nil {
+		t.Fatalf("could not parse price: %v", err)
+	}
+
+	return &testProduct{
+		Id:        "A1",
+		Price:     price,
+		Quantity:  2.5,
+		Count:     3,
+		IsActive:  true,
+		UpdatedAt: &testUpdatedAt,
+		Tags:      []string{"new", "sale"},
+		Warehouse: &testWarehouse{Id: "1", Quantity: 4},
+		Internal:  "not published",
+	}
+}
+
+func newTestEncoder(t *testing.T, format string, registry *testSchemaRegistry) *Encoder {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction: true,
+		Level:        "error",
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	var registryClient *SchemaRegistryClient
+	if registry != nil {
+		registryClient, err = CreateSchemaRegistryClient(&SchemaRegistryClientCreationInput{
+			Url: registry.server.URL,
+		})
+		if err != nil {
+			t.Fatalf("could not create schema registry client: %v", err)
+		}
+	}
+
+	encoder, err := CreateEncoder(&EncoderCreationInput{
+		Format:   format,
+		Registry: registryClient,
+		Logger:   testLogger,
+	})
+	if err != nil {
+		t.Fatalf("could not create encoder: %v", err)
+	}
+
+	return encoder
+}
+
+// Decodes the base64 body and splits the Confluent wire format header off, returning the schema ID and payload
+func unframeTestMessage(t *testing.T, message *xd_rsync.EncodedMessage, hasMessageIndexes bool) (int, []byte) {
+	framed, err := base64.StdEncoding.DecodeString(message.Body)
+	if err != nil {
+		t.Fatalf("could not decode body: %v", err)
+	}
+
+	if len(framed) < 5 || framed[0] != REGISTRY_FRAMING_MAGIC_BYTE {
+		t.Fatalf("expected the payload to start with the magic byte, got %v", framed)
+	}
+
+	schemaId := int(binary.BigEndian.Uint32(framed[1:5]))
+	payload := framed[5:]
+	if hasMessageIndexes {
+		if len(payload) == 0 || payload[0] != 0 {
+			t.Fatalf("expected the message indexes of the first message, got %v", payload)
+		}
+		payload = payload[1:]
+	}
+
+	return schemaId, payload
+}
+
+func assertTestAttributes(t *testing.T, message *xd_rsync.EncodedMessage, expected map[string]string) {
+	if !reflect.DeepEqual(message.Attributes, expected) {
+		t.Errorf("expected attributes %v, got %v", expected, message.Attributes)
+	}
+}
+
+func TestEncodeProtobufRoundTrip(t *testing.T) {
+	registry := startTestSchemaRegistry(t)
+	encoder := newTestEncoder(t, FORMAT_PROTOBUF, registry)
+
+	message, err := encoder.Encode(newTestProduct(t))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assertTestAttributes(t, message, map[string]string{
+		ATTRIBUTE_ENCODING:         FORMAT_PROTOBUF,
+		ATTRIBUTE_CONTENT_TYPE:     "application/x-protobuf",
+		ATTRIBUTE_CONTENT_ENCODING: "base64",
+		ATTRIBUTE_SCHEMA_ID:        "1",
+		ATTRIBUTE_SCHEMA_SUBJECT:   "xd_rsync.testProduct",
+	})
+
+	schemaId, payload := unframeTestMessage(t, message, true)
+	registeredSchema := registry.fetchSchema(t, schemaId)
+	expectedSchema, err := encoder.GetSchema(&testProduct{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if registeredSchema.Schema != expectedSchema || registeredSchema.SchemaType != "PROTOBUF" {
+		t.Errorf("expected the rendered protobuf schema to be registered, got %s schema\n%s", registeredSchema.SchemaType, registeredSchema.Schema)
+	}
+
+	// Mirrors the registered schema, which protobuf consumers compile with protoc
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test_product.proto"),
+		Package:    proto.String(SCHEMA_NAMESPACE),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("testProduct"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newTestProtobufField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					newTestProtobufField("price", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					newTestProtobufField("quantity", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+					newTestProtobufField("count", 4, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					newTestProtobufField("isActive", 5, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+					newTestProtobufField("updatedAt", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					newTestProtobufField("discontinuedAt", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					newTestProtobufRepeatedField("tags", 8, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					newTestProtobufField("warehouse", 9, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".xd_rsync.testWarehouse"),
+				},
+			},
+			{
+				Name: proto.String("testWarehouse"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newTestProtobufField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					newTestProtobufField("quantity", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("could not build protobuf descriptor: %v", err)
+	}
+
+	decoded := dynamicpb.NewMessage(file.Messages().ByName("testProduct"))
+	err = proto.Unmarshal(payload, decoded)
+	if err != nil {
+		t.Fatalf("could not decode protobuf payload: %v", err)
+	}
+
+	decodedJson, err := protojson.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("could not convert decoded message: %v", err)
+	}
+
+	fields := map[string]interface{}{}
+	json.Unmarshal(decodedJson, &fields)
+	expectedFields := map[string]interface{}{
+		"id":        "A1",
+		"price":     "19.90",
+		"quantity":  2.5,
+		"count":     "3",
+		"isActive":  true,
+		"updatedAt": "2024-05-01T10:30:00Z",
+		"tags":      []interface{}{"new", "sale"},
+		"warehouse": map[string]interface{}{"id": "1", "quantity": 4.0},
+	}
+	if !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("expected decoded message %v, got %v", expectedFields, fields)
+	}
+}
+
+func newTestProtobufField(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     fieldType.Enum(),
+	}
+	if len(typeName) > 0 {
+		field.TypeName = proto.String(typeName)
+	}
+
+	return field
+}
+
+func newTestProtobufRepeatedField(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	field := newTestProtobufField(name, number, fieldType, "")
+	field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	return field
+}
+
+func TestEncodeAvroRoundTrip(t *testing.T) {
+	registry := startTestSchemaRegistry(t)
+	encoder := newTestEncoder(t, FORMAT_AVRO, registry)
+
+	message, err := encoder.Encode(newTestProduct(t))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assertTestAttributes(t, message, map[string]string{
+		ATTRIBUTE_ENCODING:         FORMAT_AVRO,
+		ATTRIBUTE_CONTENT_TYPE:     "avro/binary",
+		ATTRIBUTE_CONTENT_ENCODING: "base64",
+		ATTRIBUTE_SCHEMA_ID:        "1",
+		ATTRIBUTE_SCHEMA_SUBJECT:   "xd_rsync.testProduct",
+	})
+
+	schemaId, payload := unframeTestMessage(t, message, false)
+	registeredSchema := registry.fetchSchema(t, schemaId)
+	// Avro is the registry default, so its type is left out
+	if len(registeredSchema.SchemaType) > 0 {
+		t.Errorf("expected no schema type for avro, got %s", registeredSchema.SchemaType)
+	}
+
+	var schema interface{}
+	err = json.Unmarshal([]byte(registeredSchema.Schema), &schema)
+	if err != nil {
+		t.Fatalf("could not parse registered avro schema: %v", err)
+	}
+
+	decoder := &testAvroDecoder{payload: payload, records: map[string]interface{}{}}
+	decoded := decoder.decode(t, schema)
+	if len(decoder.payload) > 0 {
+		t.Errorf("expected the whole payload to be decoded, %d bytes left", len(decoder.payload))
+	}
+
+	expected := map[string]interface{}{
+		"id":             "A1",
+		"price":          "19.90",
+		"quantity":       2.5,
+		"count":          int64(3),
+		"isActive":       true,
+		"updatedAt":      testUpdatedAt.UnixMilli(),
+		"discontinuedAt": nil,
+		"tags":           []interface{}{"new", "sale"},
+		"warehouse":      map[string]interface{}{"id": "1", "quantity": 4.0},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected decoded record %v, got %v", expected, decoded)
+	}
+}
+
+// Reads Avro binary data following a parsed schema, as a consumer would
+type testAvroDecoder struct {
+	payload []byte
+	// Named records defined so far, which later fields reference by name
+	records map[string]interface{}
+}
+
+func (d *testAvroDecoder) readLong(t *testing.T) int64 {
+	value, length := binary.Uvarint(d.payload)
+	if length <= 0 {
+		t.Fatalf("could not read avro long from %v", d.payload)
+	}
+	d.payload = d.payload[length:]
+
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (d *testAvroDecoder) readBytes(t *testing.T, length int) []byte {
+	if length > len(d.payload) {
+		t.Fatalf("could not read %d bytes from %v", length, d.payload)
+	}
+	value := d.payload[:length]
+	d.payload = d.payload[length:]
+
+	return value
+}
+
+func (d *testAvroDecoder) decode(t *testing.T, schema interface{}) interface{} {
+	switch typedSchema := schema.(type) {
+	case []interface{}:
+		return d.decode(t, typedSchema[d.readLong(t)])
+	case map[string]interface{}:
+		switch typedSchema["type"] {
+		case "record":
+			d.records[typedSchema["namespace"].(string)+"."+typedSchema["name"].(string)] = typedSchema
+			record := map[string]interface{}{}
+			for _, field := range typedSchema["fields"].([]interface{}) {
+				field := field.(map[string]interface{})
+				record[field["name"].(string)] = d.decode(t, field["type"])
+			}
+			return record
+		case "array":
+			items := []interface{}{}
+			for count := d.readLong(t); count != 0; count = d.readLong(t) {
+				for i := int64(0); i < count; i++ {
+					items = append(items, d.decode(t, typedSchema["items"]))
+				}
+			}
+			return items
+		}
+		return d.decode(t, typedSchema["type"])
+	case string:
+		switch typedSchema {
+		case "null":
+			return nil
+		case "string":
+			return string(d.readBytes(t, int(d.readLong(t))))
+		case "long":
+			return d.readLong(t)
+		case "double":
+			return math.Float64frombits(binary.LittleEndian.Uint64(d.readBytes(t, 8)))
+		case "boolean":
+			return d.readBytes(t, 1)[0] == 1
+		}
+		if record, isDefined := d.records[typedSchema]; isDefined {
+			return d.decode(t, record)
+		}
+	}
+
+	t.Fatalf("avro schema %v is not supported", schema)
+	return nil
+}
+
+func TestEncodeRegistersSchemaOnce(t *testing.T) {
+	registry := startTestSchemaRegistry(t)
+	encoder := newTestEncoder(t, FORMAT_PROTOBUF, registry)
+
+	for i := 0; i < 3; i++ {
+		message, err := encoder.Encode(newTestProduct(t))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if message.Attributes[ATTRIBUTE_SCHEMA_ID] != "1" {
+			t.Errorf("expected schema ID 1, got %s", message.Attributes[ATTRIBUTE_SCHEMA_ID])
+		}
+	}
+
+	if registry.registrations != 1 {
+		t.Errorf("expected the schema to be registered once, got %d registrations", registry.registrations)
+	}
+}
+
+func TestEncodeWithoutRegistry(t *testing.T) {
+	tests := []struct {
+		format             string
+		expectedAttributes map[string]string
+	}{
+		{
+			format: FORMAT_JSON,
+			expectedAttributes: map[string]string{
+				ATTRIBUTE_ENCODING:     FORMAT_JSON,
+				ATTRIBUTE_CONTENT_TYPE: "application/json",
+			},
+		},
+		{
+			format: FORMAT_AVRO,
+			expectedAttributes: map[string]string{
+				ATTRIBUTE_ENCODING:         FORMAT_AVRO,
+				ATTRIBUTE_CONTENT_TYPE:     "avro/binary",
+				ATTRIBUTE_CONTENT_ENCODING: "base64",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			message, err := newTestEncoder(t, test.format, nil).Encode(newTestProduct(t))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			assertTestAttributes(t, message, test.expectedAttributes)
+		})
+	}
+
+	// JSON messages are not framed, so they are the plain JSON of the record
+	message, err := newTestEncoder(t, FORMAT_JSON, startTestSchemaRegistry(t)).Encode(newTestProduct(t))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	fields := map[string]interface{}{}
+	err = json.Unmarshal([]byte(message.Body), &fields)
+	if err != nil {
+		t.Fatalf("could not parse JSON body: %v", err)
+	}
+	if fields["price"] != "19.90" || fields["count"] != 3.0 || fields["id"] != "A1" {
+		t.Errorf("expected the JSON body of the record, got %s", message.Body)
+	}
+	if _, hasSchemaId := message.Attributes[ATTRIBUTE_SCHEMA_ID]; hasSchemaId {
+		t.Errorf("expected no schema ID for JSON, got %s", strconv.Quote(message.Attributes[ATTRIBUTE_SCHEMA_ID]))
+	}
+}
@@ -0,0 +1,48 @@
+package encoders
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type jsonCodec struct{}
+
+func (c jsonCodec) Name() string {
+	return FORMAT_JSON
+}
+
+func (c jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (c jsonCodec) SchemaType() string {
+	return ""
+}
+
+func (c jsonCodec) IsBinary() bool {
+	return false
+}
+
+func (c jsonCodec) RenderSchema(record *recordSchema) (string, error) {
+	schema, err := generateJsonSchemaFromRecord(record)
+	if err != nil {
+		return "", err
+	}
+
+	bytes, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("could not render JSON schema: %w", err)
+	}
+
+	return string(bytes), nil
+}
+
+func (c jsonCodec) Encode(record *recordSchema, value reflect.Value) ([]byte, error) {
+	bytes, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("could not encode record to JSON: %w", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,139 @@
+package encoders
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+const JSON_SCHEMA_DIALECT = "https://json-schema.org/draft/2020-12/schema"
+
+type JsonSchemaTypes []string
+
+func (t JsonSchemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+
+	return json.Marshal([]string(t))
+}
+
+func (t *JsonSchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = JsonSchemaTypes{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("schema type must be a string or a list of strings: %w", err)
+	}
+
+	*t = multiple
+	return nil
+}
+
+type JsonSchema struct {
+	Schema     string                 `json:"$schema,omitempty"`
+	Id         string                 `json:"$id,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Type       JsonSchemaTypes        `json:"type,omitempty"`
+	Format     string                 `json:"format,omitempty"`
+	Properties map[string]*JsonSchema `json:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	Items      *JsonSchema            `json:"items,omitempty"`
+}
+
+func GenerateJsonSchema(record interface{}) (*JsonSchema, error) {
+	recordSchema, err := buildRecordSchema(reflect.TypeOf(record))
+	if err != nil {
+		return nil, err
+	}
+
+	return generateJsonSchemaFromRecord(recordSchema)
+}
+
+func generateJsonSchemaFromRecord(record *recordSchema) (*JsonSchema, error) {
+	schema, err := renderJsonSchemaRecord(record, map[*recordSchema]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Schema = JSON_SCHEMA_DIALECT
+	schema.Id = record.FullName()
+	schema.Title = record.name
+	return schema, nil
+}
+
+func renderJsonSchemaRecord(record *recordSchema, ancestors map[*recordSchema]bool) (*JsonSchema, error) {
+	if ancestors[record] {
+		return nil, fmt.Errorf("recursive record %s is not supported", record.FullName())
+	}
+	ancestors[record] = true
+	defer delete(ancestors, record)
+
+	schema := &JsonSchema{
+		Type:       JsonSchemaTypes{"object"},
+		Properties: map[string]*JsonSchema{},
+		Required:   []string{},
+	}
+
+	for _, field := range record.fields {
+		fieldSchema, err := renderJsonSchemaType(&field.fieldType, ancestors)
+		if err != nil {
+			return nil, err
+		}
+
+		schema.Properties[field.name] = fieldSchema
+		if !field.isOptional {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+
+	return schema, nil
+}
+
+func renderJsonSchemaType(t *fieldType, ancestors map[*recordSchema]bool) (*JsonSchema, error) {
+	var schema *JsonSchema
+	switch t.kind {
+	case kindString, kindText:
+		schema = &JsonSchema{Type: JsonSchemaTypes{"string"}}
+	case kindDouble:
+		schema = &JsonSchema{Type: JsonSchemaTypes{"number"}}
+	case kindDecimal:
+		schema = &JsonSchema{Type: JsonSchemaTypes{"string", "number"}}
+	case kindLong:
+		schema = &JsonSchema{Type: JsonSchemaTypes{"integer"}}
+	case kindBoolean:
+		schema = &JsonSchema{Type: JsonSchemaTypes{"boolean"}}
+	case kindTimestamp:
+		schema = &JsonSchema{Type: JsonSchemaTypes{"string"}, Format: "date-time"}
+	case kindRecord:
+		record, err := renderJsonSchemaRecord(t.record, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		schema = record
+	case kindArray:
+		items, err := renderJsonSchemaType(t.items, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		schema = &JsonSchema{Type: JsonSchemaTypes{"array"}, Items: items}
+	default:
+		return nil, fmt.Errorf("unknown field kind %d", t.kind)
+	}
+
+	// Nil slices are marshalled as null just like nil pointers
+	if t.nullable || t.kind == kindArray {
+		schema.Type = append(schema.Type, "null")
+	}
+
+	return schema, nil
+}
+
+func (s *JsonSchema) isRequired(property string) bool {
+	return slices.Contains(s.Required, property)
+}
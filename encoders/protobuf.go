@@ -0,0 +1,266 @@
+package encoders
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type protobufCodec struct{}
+
+func (c protobufCodec) Name() string {
+	return FORMAT_PROTOBUF
+}
+
+func (c protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (c protobufCodec) SchemaType() string {
+	return "PROTOBUF"
+}
+
+func (c protobufCodec) IsBinary() bool {
+	return true
+}
+
+func (c protobufCodec) RenderSchema(record *recordSchema) (string, error) {
+	// The root record must be the first message of the file, as the message indexes sent
+	// along with the registry framing always point to it
+	records := []*recordSchema{}
+	collectRecords(record, &records, map[*recordSchema]bool{})
+
+	usesTimestamp := false
+	messages := []string{}
+	for _, r := range records {
+		lines := []string{"message " + r.name + " {"}
+		for fieldIndex, field := range r.fields {
+			typeName, err := c.renderType(&field.fieldType)
+			if err != nil {
+				return "", fmt.Errorf("could not render protobuf schema for field %s.%s: %w", r.name, field.name, err)
+			}
+
+			if field.kind == kindTimestamp || (field.kind == kindArray && field.items.kind == kindTimestamp) {
+				usesTimestamp = true
+			}
+
+			label := ""
+			if field.kind == kindArray {
+				label = "repeated "
+			} else if field.nullable && c.isScalar(field.kind) {
+				label = "optional "
+			}
+
+			lines = append(lines, fmt.Sprintf("  %s%s %s = %d;", label, typeName, field.name, fieldIndex+1))
+		}
+		lines = append(lines, "}")
+
+		messages = append(messages, strings.Join(lines, "\n"))
+	}
+
+	header := []string{
+		`syntax = "proto3";`,
+		"package " + record.namespace + ";",
+	}
+	if usesTimestamp {
+		header = append(header, `import "google/protobuf/timestamp.proto";`)
+	}
+
+	return strings.Join(header, "\n") + "\n\n" + strings.Join(messages, "\n\n") + "\n", nil
+}
+
+func (c protobufCodec) renderType(t *fieldType) (string, error) {
+	switch t.kind {
+	case kindString, kindText, kindDecimal:
+		return "string", nil
+	case kindDouble:
+		return "double", nil
+	case kindLong:
+		return "int64", nil
+	case kindBoolean:
+		return "bool", nil
+	case kindTimestamp:
+		return "google.protobuf.Timestamp", nil
+	case kindRecord:
+		return t.record.name, nil
+	case kindArray:
+		if t.items.kind == kindArray {
+			return "", fmt.Errorf("nested arrays are not supported")
+		}
+		return c.renderType(t.items)
+	}
+
+	return "", fmt.Errorf("unknown field kind %d", t.kind)
+}
+
+func (c protobufCodec) isScalar(kind fieldKind) bool {
+	return kind != kindRecord && kind != kindTimestamp && kind != kindArray
+}
+
+func (c protobufCodec) Encode(record *recordSchema, value reflect.Value) ([]byte, error) {
+	return c.encodeRecord([]byte{}, record, value)
+}
+
+func (c protobufCodec) encodeRecord(buffer []byte, record *recordSchema, value reflect.Value) ([]byte, error) {
+	var err error
+	for fieldIndex, field := range record.fields {
+		fieldNumber := protowire.Number(fieldIndex + 1)
+		fieldValue := value.FieldByIndex(field.index)
+
+		if field.kind == kindArray {
+			buffer, err = c.encodeRepeated(buffer, fieldNumber, field.items, fieldValue)
+		} else {
+			buffer, err = c.encodeField(buffer, fieldNumber, &field.fieldType, fieldValue)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not encode field %s: %w", field.name, err)
+		}
+	}
+
+	return buffer, nil
+}
+
+func (c protobufCodec) encodeField(buffer []byte, number protowire.Number, t *fieldType, value reflect.Value) ([]byte, error) {
+	resolved, ok := resolveValue(value)
+	if !ok {
+		return buffer, nil
+	}
+
+	// Proto3 does not emit default values unless the field is explicitly optional
+	if !t.nullable && c.isScalar(t.kind) && resolved.IsZero() {
+		return buffer, nil
+	}
+
+	switch t.kind {
+	case kindString:
+		buffer = protowire.AppendTag(buffer, number, protowire.BytesType)
+		return protowire.AppendString(buffer, resolved.String()), nil
+	case kindText, kindDecimal:
+		text, err := getTextValue(resolved)
+		if err != nil {
+			return nil, err
+		}
+		buffer = protowire.AppendTag(buffer, number, protowire.BytesType)
+		return protowire.AppendString(buffer, text), nil
+	case kindDouble:
+		buffer = protowire.AppendTag(buffer, number, protowire.Fixed64Type)
+		return protowire.AppendFixed64(buffer, math.Float64bits(resolved.Float())), nil
+	case kindLong:
+		buffer = protowire.AppendTag(buffer, number, protowire.VarintType)
+		if resolved.CanUint() {
+			return protowire.AppendVarint(buffer, resolved.Uint()), nil
+		}
+		return protowire.AppendVarint(buffer, uint64(resolved.Int())), nil
+	case kindBoolean:
+		buffer = protowire.AppendTag(buffer, number, protowire.VarintType)
+		return protowire.AppendVarint(buffer, protowire.EncodeBool(resolved.Bool())), nil
+	case kindTimestamp:
+		ts := resolved.Interface().(time.Time)
+		if ts.IsZero() && !t.nullable {
+			return buffer, nil
+		}
+		buffer = protowire.AppendTag(buffer, number, protowire.BytesType)
+		return protowire.AppendBytes(buffer, encodeProtobufTimestamp(ts)), nil
+	case kindRecord:
+		nested, err := c.encodeRecord([]byte{}, t.record, resolved)
+		if err != nil {
+			return nil, err
+		}
+		buffer = protowire.AppendTag(buffer, number, protowire.BytesType)
+		return protowire.AppendBytes(buffer, nested), nil
+	}
+
+	return nil, fmt.Errorf("unknown field kind %d", t.kind)
+}
+
+func (c protobufCodec) encodeRepeated(buffer []byte, number protowire.Number, items *fieldType, value reflect.Value) ([]byte, error) {
+	resolved, ok := resolveValue(value)
+	if !ok || resolved.Len() == 0 {
+		return buffer, nil
+	}
+
+	// Numeric repeated fields are packed by default in proto3
+	if items.kind == kindDouble || items.kind == kindLong || items.kind == kindBoolean {
+		packed := []byte{}
+		for i := 0; i < resolved.Len(); i++ {
+			item, ok := resolveValue(resolved.Index(i))
+			if !ok {
+				continue
+			}
+
+			switch items.kind {
+			case kindDouble:
+				packed = protowire.AppendFixed64(packed, math.Float64bits(item.Float()))
+			case kindLong:
+				if item.CanUint() {
+					packed = protowire.AppendVarint(packed, item.Uint())
+				} else {
+					packed = protowire.AppendVarint(packed, uint64(item.Int()))
+				}
+			case kindBoolean:
+				packed = protowire.AppendVarint(packed, protowire.EncodeBool(item.Bool()))
+			}
+		}
+
+		buffer = protowire.AppendTag(buffer, number, protowire.BytesType)
+		return protowire.AppendBytes(buffer, packed), nil
+	}
+
+	// Every item has to be written, including default values, otherwise positions would shift
+	itemType := *items
+	itemType.nullable = true
+
+	var err error
+	for i := 0; i < resolved.Len(); i++ {
+		buffer, err = c.encodeField(buffer, number, &itemType, resolved.Index(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer, nil
+}
+
+func encodeProtobufTimestamp(ts time.Time) []byte {
+	buffer := []byte{}
+	if ts.Unix() != 0 {
+		buffer = protowire.AppendTag(buffer, 1, protowire.VarintType)
+		buffer = protowire.AppendVarint(buffer, uint64(ts.Unix()))
+	}
+
+	if ts.Nanosecond() != 0 {
+		buffer = protowire.AppendTag(buffer, 2, protowire.VarintType)
+		buffer = protowire.AppendVarint(buffer, uint64(ts.Nanosecond()))
+	}
+
+	return buffer
+}
+
+func collectRecords(record *recordSchema, records *[]*recordSchema, visited map[*recordSchema]bool) {
+	if visited[record] {
+		return
+	}
+	visited[record] = true
+	*records = append(*records, record)
+
+	for _, field := range record.fields {
+		t := &field.fieldType
+		if t.kind == kindArray {
+			t = t.items
+		}
+
+		if t.kind == kindRecord {
+			collectRecords(t.record, records, visited)
+		}
+	}
+}
+
+func formatProtobufMessageIndexes() []byte {
+	// Confluent framing: array of message indexes, shortened to a single 0 for the first message
+	return []byte{0}
+}
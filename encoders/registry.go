@@ -0,0 +1,111 @@
+package encoders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const SCHEMA_REGISTRY_CONTENT_TYPE = "application/vnd.schemaregistry.v1+json"
+
+type SchemaRegistryClient struct {
+	baseUrl    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+type SchemaRegistryClientCreationInput struct {
+	Url      string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+type schemaRegistrationRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type schemaRegistrationResponse struct {
+	Id int `json:"id"`
+}
+
+func CreateSchemaRegistryClient(input *SchemaRegistryClientCreationInput) (*SchemaRegistryClient, error) {
+	if len(input.Url) == 0 {
+		return nil, fmt.Errorf("schema registry URL not specified")
+	}
+
+	if _, err := url.ParseRequestURI(input.Url); err != nil {
+		return nil, fmt.Errorf("schema registry URL is not valid: %w", err)
+	}
+
+	timeout := input.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &SchemaRegistryClient{
+		baseUrl:  strings.TrimSuffix(input.Url, "/"),
+		username: input.Username,
+		password: input.Password,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// Registers the schema under the subject, returning the existing ID if it was registered before
+func (c *SchemaRegistryClient) Register(subject string, schemaType string, schema string) (int, error) {
+	// Avro is the registry default and older registries reject the explicit type
+	if schemaType == "AVRO" {
+		schemaType = ""
+	}
+
+	requestBody, err := json.Marshal(schemaRegistrationRequest{
+		Schema:     schema,
+		SchemaType: schemaType,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not build schema registration request: %w", err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, c.baseUrl+"/subjects/"+url.PathEscape(subject)+"/versions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return 0, fmt.Errorf("could not build schema registration request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", SCHEMA_REGISTRY_CONTENT_TYPE)
+	request.Header.Set("Accept", SCHEMA_REGISTRY_CONTENT_TYPE)
+	if len(c.username) > 0 {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("could not register schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("could not read schema registry response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("schema was not registered. status: %d api response: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	registration := &schemaRegistrationResponse{}
+	err = json.Unmarshal(bodyBytes, registration)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse schema registry response: %w", err)
+	}
+
+	return registration.Id, nil
+}
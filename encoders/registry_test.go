@@ -0,0 +1,153 @@
+package encoders
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testRegisteredSchema struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+	subject    string
+}
+
+// Stand-in for a Confluent-compatible schema registry, supporting the registration of schemas under a subject and
+// their lookup by ID, as done by consumers
+type testSchemaRegistry struct {
+	server        *httptest.Server
+	mutex         sync.Mutex
+	schemas       []*testRegisteredSchema
+	registrations int
+}
+
+func startTestSchemaRegistry(t *testing.T) *testSchemaRegistry {
+	registry := &testSchemaRegistry{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", registry.handleRegister)
+	mux.HandleFunc("GET /schemas/ids/{id}", registry.handleGetSchema)
+	registry.server = httptest.NewServer(mux)
+	t.Cleanup(registry.server.Close)
+
+	return registry
+}
+
+func (r *testSchemaRegistry) writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", SCHEMA_REGISTRY_CONTENT_TYPE)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (r *testSchemaRegistry) handleRegister(w http.ResponseWriter, request *http.Request) {
+	if !strings.HasPrefix(request.Header.Get("Content-Type"), SCHEMA_REGISTRY_CONTENT_TYPE) {
+		r.writeJson(w, http.StatusUnsupportedMediaType, map[string]interface{}{"error_code": 415})
+		return
+	}
+
+	schema := &testRegisteredSchema{}
+	err := json.NewDecoder(request.Body).Decode(schema)
+	if err != nil || len(schema.Schema) == 0 {
+		r.writeJson(w, http.StatusUnprocessableEntity, map[string]interface{}{"error_code": 42201})
+		return
+	}
+	schema.subject = request.PathValue("subject")
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.registrations++
+	for index, registered := range r.schemas {
+		if *registered == *schema {
+			r.writeJson(w, http.StatusOK, map[string]interface{}{"id": index + 1})
+			return
+		}
+	}
+
+	r.schemas = append(r.schemas, schema)
+	r.writeJson(w, http.StatusOK, map[string]interface{}{"id": len(r.schemas)})
+}
+
+func (r *testSchemaRegistry) handleGetSchema(w http.ResponseWriter, request *http.Request) {
+	schema := r.getSchema(request.PathValue("id"))
+	if schema == nil {
+		r.writeJson(w, http.StatusNotFound, map[string]interface{}{"error_code": 40403})
+		return
+	}
+
+	r.writeJson(w, http.StatusOK, schema)
+}
+
+func (r *testSchemaRegistry) getSchema(id string) *testRegisteredSchema {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	index, err := strconv.Atoi(id)
+	if err != nil || index < 1 || index > len(r.schemas) {
+		return nil
+	}
+
+	return r.schemas[index-1]
+}
+
+// Looks the schema up by ID, as a consumer would
+func (r *testSchemaRegistry) fetchSchema(t *testing.T, id int) *testRegisteredSchema {
+	resp, err := http.Get(r.server.URL + "/schemas/ids/" + strconv.Itoa(id))
+	if err != nil {
+		t.Fatalf("could not fetch schema %d: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("could not fetch schema %d: status %d", id, resp.StatusCode)
+	}
+
+	schema := &testRegisteredSchema{}
+	err = json.NewDecoder(resp.Body).Decode(schema)
+	if err != nil {
+		t.Fatalf("could not parse schema %d: %v", id, err)
+	}
+
+	return schema
+}
+
+func TestSchemaRegistryClientRegister(t *testing.T) {
+	registry := startTestSchemaRegistry(t)
+	client, err := CreateSchemaRegistryClient(&SchemaRegistryClientCreationInput{
+		Url: registry.server.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	firstId, err := client.Register("xd_rsync.XdProduct", "PROTOBUF", `syntax = "proto3";`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	secondId, err := client.Register("xd_rsync.XdProduct", "PROTOBUF", `syntax = "proto3";`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if firstId != 1 || secondId != firstId {
+		t.Errorf("expected the same schema to keep ID 1, got %d and %d", firstId, secondId)
+	}
+
+	_, err = client.Register("xd_rsync.XdProduct", "AVRO", "")
+	if err == nil {
+		t.Error("expected the registry rejection to be an error, got none")
+	}
+}
+
+func TestCreateSchemaRegistryClientValidatesUrl(t *testing.T) {
+	for _, url := range []string{"", "registry"} {
+		_, err := CreateSchemaRegistryClient(&SchemaRegistryClientCreationInput{Url: url})
+		if err == nil {
+			t.Errorf("expected URL '%s' to be rejected", url)
+		}
+	}
+}
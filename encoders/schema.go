@@ -0,0 +1,204 @@
+package encoders
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+const SCHEMA_NAMESPACE = "xd_rsync"
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindDouble
+	kindLong
+	kindBoolean
+	kindTimestamp
+	kindText
+	kindRecord
+	kindArray
+	// Exact decimal, written in JSON as a string or a number depending on the money settings
+	kindDecimal
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var decimalType = reflect.TypeOf(xd_rsync.Decimal{})
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type fieldType struct {
+	kind     fieldKind
+	nullable bool
+	record   *recordSchema
+	items    *fieldType
+}
+
+type schemaField struct {
+	name  string
+	index []int
+	// Left out of the JSON payload when empty, through the omitempty option
+	isOptional bool
+	fieldType
+}
+
+type recordSchema struct {
+	name      string
+	namespace string
+	fields    []schemaField
+}
+
+func (r *recordSchema) FullName() string {
+	return r.namespace + "." + r.name
+}
+
+func buildRecordSchema(t reflect.Type) (*recordSchema, error) {
+	return buildRecordSchemaWithCache(t, map[reflect.Type]*recordSchema{})
+}
+
+func buildRecordSchemaWithCache(t reflect.Type, known map[reflect.Type]*recordSchema) (*recordSchema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("could not build schema: %s is not a struct", t.String())
+	}
+
+	if record, ok := known[t]; ok {
+		return record, nil
+	}
+
+	record := &recordSchema{
+		name:      t.Name(),
+		namespace: SCHEMA_NAMESPACE,
+		fields:    []schemaField{},
+	}
+	known[t] = record
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		name := getJsonFieldName(structField)
+		if name == "-" {
+			continue
+		}
+
+		resolvedType, err := resolveFieldType(structField.Type, known)
+		if err != nil {
+			return nil, fmt.Errorf("could not build schema for field %s.%s: %w", t.Name(), structField.Name, err)
+		}
+
+		_, options, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		record.fields = append(record.fields, schemaField{
+			name:       name,
+			index:      structField.Index,
+			isOptional: slices.Contains(strings.Split(options, ","), "omitempty"),
+			fieldType:  *resolvedType,
+		})
+	}
+
+	return record, nil
+}
+
+func resolveFieldType(t reflect.Type, known map[reflect.Type]*recordSchema) (*fieldType, error) {
+	if t.Kind() == reflect.Pointer {
+		resolvedType, err := resolveFieldType(t.Elem(), known)
+		if err != nil {
+			return nil, err
+		}
+
+		resolvedType.nullable = true
+		return resolvedType, nil
+	}
+
+	if t == timeType {
+		return &fieldType{kind: kindTimestamp}, nil
+	}
+
+	if t == decimalType {
+		return &fieldType{kind: kindDecimal}, nil
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &fieldType{kind: kindText}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &fieldType{kind: kindString}, nil
+	case reflect.Float32, reflect.Float64:
+		return &fieldType{kind: kindDouble}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &fieldType{kind: kindLong}, nil
+	case reflect.Bool:
+		return &fieldType{kind: kindBoolean}, nil
+	case reflect.Struct:
+		record, err := buildRecordSchemaWithCache(t, known)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fieldType{kind: kindRecord, record: record}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := resolveFieldType(t.Elem(), known)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fieldType{kind: kindArray, items: items}, nil
+	}
+
+	return nil, fmt.Errorf("type %s is not supported", t.String())
+}
+
+func getJsonFieldName(structField reflect.StructField) string {
+	tag := structField.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	if len(name) == 0 {
+		return structField.Name
+	}
+
+	return name
+}
+
+// Walks down pointers, returning false when a nil pointer is found
+func resolveValue(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+
+	return value, true
+}
+
+func getTextValue(value reflect.Value) (string, error) {
+	var marshaler encoding.TextMarshaler
+	if value.Type().Implements(textMarshalerType) {
+		marshaler = value.Interface().(encoding.TextMarshaler)
+	} else if value.CanAddr() {
+		marshaler = value.Addr().Interface().(encoding.TextMarshaler)
+	} else {
+		copied := reflect.New(value.Type())
+		copied.Elem().Set(value)
+		marshaler = copied.Interface().(encoding.TextMarshaler)
+	}
+
+	text, err := marshaler.MarshalText()
+	if err != nil {
+		return "", err
+	}
+
+	return string(text), nil
+}
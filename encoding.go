@@ -0,0 +1,10 @@
+package xd_rsync
+
+type EncodedMessage struct {
+	Body       string
+	Attributes map[string]string
+}
+
+type MessageEncoder interface {
+	Encode(record interface{}) (*EncodedMessage, error)
+}
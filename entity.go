@@ -0,0 +1,126 @@
+package xd_rsync
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Record of an XD table whose changes are captured and published. The selected columns are read from the
+// `dbSelector` struct tags, and the `db` tags map them back to the struct fields.
+type Entity interface {
+	// Name used in logs, metrics and spans, e.g. "products"
+	GetEntityName() string
+	// Table expression including its alias, e.g. "items i"
+	GetTableName() string
+	GetJoinExpressions() []string
+	// Conditions every synchronised record must meet
+	GetConditions() []Condition
+	// Timestamp columns compared against the checkpoint to find changed records
+	GetChangeColumns() []string
+	GetPrimaryKeyColumnName() string
+	// Primary key of the record, used as the message group ID
+	GetKey() string
+	// Returns the most recent of the change tracking timestamps
+	GetLastChangedAt() *time.Time
+}
+
+// Records read from another table and embedded into each record of an entity, e.g. the lines of a document
+type ParentEntity interface {
+	Entity
+	// Prototype of the embedded records
+	GetChildEntity() ChildEntity
+	SetChildren(children []ChildEntity)
+}
+
+type ChildEntity interface {
+	GetTableName() string
+	GetJoinExpressions() []string
+	GetConditions() []Condition
+	// Column holding the primary key of the parent record
+	GetParentKeyColumnName() string
+	// Column ordering the records within their parent, e.g. the line number
+	GetOrderColumnName() string
+	GetParentKey() string
+}
+
+// SQL condition whose "?" placeholders are bound to the given arguments. Slice arguments are expanded, so they
+// can be used with IN (?).
+type Condition struct {
+	Expression string
+	Args       []interface{}
+}
+
+func NewCondition(expression string, args ...interface{}) Condition {
+	return Condition{
+		Expression: expression,
+		Args:       args,
+	}
+}
+
+// Selects the records of an entity
+type EntityQuery struct {
+	Entity Entity
+	// Added to the conditions of the entity, e.g. from the configuration
+	Conditions []Condition
+	// Only selects the records changed after this time, when set
+	ChangedAfter *time.Time
+	// Added to the join expressions of the entity, e.g. tables whose changes are tracked from the configuration
+	Joins []string
+	// Added to the change tracking columns of the entity, usually from the added joins
+	ChangeColumns []string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Returns the column selectors of an entity, in the order of its fields. Nested structs without a selector are
+// read from their own fields, whose selectors must alias them as "<db tag of the struct>.<db tag of the field>".
+func GetEntityColumns(record interface{}) []string {
+	return getStructColumns(reflect.TypeOf(record).Elem())
+}
+
+func getStructColumns(structType reflect.Type) []string {
+	columnNames := []string{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		selector := field.Tag.Get("dbSelector")
+		if len(selector) > 0 {
+			columnNames = append(columnNames, selector)
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != timeType && len(field.Tag.Get("db")) > 0 {
+			columnNames = append(columnNames, getStructColumns(field.Type)...)
+		}
+	}
+
+	return columnNames
+}
+
+func GetEntityColumnsQuerySelectors(record interface{}) string {
+	return strings.Join(GetEntityColumns(record), ", ")
+}
+
+// Returns the most recent of the given timestamps
+func GetLatestTimestamp(timestamps ...*time.Time) *time.Time {
+	var latest *time.Time
+	for _, ts := range timestamps {
+		if ts != nil && (latest == nil || ts.After(*latest)) {
+			latest = ts
+		}
+	}
+
+	return latest
+}
+
+// Returns the earliest of the given timestamps
+func GetEarliestTimestamp(timestamps ...*time.Time) *time.Time {
+	var earliest *time.Time
+	for _, ts := range timestamps {
+		if ts != nil && (earliest == nil || ts.Before(*earliest)) {
+			earliest = ts
+		}
+	}
+
+	return earliest
+}
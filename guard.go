@@ -0,0 +1,104 @@
+package xd_rsync
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	PRICE_GUARD_RULE_MAX_DROP               = "max_drop"
+	PRICE_GUARD_RULE_MAX_RISE               = "max_rise"
+	PRICE_GUARD_RULE_BELOW_COST             = "below_cost"
+	PRICE_GUARD_RULE_COMPARE_AT_BELOW_PRICE = "compare_at_below_price"
+)
+
+// Rules a product price must meet to be published. Products breaking any of them are quarantined.
+type PriceGuardConfig struct {
+	// Maximum drop of the base price since it was last published, in percentage. Disabled when 0.
+	MaxDropPercentage float64 `json:"maxDropPercentage"`
+	// Maximum rise of the base price since it was last published, in percentage. Disabled when 0.
+	MaxRisePercentage        float64 `json:"maxRisePercentage"`
+	BlockBelowCost           bool    `json:"blockBelowCost"`
+	BlockCompareAtBelowPrice bool    `json:"blockCompareAtBelowPrice"`
+}
+
+type XdPriceGuardViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (c *PriceGuardConfig) IsEnabled() bool {
+	return c != nil && (c.MaxDropPercentage > 0 || c.MaxRisePercentage > 0 || c.BlockBelowCost || c.BlockCompareAtBelowPrice)
+}
+
+// Returns the rules broken by the prices of the product, given the base price it was last published with, if any.
+// Drops and rises compare base prices, so promotions starting or ending are not mistaken for wrong prices, while the
+// cost and compare-at price are checked against the effective price.
+func (c *PriceGuardConfig) Check(product *XdProduct, previousPrice *Decimal) []XdPriceGuardViolation {
+	violations := []XdPriceGuardViolation{}
+	if !c.IsEnabled() {
+		return violations
+	}
+
+	basePrice := product.GetBaseAmount()
+	if previousPrice != nil && previousPrice.Sign() > 0 {
+		difference := new(big.Rat).Sub(basePrice.rat(), previousPrice.rat())
+		change, _ := difference.Mul(difference, big.NewRat(100, 1)).Quo(difference, previousPrice.rat()).Float64()
+		if c.MaxDropPercentage > 0 && -change > c.MaxDropPercentage {
+			violations = append(violations, XdPriceGuardViolation{
+				Rule:    PRICE_GUARD_RULE_MAX_DROP,
+				Message: fmt.Sprintf("price dropped %.2f%% from %s to %s, above the maximum of %g%%", -change, previousPrice, basePrice, c.MaxDropPercentage),
+			})
+		}
+
+		if c.MaxRisePercentage > 0 && change > c.MaxRisePercentage {
+			violations = append(violations, XdPriceGuardViolation{
+				Rule:    PRICE_GUARD_RULE_MAX_RISE,
+				Message: fmt.Sprintf("price rose %.2f%% from %s to %s, above the maximum of %g%%", change, previousPrice, basePrice, c.MaxRisePercentage),
+			})
+		}
+	}
+
+	price := product.GetEffectiveAmount()
+
+	if c.BlockBelowCost && product.CostPrice.Sign() > 0 && price.Cmp(product.CostPrice) < 0 {
+		violations = append(violations, XdPriceGuardViolation{
+			Rule:    PRICE_GUARD_RULE_BELOW_COST,
+			Message: fmt.Sprintf("price %s is below the cost of %s", price, product.CostPrice),
+		})
+	}
+
+	// Products without a compare-at price have it set to 0
+	compareAtPrice := product.GetCompareAtAmount()
+	if c.BlockCompareAtBelowPrice && compareAtPrice.Sign() > 0 && compareAtPrice.Cmp(price) < 0 {
+		violations = append(violations, XdPriceGuardViolation{
+			Rule:    PRICE_GUARD_RULE_COMPARE_AT_BELOW_PRICE,
+			Message: fmt.Sprintf("compare-at price %s is below the price of %s", compareAtPrice, price),
+		})
+	}
+
+	return violations
+}
+
+// Product held back by the price guard until it is approved, rejected or changed again
+type XdQuarantinedProduct struct {
+	Product XdProduct `json:"product"`
+	// Base price the product was last published with
+	PreviousPrice *Decimal                `json:"previousPrice"`
+	Violations    []XdPriceGuardViolation `json:"violations"`
+	QuarantinedAt time.Time               `json:"quarantinedAt"`
+}
+
+// Raised when a product is quarantined by the price guard
+type XdPriceAlert struct {
+	SKU string `json:"sku"`
+	// Effective price the product would be sold at
+	Price float64 `json:"price"`
+	// Base price the product was last published with
+	PreviousPrice  *float64                `json:"previousPrice,omitempty"`
+	CompareAtPrice float64                 `json:"compareAtPrice"`
+	CostPrice      float64                 `json:"costPrice"`
+	Violations     []XdPriceGuardViolation `json:"violations"`
+	QuarantinedAt  time.Time               `json:"quarantinedAt"`
+}
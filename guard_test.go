@@ -0,0 +1,136 @@
+package xd_rsync
+
+import (
+	"slices"
+	"testing"
+)
+
+func mustParseDecimal(t *testing.T, text string) Decimal {
+	decimal, err := ParseDecimal(text)
+	if err != nil {
+		t.Fatalf("could not parse decimal: %v", err)
+	}
+
+	return decimal
+}
+
+func TestPriceGuardCheck(t *testing.T) {
+	guard := &PriceGuardConfig{
+		MaxDropPercentage:        20,
+		MaxRisePercentage:        50,
+		BlockBelowCost:           true,
+		BlockCompareAtBelowPrice: true,
+	}
+	money := &MoneyConfig{Currency: "EUR", Scale: 2}
+
+	testCases := []struct {
+		name           string
+		price          string
+		compareAtPrice string
+		costPrice      string
+		promotionPrice string
+		previousPrice  string
+		expectedRules  []string
+	}{
+		{
+			name:          "drop at the maximum",
+			price:         "8.00",
+			previousPrice: "10.00",
+			expectedRules: []string{},
+		},
+		{
+			name:          "drop above the maximum",
+			price:         "7.99",
+			previousPrice: "10.00",
+			expectedRules: []string{PRICE_GUARD_RULE_MAX_DROP},
+		},
+		{
+			name:          "rise above the maximum",
+			price:         "15.01",
+			previousPrice: "10.00",
+			expectedRules: []string{PRICE_GUARD_RULE_MAX_RISE},
+		},
+		{
+			name:          "first publish",
+			price:         "1.00",
+			expectedRules: []string{},
+		},
+		{
+			name:           "deep promotion starting",
+			price:          "10.00",
+			promotionPrice: "5.00",
+			previousPrice:  "10.00",
+			expectedRules:  []string{},
+		},
+		{
+			name:          "deep promotion ending",
+			price:         "10.00",
+			previousPrice: "10.00",
+			expectedRules: []string{},
+		},
+		{
+			name:           "price dropping above the maximum during a promotion",
+			price:          "7.99",
+			promotionPrice: "5.00",
+			previousPrice:  "10.00",
+			expectedRules:  []string{PRICE_GUARD_RULE_MAX_DROP},
+		},
+		{
+			name:           "promotional price below cost",
+			price:          "10.00",
+			costPrice:      "6.50",
+			promotionPrice: "6.49",
+			previousPrice:  "7.00",
+			expectedRules:  []string{PRICE_GUARD_RULE_BELOW_COST},
+		},
+		{
+			name:           "compare-at price below the price",
+			price:          "10.00",
+			compareAtPrice: "9.99",
+			previousPrice:  "10.00",
+			expectedRules:  []string{PRICE_GUARD_RULE_COMPARE_AT_BELOW_PRICE},
+		},
+		{
+			name:           "compare-at price above the promotional price",
+			price:          "10.00",
+			compareAtPrice: "12.00",
+			promotionPrice: "9.00",
+			previousPrice:  "10.00",
+			expectedRules:  []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			product := &XdProduct{
+				SKU:               "A1",
+				ExactRetailPrice2: mustParseDecimal(t, testCase.price),
+			}
+			if len(testCase.compareAtPrice) > 0 {
+				product.ExactRetailPrice1 = mustParseDecimal(t, testCase.compareAtPrice)
+			}
+			if len(testCase.costPrice) > 0 {
+				product.CostPrice = mustParseDecimal(t, testCase.costPrice)
+			}
+			if len(testCase.promotionPrice) > 0 {
+				product.SetPromotion(&XdPromotion{Id: "P1", Price: mustParseDecimal(t, testCase.promotionPrice)})
+			}
+			product.SetPrices(money)
+
+			var previousPrice *Decimal
+			if len(testCase.previousPrice) > 0 {
+				parsedPrice := mustParseDecimal(t, testCase.previousPrice)
+				previousPrice = &parsedPrice
+			}
+
+			rules := []string{}
+			for _, violation := range guard.Check(product, previousPrice) {
+				rules = append(rules, violation.Rule)
+			}
+
+			if !slices.Equal(rules, testCase.expectedRules) {
+				t.Errorf("expected rules %v, got %v", testCase.expectedRules, rules)
+			}
+		})
+	}
+}
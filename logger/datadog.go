@@ -2,21 +2,70 @@ package logger
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime"
 	"net/http"
 	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
 )
 
 const DATADOG_INGEST_URL = "https://http-intake.logs.datadoghq.eu/api/v2/logs"
 
+// Datadog intake limits: https://docs.datadoghq.com/api/latest/logs/#send-logs
+const (
+	DATADOG_MAX_BATCH_ENTRIES    = 1000
+	DATADOG_MAX_BATCH_BYTES      = 5 * 1024 * 1024
+	DATADOG_MAX_ENTRY_BYTES      = 1024 * 1024
+	DATADOG_QUEUE_SIZE           = 10000
+	DATADOG_FLUSH_INTERVAL       = 5 * time.Second
+	DATADOG_FLUSH_TIMEOUT        = 10 * time.Second
+	DATADOG_REQUEST_TIMEOUT      = 10 * time.Second
+	DATADOG_MAX_SEND_ATTEMPTS    = 5
+	DATADOG_INITIAL_BACKOFF      = 500 * time.Millisecond
+	DATADOG_MAX_BACKOFF          = 10 * time.Second
+	DATADOG_MIN_RETRYABLE_STATUS = 500
+)
+
+var ErrDatadogQueueFull = errors.New("datadog event queue is full, event dropped")
+var ErrDatadogEventTooLarge = errors.New("datadog event exceeds maximum size, event dropped")
+var ErrDatadogFlushTimeout = errors.New("timed out flushing datadog events")
+
+type datadogBatch struct {
+	entries [][]byte
+	size    int
+}
+
+func (b *datadogBatch) fits(entry []byte) bool {
+	// Account for the array brackets and separators
+	return len(b.entries) < DATADOG_MAX_BATCH_ENTRIES && b.size+len(entry)+len(b.entries)+2 <= DATADOG_MAX_BATCH_BYTES
+}
+
+func (b *datadogBatch) add(entry []byte) {
+	b.entries = append(b.entries, entry)
+	b.size += len(entry)
+}
+
+func (b *datadogBatch) reset() {
+	b.entries = [][]byte{}
+	b.size = 0
+}
+
 type DatadogIngestClient struct {
-	ingestUrl string
+	ingestUrl     string
+	httpClient    *http.Client
+	queue         chan []byte
+	flushRequests chan chan struct{}
+	stop          chan struct{}
+	// Shared by the clients of a logger, so the count is kept when the ingestion is replaced
+	droppedEvents *atomic.Uint64
 }
 
-func (c DatadogIngestClient) transformEventPayloadToJson(eventDetails map[string]interface{}) ([]byte, error) {
+func (c *DatadogIngestClient) transformEventPayloadToJson(eventDetails map[string]interface{}) ([]byte, error) {
 	bytes, err := json.Marshal(eventDetails)
 	if err != nil {
 		return nil, fmt.Errorf("could not transform event to JSON format: %s", err)
@@ -25,31 +74,182 @@ func (c DatadogIngestClient) transformEventPayloadToJson(eventDetails map[string
 	return bytes, nil
 }
 
-func (c DatadogIngestClient) SendEvent(eventDetails map[string]interface{}) error {
+// Queues the event to be shipped in the background. Events are dropped when the queue is full.
+func (c *DatadogIngestClient) SendEvent(eventDetails map[string]interface{}) error {
 	eventPayload, err := c.transformEventPayloadToJson(eventDetails)
 	if err != nil {
+		c.droppedEvents.Add(1)
 		return err
 	}
 
-	resp, err := http.Post(c.ingestUrl, mime.TypeByExtension(".json"), bytes.NewBuffer(eventPayload))
+	if len(eventPayload) > DATADOG_MAX_ENTRY_BYTES {
+		c.droppedEvents.Add(1)
+		return ErrDatadogEventTooLarge
+	}
+
+	select {
+	case c.queue <- eventPayload:
+		return nil
+	default:
+		c.droppedEvents.Add(1)
+		return ErrDatadogQueueFull
+	}
+}
+
+// Ships every event queued so far, waiting at most the given timeout
+func (c *DatadogIngestClient) Flush(timeout time.Duration) error {
+	done := make(chan struct{})
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case c.flushRequests <- done:
+	case <-timer.C:
+		return ErrDatadogFlushTimeout
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return ErrDatadogFlushTimeout
+	}
+}
+
+// Ships the queued events and stops the background shipping. Events sent afterwards are never shipped.
+func (c *DatadogIngestClient) Close(timeout time.Duration) error {
+	err := c.Flush(timeout)
+	close(c.stop)
+
+	return err
+}
+
+// Returns the number of events dropped by this client and the previous clients of its logger
+func (c *DatadogIngestClient) DroppedEvents() uint64 {
+	return c.droppedEvents.Load()
+}
+
+func (c *DatadogIngestClient) run() {
+	ticker := time.NewTicker(DATADOG_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	batch := &datadogBatch{}
+	for {
+		select {
+		case entry := <-c.queue:
+			c.addToBatch(batch, entry)
+		case <-ticker.C:
+			c.sendBatch(batch)
+		case done := <-c.flushRequests:
+			c.drainQueue(batch)
+			c.sendBatch(batch)
+			close(done)
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *DatadogIngestClient) addToBatch(batch *datadogBatch, entry []byte) {
+	if !batch.fits(entry) {
+		c.sendBatch(batch)
+	}
+
+	batch.add(entry)
+}
+
+func (c *DatadogIngestClient) drainQueue(batch *datadogBatch) {
+	for {
+		select {
+		case entry := <-c.queue:
+			c.addToBatch(batch, entry)
+		default:
+			return
+		}
+	}
+}
+
+func (c *DatadogIngestClient) sendBatch(batch *datadogBatch) {
+	if len(batch.entries) == 0 {
+		return
+	}
+	defer batch.reset()
+
+	payload, err := c.compressBatch(batch)
 	if err != nil {
-		return fmt.Errorf("could not post event into datalog: %s", err)
+		c.droppedEvents.Add(uint64(len(batch.entries)))
+		fmt.Fprintf(os.Stderr, "⚠️ Could not compress Datadog events batch: %s\n", err)
+		return
+	}
+
+	backoff := DATADOG_INITIAL_BACKOFF
+	for attempt := 1; attempt <= DATADOG_MAX_SEND_ATTEMPTS; attempt++ {
+		isRetryable, err := c.postPayload(payload)
+		if err == nil {
+			return
+		}
+
+		if !isRetryable || attempt == DATADOG_MAX_SEND_ATTEMPTS {
+			c.droppedEvents.Add(uint64(len(batch.entries)))
+			fmt.Fprintf(os.Stderr, "⚠️ Dropped %d Datadog events after %d attempts: %s\n", len(batch.entries), attempt, err)
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, DATADOG_MAX_BACKOFF)
+	}
+}
+
+func (c *DatadogIngestClient) compressBatch(batch *datadogBatch) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+
+	writer.Write([]byte("["))
+	for index, entry := range batch.entries {
+		if index > 0 {
+			writer.Write([]byte(","))
+		}
+		writer.Write(entry)
+	}
+	writer.Write([]byte("]"))
+
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (c *DatadogIngestClient) postPayload(payload []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, c.ingestUrl, bytes.NewReader(payload))
+	if err != nil {
+		return false, fmt.Errorf("could not build datadog request: %s", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return true, fmt.Errorf("could not post events into datadog: %s", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 && resp.StatusCode != 201 && resp.StatusCode != 202 {
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return fmt.Errorf("event was not ingested but response body could not be parsed: %s", string(bodyBytes))
+			return true, fmt.Errorf("events were not ingested but response body could not be parsed: %s", err)
 		}
 
-		return fmt.Errorf("event was not ingested. api response: %s", string(bodyBytes))
+		isRetryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= DATADOG_MIN_RETRYABLE_STATUS
+		return isRetryable, fmt.Errorf("events were not ingested. status: %d api response: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	return err
+	return false, nil
 }
 
-func createDatadogIngestClient(host, apiKey string) (*DatadogIngestClient, error) {
+func createDatadogIngestClient(host, apiKey string, droppedEvents *atomic.Uint64) (*DatadogIngestClient, error) {
 	ingestUrl := url.URL{
 		Scheme: "https",
 		Host:   host,
@@ -63,7 +263,18 @@ func createDatadogIngestClient(host, apiKey string) (*DatadogIngestClient, error
 
 	ingestUrl.RawQuery = queryParams.Encode()
 
-	return &DatadogIngestClient{
+	client := &DatadogIngestClient{
 		ingestUrl: ingestUrl.String(),
-	}, nil
+		httpClient: &http.Client{
+			Timeout: DATADOG_REQUEST_TIMEOUT,
+		},
+		queue:         make(chan []byte, DATADOG_QUEUE_SIZE),
+		flushRequests: make(chan chan struct{}),
+		stop:          make(chan struct{}),
+		droppedEvents: droppedEvents,
+	}
+
+	go client.run()
+
+	return client, nil
 }
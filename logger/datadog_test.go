@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDroppedDatadogEventsAcrossIngestionChanges(t *testing.T) {
+	host, apiKey := "127.0.0.1:1", "test"
+	testLogger, err := CreateLogger(&LoggerOptions{
+		IsProduction:      true,
+		Level:             "error",
+		DatadogIngestHost: &host,
+		DatadogApiKey:     &apiKey,
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	oversizedEvent := map[string]interface{}{"message": strings.Repeat("a", DATADOG_MAX_ENTRY_BYTES)}
+	err = testLogger.sinks.Load().datadogClient.SendEvent(oversizedEvent)
+	if !errors.Is(err, ErrDatadogEventTooLarge) {
+		t.Fatalf("expected the event to be too large, got %v", err)
+	}
+
+	// The count is kept when the ingestion is replaced, e.g. on a configuration reload
+	err = testLogger.SetDatadogIngestion(&host, &apiKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testLogger.sinks.Load().datadogClient.SendEvent(oversizedEvent)
+
+	derivedLogger := testLogger.WithFields(map[string]interface{}{"tenant": "store-a"})
+	if testLogger.DroppedDatadogEvents() != 2 || derivedLogger.DroppedDatadogEvents() != 2 {
+		t.Errorf("expected 2 dropped events, got %d and %d", testLogger.DroppedDatadogEvents(), derivedLogger.DroppedDatadogEvents())
+	}
+
+	testLogger.SetDatadogIngestion(nil, nil)
+}
@@ -2,6 +2,8 @@ package logger
 
 import (
 	"fmt"
+	"maps"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 )
@@ -13,8 +15,7 @@ func CreateProductionLogger(initialFields map[string]interface{}) (*Logger, erro
 
 	config.Development = false
 
-	config.InitialFields = initialFields
-	config.InitialFields["loggerMode"] = "production"
+	initialFields["loggerMode"] = "production"
 
 	logger, err := config.Build()
 	if err != nil {
@@ -24,6 +25,7 @@ func CreateProductionLogger(initialFields map[string]interface{}) (*Logger, erro
 
 	return &Logger{
 		instance: logger,
+		level:    config.Level,
 	}, nil
 }
 
@@ -34,8 +36,7 @@ func CreateDevelopmentLogger(initialFields map[string]interface{}) (*Logger, err
 
 	config.Development = true
 
-	config.InitialFields = initialFields
-	config.InitialFields["loggerMode"] = "development"
+	initialFields["loggerMode"] = "development"
 
 	logger, err := config.Build()
 	if err != nil {
@@ -44,49 +45,75 @@ func CreateDevelopmentLogger(initialFields map[string]interface{}) (*Logger, err
 
 	return &Logger{
 		instance: logger,
+		level:    config.Level,
 	}, nil
 }
 
 type LoggerOptions struct {
 	IsProduction      bool
+	Level             string
 	InitialFields     map[string]interface{}
 	DatadogApiKey     *string
 	DatadogIngestHost *string
 }
 
+func createDatadogIngestClientFromOptions(host *string, apiKey *string, droppedEvents *atomic.Uint64) (*DatadogIngestClient, error) {
+	isDatadogIngestHostValid := host != nil && len(*host) > 0
+	isDatadogApiKeyValid := apiKey != nil && len(*apiKey) > 0
+
+	if !isDatadogIngestHostValid && isDatadogApiKeyValid {
+		fmt.Println("⚠️ Datadog ingest host was not provided. Datadog ingestion is disabled!")
+	}
+
+	if isDatadogIngestHostValid && !isDatadogApiKeyValid {
+		fmt.Println("⚠️ Datadog API key was not provided. Datadog ingestion is disabled!")
+	}
+
+	if !isDatadogIngestHostValid || !isDatadogApiKeyValid {
+		return nil, nil
+	}
+
+	return createDatadogIngestClient(*host, *apiKey, droppedEvents)
+}
+
 func CreateLogger(opts *LoggerOptions) (*Logger, error) {
 	var logger *Logger
 	var err error
 
+	// Base fields are added to each event, so they can be replaced while the logger is in use
+	initialFields := maps.Clone(opts.InitialFields)
+	if initialFields == nil {
+		initialFields = map[string]interface{}{}
+	}
+
 	if opts.IsProduction {
-		logger, err = CreateProductionLogger(opts.InitialFields)
+		logger, err = CreateProductionLogger(initialFields)
 	} else {
-		logger, err = CreateDevelopmentLogger(opts.InitialFields)
+		logger, err = CreateDevelopmentLogger(initialFields)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("could not create logger: %w", err)
 	}
 
-	logger.eventBaseFields = opts.InitialFields
-
-	isDatadogIngestHostValid := opts.DatadogIngestHost != nil && len(*opts.DatadogIngestHost) > 0
-	isDatadogApiKeyValid := opts.DatadogApiKey != nil && len(*opts.DatadogApiKey) > 0
-
-	if !isDatadogIngestHostValid && isDatadogApiKeyValid {
-		fmt.Println("⚠️ Datadog ingest host was not provided. Datadog ingestion is disabled!")
-	}
-
-	if isDatadogIngestHostValid && !isDatadogApiKeyValid {
-		fmt.Println("⚠️ Datadog API key was not provided. Datadog ingestion is disabled!")
-	}
-
-	if isDatadogIngestHostValid && isDatadogApiKeyValid {
-		logger.datadogClient, err = createDatadogIngestClient(*opts.DatadogIngestHost, *opts.DatadogApiKey)
+	if len(opts.Level) > 0 {
+		err = logger.SetLevel(opts.Level)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	logger.droppedDatadogEvents = &atomic.Uint64{}
+	datadogClient, err := createDatadogIngestClientFromOptions(opts.DatadogIngestHost, opts.DatadogApiKey, logger.droppedDatadogEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.sinks = &atomic.Pointer[loggerSinks]{}
+	logger.sinks.Store(&loggerSinks{
+		datadogClient:   datadogClient,
+		eventBaseFields: initialFields,
+	})
+
 	return logger, nil
 }
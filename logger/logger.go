@@ -1,18 +1,50 @@
 package logger
 
 import (
+	"fmt"
 	"maps"
+	"os"
+	"sync/atomic"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-type Logger struct {
-	instance        *zap.Logger
+// Destinations and fields that can be replaced while the logger is in use
+type loggerSinks struct {
 	datadogClient   *DatadogIngestClient
 	eventBaseFields map[string]interface{}
 }
 
+type Logger struct {
+	instance *zap.Logger
+	level    zap.AtomicLevel
+	// Shared with the loggers derived through WithFields
+	sinks  *atomic.Pointer[loggerSinks]
+	fields map[string]interface{}
+	// Events dropped instead of being shipped to Datadog, shared with the loggers derived through WithFields
+	droppedDatadogEvents *atomic.Uint64
+}
+
+// Returns a logger that adds the given fields to every event, sharing the level and sinks of this one
+func (txLogger *Logger) WithFields(fields map[string]interface{}) *Logger {
+	mergedFields := maps.Clone(txLogger.fields)
+	if mergedFields == nil {
+		mergedFields = map[string]interface{}{}
+	}
+	maps.Copy(mergedFields, fields)
+
+	return &Logger{
+		instance:             txLogger.instance,
+		level:                txLogger.level,
+		sinks:                txLogger.sinks,
+		fields:               mergedFields,
+		droppedDatadogEvents: txLogger.droppedDatadogEvents,
+	}
+}
+
 func (txLogger *Logger) Debug(event_type string, message string, extraFields *map[string]interface{}) {
+	sinks := txLogger.sinks.Load()
 	eventPayload := map[string]interface{}{
 		"event_type": event_type,
 		"level":      "debug",
@@ -22,17 +54,19 @@ func (txLogger *Logger) Debug(event_type string, message string, extraFields *ma
 		maps.Copy(eventPayload, *extraFields)
 	}
 
-	maps.Copy(eventPayload, txLogger.eventBaseFields)
+	maps.Copy(eventPayload, txLogger.fields)
+	maps.Copy(eventPayload, sinks.eventBaseFields)
 
 	txLogger.instance.Debug(message, *mapExtraFieldsToZap(eventPayload)...)
 
-	if txLogger.datadogClient != nil {
-		txLogger.datadogClient.SendEvent(eventPayload)
+	if sinks.datadogClient != nil && txLogger.level.Enabled(zapcore.DebugLevel) {
+		sinks.datadogClient.SendEvent(eventPayload)
 	}
 
 }
 
 func (txLogger *Logger) Info(event_type string, message string, extraFields *map[string]interface{}) {
+	sinks := txLogger.sinks.Load()
 	eventPayload := map[string]interface{}{
 		"event_type": event_type,
 		"level":      "info",
@@ -42,16 +76,18 @@ func (txLogger *Logger) Info(event_type string, message string, extraFields *map
 		maps.Copy(eventPayload, *extraFields)
 	}
 
-	maps.Copy(eventPayload, txLogger.eventBaseFields)
+	maps.Copy(eventPayload, txLogger.fields)
+	maps.Copy(eventPayload, sinks.eventBaseFields)
 
 	txLogger.instance.Info(message, *mapExtraFieldsToZap(eventPayload)...)
 
-	if txLogger.datadogClient != nil {
-		txLogger.datadogClient.SendEvent(eventPayload)
+	if sinks.datadogClient != nil && txLogger.level.Enabled(zapcore.InfoLevel) {
+		sinks.datadogClient.SendEvent(eventPayload)
 	}
 }
 
 func (txLogger *Logger) Warn(event_type string, message string, extraFields *map[string]interface{}) {
+	sinks := txLogger.sinks.Load()
 	eventPayload := map[string]interface{}{
 		"event_type": event_type,
 		"level":      "warn",
@@ -61,16 +97,18 @@ func (txLogger *Logger) Warn(event_type string, message string, extraFields *map
 		maps.Copy(eventPayload, *extraFields)
 	}
 
-	maps.Copy(eventPayload, txLogger.eventBaseFields)
+	maps.Copy(eventPayload, txLogger.fields)
+	maps.Copy(eventPayload, sinks.eventBaseFields)
 
 	txLogger.instance.Warn(message, *mapExtraFieldsToZap(eventPayload)...)
 
-	if txLogger.datadogClient != nil {
-		txLogger.datadogClient.SendEvent(eventPayload)
+	if sinks.datadogClient != nil && txLogger.level.Enabled(zapcore.WarnLevel) {
+		sinks.datadogClient.SendEvent(eventPayload)
 	}
 }
 
 func (txLogger *Logger) Error(event_type string, message string, extraFields *map[string]interface{}) {
+	sinks := txLogger.sinks.Load()
 	eventPayload := map[string]interface{}{
 		"event_type": event_type,
 		"level":      "error",
@@ -80,16 +118,18 @@ func (txLogger *Logger) Error(event_type string, message string, extraFields *ma
 		maps.Copy(eventPayload, *extraFields)
 	}
 
-	maps.Copy(eventPayload, txLogger.eventBaseFields)
+	maps.Copy(eventPayload, txLogger.fields)
+	maps.Copy(eventPayload, sinks.eventBaseFields)
 
 	txLogger.instance.Error(message, *mapExtraFieldsToZap(eventPayload)...)
 
-	if txLogger.datadogClient != nil {
-		txLogger.datadogClient.SendEvent(eventPayload)
+	if sinks.datadogClient != nil && txLogger.level.Enabled(zapcore.ErrorLevel) {
+		sinks.datadogClient.SendEvent(eventPayload)
 	}
 }
 
 func (txLogger *Logger) Fatal(event_type string, message string, extraFields *map[string]interface{}) {
+	sinks := txLogger.sinks.Load()
 	eventPayload := map[string]interface{}{
 		"event_type": event_type,
 		"level":      "fatal",
@@ -99,11 +139,100 @@ func (txLogger *Logger) Fatal(event_type string, message string, extraFields *ma
 		maps.Copy(eventPayload, *extraFields)
 	}
 
-	maps.Copy(eventPayload, txLogger.eventBaseFields)
+	maps.Copy(eventPayload, txLogger.fields)
+	maps.Copy(eventPayload, sinks.eventBaseFields)
+
+	// Fatal exits the process, so pending events must be shipped beforehand
+	if sinks.datadogClient != nil {
+		sinks.datadogClient.SendEvent(eventPayload)
+	}
+	txLogger.Flush()
 
 	txLogger.instance.Fatal(message, *mapExtraFieldsToZap(eventPayload)...)
+}
+
+func (txLogger *Logger) Flush() {
+	datadogClient := txLogger.sinks.Load().datadogClient
+	if datadogClient != nil {
+		err := datadogClient.Flush(DATADOG_FLUSH_TIMEOUT)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ Could not flush Datadog events: %s\n", err)
+		}
+
+		if droppedEvents := datadogClient.DroppedEvents(); droppedEvents > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️ %d Datadog events were dropped\n", droppedEvents)
+		}
+	}
+
+	txLogger.instance.Sync()
+}
+
+// Returns the number of events dropped instead of being shipped to Datadog, e.g. when its queue was full
+func (txLogger *Logger) DroppedDatadogEvents() uint64 {
+	return txLogger.droppedDatadogEvents.Load()
+}
 
-	if txLogger.datadogClient != nil {
-		txLogger.datadogClient.SendEvent(eventPayload)
+// Changes the minimum level of the logged events, e.g. "debug" or "warn"
+func (txLogger *Logger) SetLevel(level string) error {
+	parsedLevel, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("could not parse log level: %w", err)
 	}
+
+	txLogger.level.SetLevel(parsedLevel)
+	return nil
+}
+
+func (txLogger *Logger) GetLevel() string {
+	return txLogger.level.String()
+}
+
+// Replaces the fields added to every event
+func (txLogger *Logger) SetEventBaseFields(eventBaseFields map[string]interface{}) {
+	for {
+		currentSinks := txLogger.sinks.Load()
+		updatedSinks := &loggerSinks{
+			datadogClient:   currentSinks.datadogClient,
+			eventBaseFields: withLoggerMode(eventBaseFields, currentSinks.eventBaseFields),
+		}
+
+		if txLogger.sinks.CompareAndSwap(currentSinks, updatedSinks) {
+			return
+		}
+	}
+}
+
+// Replaces the Datadog ingestion, shipping the events queued on the previous one. Ingestion is disabled when
+// the host or the API key are empty.
+func (txLogger *Logger) SetDatadogIngestion(host *string, apiKey *string) error {
+	datadogClient, err := createDatadogIngestClientFromOptions(host, apiKey, txLogger.droppedDatadogEvents)
+	if err != nil {
+		return err
+	}
+
+	for {
+		currentSinks := txLogger.sinks.Load()
+		updatedSinks := &loggerSinks{
+			datadogClient:   datadogClient,
+			eventBaseFields: currentSinks.eventBaseFields,
+		}
+
+		if txLogger.sinks.CompareAndSwap(currentSinks, updatedSinks) {
+			if currentSinks.datadogClient != nil {
+				return currentSinks.datadogClient.Close(DATADOG_FLUSH_TIMEOUT)
+			}
+
+			return nil
+		}
+	}
+}
+
+func withLoggerMode(eventBaseFields map[string]interface{}, previousEventBaseFields map[string]interface{}) map[string]interface{} {
+	fields := maps.Clone(eventBaseFields)
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+
+	fields["loggerMode"] = previousEventBaseFields["loggerMode"]
+	return fields
 }
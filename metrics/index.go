@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/fabiofcferreira/xd-rsync/logger"
+)
+
+const NAMESPACE = "xd_rsync"
+
+// Label identifying the XD database (source) of each observation
+const TENANT_LABEL = "tenant"
+
+var DURATION_BUCKETS = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+type Metrics struct {
+	registry *prometheus.Registry
+	statsd   *StatsdClient
+
+	SyncRuns             *Counter
+	SyncRunDuration      *Histogram
+	LastSyncRunTimestamp *Gauge
+	ProductsChanged      *Counter
+	EntitiesChanged      *Counter
+	StockTransitions     *Counter
+	QuarantinedProducts  *Counter
+	MassChanges          *Counter
+	LastRunChanges       *Gauge
+	RowsScanned          *Counter
+	DatabaseQueryLatency *Histogram
+	MessagesPublished    *Counter
+	MessagesFailed       *Counter
+	SNSPublishLatency    *Histogram
+	ReplicationLag       *Histogram
+	MaxReplicationLag    *Gauge
+}
+
+type MetricsCreationInput struct {
+	DogStatsdAddress string
+	ConstantTags     map[string]string
+	Logger           *logger.Logger
+}
+
+func CreateMetrics(input *MetricsCreationInput) (*Metrics, error) {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		// Read from the logger, as it is shared by every source
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "datadog_dropped_events_total",
+			Help:      "Number of log events dropped instead of being shipped to Datadog",
+		}, func() float64 {
+			return float64(input.Logger.DroppedDatadogEvents())
+		}),
+	)
+
+	if len(input.DogStatsdAddress) > 0 {
+		statsd, err := createStatsdClient(input.DogStatsdAddress, input.ConstantTags)
+		if err != nil {
+			input.Logger.Error("failed_dogstatsd_client_create", "Failed to create DogStatsD client", &map[string]interface{}{
+				"error":   err.Error(),
+				"address": input.DogStatsdAddress,
+			})
+			return nil, err
+		}
+
+		m.statsd = statsd
+		input.Logger.Info("created_dogstatsd_client", "Created DogStatsD client", &map[string]interface{}{
+			"address": input.DogStatsdAddress,
+		})
+	}
+
+	m.SyncRuns = m.newCounter("sync_runs_total", "Number of sync runs by status", "status")
+	m.SyncRunDuration = m.newHistogram("sync_run_duration_seconds", "Duration of sync runs", DURATION_BUCKETS)
+	m.LastSyncRunTimestamp = m.newGauge("last_sync_run_timestamp_seconds", "Unix timestamp of the last sync run by status", "status")
+	m.ProductsChanged = m.newCounter("products_changed_total", "Number of changed products found")
+	m.EntitiesChanged = m.newCounter("entities_changed_total", "Number of changed entities found by entity", "entity")
+	m.StockTransitions = m.newCounter("stock_transitions_total", "Number of stock threshold crossings published by type", "type")
+	m.QuarantinedProducts = m.newCounter("price_guard_quarantined_products_total", "Number of products quarantined by the price guard")
+	m.MassChanges = m.newCounter("mass_changes_total", "Number of runs whose changes were a mass change by action", "action")
+	m.LastRunChanges = m.newGauge("last_sync_run_changed_products", "Number of changed products found on the last sync run")
+	m.RowsScanned = m.newCounter("db_rows_scanned_total", "Number of rows read from the database by query", "query")
+	m.DatabaseQueryLatency = m.newHistogram("db_query_duration_seconds", "Duration of database queries", DURATION_BUCKETS, "query", "status")
+	m.MessagesPublished = m.newCounter("sns_messages_published_total", "Number of messages published to SNS")
+	m.MessagesFailed = m.newCounter("sns_messages_failed_total", "Number of messages that could not be published to SNS")
+	m.SNSPublishLatency = m.newHistogram("sns_publish_duration_seconds", "Duration of SNS publish requests", DURATION_BUCKETS, "operation", "status")
+	m.ReplicationLag = m.newHistogram("replication_lag_seconds", "Time between a change in XD and its acknowledgement by SNS by entity", LAG_BUCKETS, "entity")
+	m.MaxReplicationLag = m.newGauge("max_replication_lag_seconds", "Maximum replication lag of the records published on the last sync run by entity", "entity")
+
+	return m, nil
+}
+
+func (m *Metrics) newCounter(name string, help string, labelNames ...string) *Counter {
+	vector := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      name,
+		Help:      help,
+	}, append(labelNames, TENANT_LABEL))
+	m.registry.MustRegister(vector)
+
+	return &Counter{
+		name:         NAMESPACE + "." + name,
+		vector:       vector,
+		statsd:       m.statsd,
+		presetLabels: Labels{TENANT_LABEL: ""},
+	}
+}
+
+func (m *Metrics) newGauge(name string, help string, labelNames ...string) *Gauge {
+	vector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      name,
+		Help:      help,
+	}, append(labelNames, TENANT_LABEL))
+	m.registry.MustRegister(vector)
+
+	return &Gauge{
+		name:         NAMESPACE + "." + name,
+		vector:       vector,
+		statsd:       m.statsd,
+		presetLabels: Labels{TENANT_LABEL: ""},
+	}
+}
+
+func (m *Metrics) newHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	vector := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, append(labelNames, TENANT_LABEL))
+	m.registry.MustRegister(vector)
+
+	return &Histogram{
+		name:         NAMESPACE + "." + name,
+		vector:       vector,
+		statsd:       m.statsd,
+		presetLabels: Labels{TENANT_LABEL: ""},
+	}
+}
+
+// Returns the same metrics, labelled with the given tenant
+func (m *Metrics) WithTenant(tenant string) *Metrics {
+	labels := Labels{TENANT_LABEL: tenant}
+
+	return &Metrics{
+		registry:             m.registry,
+		statsd:               m.statsd,
+		SyncRuns:             m.SyncRuns.withLabels(labels),
+		SyncRunDuration:      m.SyncRunDuration.withLabels(labels),
+		LastSyncRunTimestamp: m.LastSyncRunTimestamp.withLabels(labels),
+		ProductsChanged:      m.ProductsChanged.withLabels(labels),
+		EntitiesChanged:      m.EntitiesChanged.withLabels(labels),
+		StockTransitions:     m.StockTransitions.withLabels(labels),
+		QuarantinedProducts:  m.QuarantinedProducts.withLabels(labels),
+		MassChanges:          m.MassChanges.withLabels(labels),
+		LastRunChanges:       m.LastRunChanges.withLabels(labels),
+		RowsScanned:          m.RowsScanned.withLabels(labels),
+		DatabaseQueryLatency: m.DatabaseQueryLatency.withLabels(labels),
+		MessagesPublished:    m.MessagesPublished.withLabels(labels),
+		MessagesFailed:       m.MessagesFailed.withLabels(labels),
+		SNSPublishLatency:    m.SNSPublishLatency.withLabels(labels),
+		ReplicationLag:       m.ReplicationLag.withLabels(labels),
+		MaxReplicationLag:    m.MaxReplicationLag.withLabels(labels),
+	}
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry: m.registry,
+	})
+}
+
+func (m *Metrics) Close() error {
+	return m.statsd.Close()
+}
+
+func GetStatusLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
+
+func (m *Metrics) ObserveDatabaseQuery(query string, startedAt time.Time, rows int, err error) {
+	m.DatabaseQueryLatency.Observe(time.Since(startedAt).Seconds(), Labels{
+		"query":  query,
+		"status": GetStatusLabel(err),
+	})
+
+	if rows > 0 {
+		m.RowsScanned.Add(float64(rows), Labels{"query": query})
+	}
+}
+
+func (m *Metrics) ObserveSNSPublish(operation string, startedAt time.Time, err error) {
+	m.SNSPublishLatency.Observe(time.Since(startedAt).Seconds(), Labels{
+		"operation": operation,
+		"status":    GetStatusLabel(err),
+	})
+}
+
+func (m *Metrics) ObserveSyncRun(startedAt time.Time, changedProducts int, errs []error) {
+	var err error
+	for _, runErr := range errs {
+		if runErr != nil {
+			err = runErr
+			break
+		}
+	}
+
+	status := GetStatusLabel(err)
+	m.SyncRuns.Inc(Labels{"status": status})
+	m.SyncRunDuration.Observe(time.Since(startedAt).Seconds(), nil)
+	m.LastSyncRunTimestamp.Set(float64(time.Now().Unix()), Labels{"status": status})
+	m.ProductsChanged.Add(float64(changedProducts), nil)
+	m.LastRunChanges.Set(float64(changedProducts), nil)
+}
+
+func (m *Metrics) ObserveEntityChanges(entity string, changedEntities int) {
+	m.EntitiesChanged.Add(float64(changedEntities), Labels{"entity": entity})
+}
+
+func (m *Metrics) ObserveStockTransition(transitionType string) {
+	m.StockTransitions.Add(1, Labels{"type": transitionType})
+}
+
+func (m *Metrics) ObserveProductQuarantined() {
+	m.QuarantinedProducts.Inc(nil)
+}
+
+func (m *Metrics) ObserveMassChange(action string) {
+	m.MassChanges.Inc(Labels{"action": action})
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/fabiofcferreira/xd-rsync/logger"
+)
+
+func TestDatadogDroppedEventsCounter(t *testing.T) {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction: true,
+		Level:        "error",
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	testMetrics, err := CreateMetrics(&MetricsCreationInput{Logger: testLogger})
+	if err != nil {
+		t.Fatalf("could not create metrics: %v", err)
+	}
+
+	families, err := testMetrics.registry.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == "xd_rsync_datadog_dropped_events_total" {
+			if value := family.GetMetric()[0].GetCounter().GetValue(); value != 0 {
+				t.Errorf("expected no dropped events, got %g", value)
+			}
+			return
+		}
+	}
+
+	t.Error("expected the dropped events counter to be exposed")
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+var LAG_BUCKETS = []float64{1, 5, 15, 30, 60, 120, 300, 600, 900, 1800, 3600, 7200, 21600, 86400}
+
+type ReplicationLagBreach struct {
+	Key string
+	Lag time.Duration
+}
+
+// Tracks the replication lag of the messages of an entity published during a single sync run
+type ReplicationLagTracker struct {
+	mutex    sync.Mutex
+	metrics  *Metrics
+	entity   string
+	slo      time.Duration
+	maxLag   time.Duration
+	breaches []ReplicationLagBreach
+}
+
+func (m *Metrics) CreateReplicationLagTracker(entity string, slo time.Duration) *ReplicationLagTracker {
+	return &ReplicationLagTracker{
+		metrics:  m,
+		entity:   entity,
+		slo:      slo,
+		breaches: []ReplicationLagBreach{},
+	}
+}
+
+func (t *ReplicationLagTracker) Observe(key string, changedAt time.Time, acknowledgedAt time.Time) {
+	lag := acknowledgedAt.Sub(changedAt)
+	t.metrics.ReplicationLag.Observe(lag.Seconds(), Labels{"entity": t.entity})
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if lag > t.maxLag {
+		t.maxLag = lag
+	}
+
+	if t.slo > 0 && lag > t.slo {
+		t.breaches = append(t.breaches, ReplicationLagBreach{
+			Key: key,
+			Lag: lag,
+		})
+	}
+}
+
+// Publishes the maximum lag of the run, returning it along with the SLO breaches
+func (t *ReplicationLagTracker) Finish() (time.Duration, []ReplicationLagBreach) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.metrics.MaxReplicationLag.Set(t.maxLag.Seconds(), Labels{"entity": t.entity})
+	return t.maxLag, t.breaches
+}
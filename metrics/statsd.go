@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	STATSD_COUNTER   = "c"
+	STATSD_GAUGE     = "g"
+	STATSD_HISTOGRAM = "h"
+)
+
+type StatsdClient struct {
+	mutex      sync.Mutex
+	connection net.Conn
+	tags       []string
+}
+
+func createStatsdClient(address string, constantTags map[string]string) (*StatsdClient, error) {
+	connection, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to DogStatsD agent: %w", err)
+	}
+
+	return &StatsdClient{
+		connection: connection,
+		tags:       formatStatsdTags(constantTags),
+	}, nil
+}
+
+func formatStatsdTags(tags map[string]string) []string {
+	formattedTags := []string{}
+	for key, value := range tags {
+		formattedTags = append(formattedTags, key+":"+value)
+	}
+
+	return formattedTags
+}
+
+// Metrics are sent on a best effort basis, a missing agent must never affect the sync
+func (c *StatsdClient) send(name string, value float64, metricType string, tags map[string]string) {
+	if c == nil {
+		return
+	}
+
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + metricType
+
+	allTags := append(formatStatsdTags(tags), c.tags...)
+	if len(allTags) > 0 {
+		line += "|#" + strings.Join(allTags, ",")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.connection.Write([]byte(line))
+}
+
+func (c *StatsdClient) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	return c.connection.Close()
+}
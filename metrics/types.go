@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Labels map[string]string
+
+// Merges the labels set when scoping the metrics with the ones of a single observation
+func mergeLabels(presetLabels Labels, labels Labels) Labels {
+	merged := maps.Clone(presetLabels)
+	if merged == nil {
+		merged = Labels{}
+	}
+
+	maps.Copy(merged, labels)
+	return merged
+}
+
+type Counter struct {
+	name         string
+	vector       *prometheus.CounterVec
+	statsd       *StatsdClient
+	presetLabels Labels
+}
+
+func (c *Counter) Add(value float64, labels Labels) {
+	labels = mergeLabels(c.presetLabels, labels)
+	c.vector.With(prometheus.Labels(labels)).Add(value)
+	c.statsd.send(c.name, value, STATSD_COUNTER, labels)
+}
+
+func (c *Counter) Inc(labels Labels) {
+	c.Add(1, labels)
+}
+
+func (c *Counter) withLabels(labels Labels) *Counter {
+	return &Counter{
+		name:         c.name,
+		vector:       c.vector,
+		statsd:       c.statsd,
+		presetLabels: mergeLabels(c.presetLabels, labels),
+	}
+}
+
+type Gauge struct {
+	name         string
+	vector       *prometheus.GaugeVec
+	statsd       *StatsdClient
+	presetLabels Labels
+}
+
+func (g *Gauge) Set(value float64, labels Labels) {
+	labels = mergeLabels(g.presetLabels, labels)
+	g.vector.With(prometheus.Labels(labels)).Set(value)
+	g.statsd.send(g.name, value, STATSD_GAUGE, labels)
+}
+
+func (g *Gauge) withLabels(labels Labels) *Gauge {
+	return &Gauge{
+		name:         g.name,
+		vector:       g.vector,
+		statsd:       g.statsd,
+		presetLabels: mergeLabels(g.presetLabels, labels),
+	}
+}
+
+type Histogram struct {
+	name         string
+	vector       *prometheus.HistogramVec
+	statsd       *StatsdClient
+	presetLabels Labels
+}
+
+func (h *Histogram) Observe(value float64, labels Labels) {
+	labels = mergeLabels(h.presetLabels, labels)
+	h.vector.With(prometheus.Labels(labels)).Observe(value)
+	h.statsd.send(h.name, value, STATSD_HISTOGRAM, labels)
+}
+
+func (h *Histogram) withLabels(labels Labels) *Histogram {
+	return &Histogram{
+		name:         h.name,
+		vector:       h.vector,
+		statsd:       h.statsd,
+		presetLabels: mergeLabels(h.presetLabels, labels),
+	}
+}
@@ -0,0 +1,213 @@
+package xd_rsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Amounts are published as JSON strings, e.g. "19.90"
+	MONEY_FORMAT_STRING = "string"
+	// Amounts are published as JSON numbers with the exact digits, e.g. 19.90
+	MONEY_FORMAT_NUMBER = "number"
+)
+
+var MONEY_FORMATS = []string{MONEY_FORMAT_STRING, MONEY_FORMAT_NUMBER}
+
+var DECIMAL_PATTERN = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+// Exact decimal number, as read from a MySQL DECIMAL column. The zero value is 0.
+type Decimal struct {
+	value *big.Rat
+	// Digits after the decimal point when serialised
+	scale int
+	// Serialised as a JSON number instead of a string
+	isNumber bool
+}
+
+// Parses a plain decimal number, e.g. "-19.90". Its scale is the number of digits after the decimal point.
+func ParseDecimal(text string) (Decimal, error) {
+	text = strings.TrimSpace(text)
+	if !DECIMAL_PATTERN.MatchString(text) {
+		return Decimal{}, fmt.Errorf("could not parse decimal '%s'", text)
+	}
+
+	value, _ := new(big.Rat).SetString(text)
+	scale := 0
+	if _, fraction, hasFraction := strings.Cut(text, "."); hasFraction {
+		scale = len(fraction)
+	}
+
+	return Decimal{value: value, scale: scale}, nil
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.value == nil {
+		return new(big.Rat)
+	}
+
+	return new(big.Rat).Set(d.value)
+}
+
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+func (d Decimal) Sign() int {
+	return d.rat().Sign()
+}
+
+func (d Decimal) Cmp(other Decimal) int {
+	return d.rat().Cmp(other.rat())
+}
+
+// Rounds to the given digits after the decimal point, with halves rounded away from zero
+func (d Decimal) Round(scale int) Decimal {
+	scale = max(scale, 0)
+	rounded, _ := new(big.Rat).SetString(d.rat().FloatString(scale))
+
+	return Decimal{value: rounded, scale: scale, isNumber: d.isNumber}
+}
+
+// Serialises the decimal as a JSON number when enabled, instead of a string
+func (d Decimal) AsNumber(isNumber bool) Decimal {
+	d.isNumber = isNumber
+	return d
+}
+
+// Closest float, for calculations where exactness does not matter
+func (d Decimal) Float64() float64 {
+	value, _ := d.rat().Float64()
+	return value
+}
+
+func (d Decimal) String() string {
+	return d.rat().FloatString(d.scale)
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d.isNumber {
+		return []byte(d.String()), nil
+	}
+
+	return json.Marshal(d.String())
+}
+
+// Accepts both strings and numbers, keeping the format it was read in
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		*d = Decimal{}
+		return nil
+	}
+
+	isNumber := !strings.HasPrefix(text, `"`)
+	if !isNumber {
+		err := json.Unmarshal(data, &text)
+		if err != nil {
+			return fmt.Errorf("could not parse decimal: %w", err)
+		}
+	}
+
+	parsed, err := ParseDecimal(text)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed.AsNumber(isNumber)
+	return nil
+}
+
+// Reads DECIMAL columns, which the MySQL driver returns as text, without going through a float
+func (d *Decimal) Scan(src interface{}) error {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		text = string(value)
+	case string:
+		text = value
+	case int64:
+		text = strconv.FormatInt(value, 10)
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Errorf("could not scan %T into a decimal", src)
+	}
+
+	parsed, err := ParseDecimal(text)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+// Amount of a price, with its VAT-exclusive and VAT-inclusive variants
+type XdMoney struct {
+	// Amount as stored in XD
+	Amount   Decimal `json:"amount"`
+	Currency string  `json:"currency"`
+	// VAT rate in percentage, e.g. 23
+	VatRate            Decimal `json:"vatRate"`
+	AmountExcludingVat Decimal `json:"amountExcludingVat"`
+	AmountIncludingVat Decimal `json:"amountIncludingVat"`
+}
+
+// How the amounts read from XD are published
+type MoneyConfig struct {
+	// ISO 4217 code of the currency of the XD prices, e.g. "EUR"
+	Currency string `json:"currency"`
+	// Digits after the decimal point of the published amounts
+	Scale  int    `json:"scale"`
+	Format string `json:"format"`
+	// Whether the XD retail prices include VAT
+	PricesIncludeVat bool `json:"pricesIncludeVat"`
+}
+
+// Computes the VAT variants of an XD amount, at the given VAT rate in percentage, and rounds them to the scale
+func (c *MoneyConfig) NewMoney(amount Decimal, vatRate Decimal) *XdMoney {
+	if vatRate.Sign() < 0 {
+		vatRate = Decimal{}
+	}
+
+	hundred := big.NewRat(100, 1)
+	vatFactor := new(big.Rat).Quo(new(big.Rat).Add(hundred, vatRate.rat()), hundred)
+
+	includingVat, excludingVat := amount.rat(), amount.rat()
+	if c.PricesIncludeVat {
+		excludingVat.Quo(excludingVat, vatFactor)
+	} else {
+		includingVat.Mul(includingVat, vatFactor)
+	}
+
+	isNumber := c.Format == MONEY_FORMAT_NUMBER
+	return &XdMoney{
+		Amount:             amount.Round(c.Scale).AsNumber(isNumber),
+		Currency:           c.Currency,
+		VatRate:            vatRate.AsNumber(isNumber),
+		AmountExcludingVat: Decimal{value: excludingVat}.Round(c.Scale).AsNumber(isNumber),
+		AmountIncludingVat: Decimal{value: includingVat}.Round(c.Scale).AsNumber(isNumber),
+	}
+}
@@ -0,0 +1,290 @@
+package xd_rsync
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		text          string
+		expectedText  string
+		expectedScale int
+		isValid       bool
+	}{
+		{text: "19.90", expectedText: "19.90", expectedScale: 2, isValid: true},
+		{text: "-19.90", expectedText: "-19.90", expectedScale: 2, isValid: true},
+		{text: " +3 ", expectedText: "3", expectedScale: 0, isValid: true},
+		{text: "0.000", expectedText: "0.000", expectedScale: 3, isValid: true},
+		{text: ""},
+		{text: "1."},
+		{text: ".5"},
+		{text: "1e3"},
+		{text: "1,50"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.text, func(t *testing.T) {
+			decimal, err := ParseDecimal(testCase.text)
+			if !testCase.isValid {
+				if err == nil {
+					t.Errorf("expected an error, got %s", decimal)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if decimal.String() != testCase.expectedText || decimal.Scale() != testCase.expectedScale {
+				t.Errorf("expected %s with scale %d, got %s with scale %d", testCase.expectedText, testCase.expectedScale, decimal, decimal.Scale())
+			}
+		})
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		scale    int
+		expected string
+	}{
+		{name: "half up", text: "1.005", scale: 2, expected: "1.01"},
+		{name: "below half", text: "1.004", scale: 2, expected: "1.00"},
+		{name: "negative half away from zero", text: "-1.005", scale: 2, expected: "-1.01"},
+		{name: "negative below half", text: "-1.004", scale: 2, expected: "-1.00"},
+		{name: "half to an integer", text: "2.5", scale: 0, expected: "3"},
+		{name: "negative half to an integer", text: "-2.5", scale: 0, expected: "-3"},
+		{name: "larger scale", text: "19.9", scale: 3, expected: "19.900"},
+		{name: "negative scale", text: "19.5", scale: -1, expected: "20"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rounded := mustParseDecimal(t, testCase.text).Round(testCase.scale)
+			if rounded.String() != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, rounded)
+			}
+		})
+	}
+}
+
+func TestDecimalScan(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      interface{}
+		expected string
+		isValid  bool
+	}{
+		{name: "NULL", src: nil, expected: "0", isValid: true},
+		{name: "bytes", src: []byte("-12.50"), expected: "-12.50", isValid: true},
+		{name: "string", src: "19.99", expected: "19.99", isValid: true},
+		{name: "integer", src: int64(-3), expected: "-3", isValid: true},
+		{name: "float", src: 1.5, expected: "1.5", isValid: true},
+		{name: "boolean", src: true},
+		{name: "text that is not a number", src: []byte("abc")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decimal := mustParseDecimal(t, "99.99")
+			err := decimal.Scan(testCase.src)
+			if !testCase.isValid {
+				if err == nil {
+					t.Errorf("expected an error, got %s", decimal)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if decimal.String() != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, decimal)
+			}
+		})
+	}
+}
+
+func TestDecimalMarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		decimal  Decimal
+		expected string
+	}{
+		{name: "string", decimal: mustParseDecimal(t, "19.90"), expected: `"19.90"`},
+		{name: "negative string", decimal: mustParseDecimal(t, "-1.50"), expected: `"-1.50"`},
+		{name: "number", decimal: mustParseDecimal(t, "19.90").AsNumber(true), expected: `19.90`},
+		{name: "negative number", decimal: mustParseDecimal(t, "-1.50").AsNumber(true), expected: `-1.50`},
+		{name: "zero value", decimal: Decimal{}, expected: `"0"`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data, err := json.Marshal(testCase.decimal)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if string(data) != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, data)
+			}
+
+			decoded := Decimal{}
+			err = json.Unmarshal(data, &decoded)
+			if err != nil {
+				t.Fatalf("could not decode %s: %v", data, err)
+			}
+
+			data, err = json.Marshal(decoded)
+			if err != nil || string(data) != testCase.expected {
+				t.Errorf("expected %s after a round trip, got %s", testCase.expected, data)
+			}
+		})
+	}
+}
+
+func TestMoneyConfigNewMoney(t *testing.T) {
+	testCases := []struct {
+		name                       string
+		money                      *MoneyConfig
+		amount                     string
+		vatRate                    string
+		expectedAmount             string
+		expectedAmountExcludingVat string
+		expectedAmountIncludingVat string
+	}{
+		{
+			name:                       "VAT included",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2, PricesIncludeVat: true},
+			amount:                     "12.30",
+			vatRate:                    "23",
+			expectedAmount:             "12.30",
+			expectedAmountExcludingVat: "10.00",
+			expectedAmountIncludingVat: "12.30",
+		},
+		{
+			name:                       "VAT included rounding the amount excluding VAT",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2, PricesIncludeVat: true},
+			amount:                     "19.99",
+			vatRate:                    "23",
+			expectedAmount:             "19.99",
+			expectedAmountExcludingVat: "16.25",
+			expectedAmountIncludingVat: "19.99",
+		},
+		{
+			name:                       "VAT excluded",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2},
+			amount:                     "10.00",
+			vatRate:                    "23",
+			expectedAmount:             "10.00",
+			expectedAmountExcludingVat: "10.00",
+			expectedAmountIncludingVat: "12.30",
+		},
+		{
+			name:                       "VAT excluded rounding the amount including VAT",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2},
+			amount:                     "9.99",
+			vatRate:                    "23",
+			expectedAmount:             "9.99",
+			expectedAmountExcludingVat: "9.99",
+			expectedAmountIncludingVat: "12.29",
+		},
+		{
+			name:                       "negative amount",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2, PricesIncludeVat: true},
+			amount:                     "-12.30",
+			vatRate:                    "23",
+			expectedAmount:             "-12.30",
+			expectedAmountExcludingVat: "-10.00",
+			expectedAmountIncludingVat: "-12.30",
+		},
+		{
+			name:                       "negative VAT rate",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2},
+			amount:                     "10.00",
+			vatRate:                    "-23",
+			expectedAmount:             "10.00",
+			expectedAmountExcludingVat: "10.00",
+			expectedAmountIncludingVat: "10.00",
+		},
+		{
+			name:                       "amount rounded to the scale",
+			money:                      &MoneyConfig{Currency: "EUR", Scale: 2},
+			amount:                     "10.005",
+			vatRate:                    "0",
+			expectedAmount:             "10.01",
+			expectedAmountExcludingVat: "10.01",
+			expectedAmountIncludingVat: "10.01",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			money := testCase.money.NewMoney(mustParseDecimal(t, testCase.amount), mustParseDecimal(t, testCase.vatRate))
+			if money.Currency != testCase.money.Currency {
+				t.Errorf("expected currency %s, got %s", testCase.money.Currency, money.Currency)
+			}
+
+			amounts := map[string][2]string{
+				"amount":               {testCase.expectedAmount, money.Amount.String()},
+				"amount excluding VAT": {testCase.expectedAmountExcludingVat, money.AmountExcludingVat.String()},
+				"amount including VAT": {testCase.expectedAmountIncludingVat, money.AmountIncludingVat.String()},
+			}
+			for name, amount := range amounts {
+				if amount[0] != amount[1] {
+					t.Errorf("expected %s %s, got %s", name, amount[0], amount[1])
+				}
+			}
+		})
+	}
+}
+
+func TestMoneyConfigNewMoneyFormat(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   MONEY_FORMAT_STRING,
+			expected: `{"amount":"10.00","currency":"EUR","vatRate":"23","amountExcludingVat":"10.00","amountIncludingVat":"12.30"}`,
+		},
+		{
+			format:   MONEY_FORMAT_NUMBER,
+			expected: `{"amount":10.00,"currency":"EUR","vatRate":23,"amountExcludingVat":10.00,"amountIncludingVat":12.30}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.format, func(t *testing.T) {
+			money := &MoneyConfig{Currency: "EUR", Scale: 2, Format: testCase.format}
+			data, err := json.Marshal(money.NewMoney(mustParseDecimal(t, "10"), mustParseDecimal(t, "23")))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if string(data) != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, data)
+			}
+		})
+	}
+}
+
+func TestSetPricesDerivesClientPrices(t *testing.T) {
+	product := &XdProduct{
+		ExactRetailPrice1: mustParseDecimal(t, "24.995"),
+		ExactRetailPrice2: mustParseDecimal(t, "19.994"),
+	}
+	product.SetPrices(&MoneyConfig{Currency: "EUR", Scale: 2})
+
+	if product.RetailPrice2 != 19.99 {
+		t.Errorf("expected client price 19.99, got %v", product.RetailPrice2)
+	}
+
+	if product.RetailPrice1 != 25 {
+		t.Errorf("expected client compare-at price 25, got %v", product.RetailPrice1)
+	}
+}
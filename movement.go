@@ -0,0 +1,57 @@
+package xd_rsync
+
+import (
+	"time"
+)
+
+const (
+	MOVEMENT_TYPE_ENTRANCE = "entrance"
+	MOVEMENT_TYPE_EXIT     = "exit"
+)
+
+// Entrance or exit of stock in a warehouse, read from the stock ledger of XD
+type XdStockMovement struct {
+	Id          string `db:"KeyId" dbSelector:"m.KeyId" json:"id"`
+	SKU         string `db:"ItemKeyId" dbSelector:"m.ItemKeyId" json:"sku"`
+	WarehouseId string `db:"WarehouseId" dbSelector:"m.WarehouseId" json:"warehouseId"`
+	// XD records exits as negative quantities, which are published as positive quantities of an exit. One of
+	// MOVEMENT_TYPE_ENTRANCE or MOVEMENT_TYPE_EXIT.
+	Type           string     `db:"MovementType" dbSelector:"IF(m.Quantity < 0, 'exit', 'entrance') as MovementType" json:"type"`
+	Quantity       float64    `db:"Quantity" dbSelector:"ABS(m.Quantity) as Quantity" json:"quantity"`
+	DocumentType   *string    `db:"DocType" dbSelector:"m.DocType" json:"documentType,omitempty"`
+	DocumentNumber *string    `db:"DocumentNumber" dbSelector:"m.DocumentNumber" json:"documentNumber,omitempty"`
+	MovedAt        *time.Time `db:"MovementDate" dbSelector:"m.MovementDate" json:"movedAt"`
+	SyncStamp      *time.Time `db:"SyncStamp" dbSelector:"m.SyncStamp as SyncStamp" json:"syncStamp"`
+}
+
+func (m *XdStockMovement) GetEntityName() string {
+	return "stock_movements"
+}
+
+func (m *XdStockMovement) GetTableName() string {
+	return "stockmovements m"
+}
+
+func (m *XdStockMovement) GetJoinExpressions() []string {
+	return nil
+}
+
+func (m *XdStockMovement) GetConditions() []Condition {
+	return nil
+}
+
+func (m *XdStockMovement) GetChangeColumns() []string {
+	return []string{"m.SyncStamp"}
+}
+
+func (m *XdStockMovement) GetPrimaryKeyColumnName() string {
+	return "m.KeyId"
+}
+
+func (m *XdStockMovement) GetKey() string {
+	return m.Id
+}
+
+func (m *XdStockMovement) GetLastChangedAt() *time.Time {
+	return m.SyncStamp
+}
@@ -0,0 +1,160 @@
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sink of the published messages
+const SINK_SNS = "sns"
+
+var SUPPORTED_SINKS = []string{SINK_SNS}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Hashes or leaves out the personal data fields of the records sent to a sink
+type Policy struct {
+	hashKey       []byte
+	hashedFields  []string
+	omittedFields []string
+}
+
+type PolicyCreationInput struct {
+	HashKey string
+	// Paths of the fields to hash, made of their JSON names, e.g. "contacts.email"
+	HashedFields  []string
+	OmittedFields []string
+}
+
+func CreatePolicy(input *PolicyCreationInput) *Policy {
+	return &Policy{
+		hashKey:       []byte(input.HashKey),
+		hashedFields:  input.HashedFields,
+		omittedFields: input.OmittedFields,
+	}
+}
+
+func (p *Policy) isEmpty() bool {
+	return p == nil || (len(p.hashedFields) == 0 && len(p.omittedFields) == 0)
+}
+
+// Returns the HMAC-SHA256 of the value, so equal values can be matched without being revealed
+func (p *Policy) hash(value string) string {
+	mac := hmac.New(sha256.New, p.hashKey)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns a copy of the record with the policy applied. The given record is left untouched.
+func (p *Policy) Apply(record interface{}) interface{} {
+	value := reflect.ValueOf(record)
+	if p.isEmpty() || value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return record
+	}
+
+	copied := reflect.New(value.Elem().Type())
+	copied.Elem().Set(value.Elem())
+	p.applyToStruct(copied.Elem(), "")
+
+	return copied.Interface()
+}
+
+func (p *Policy) applyToStruct(value reflect.Value, prefix string) {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := value.Field(i)
+		path := prefix + getJsonFieldName(field)
+
+		if isNestedStruct(field.Type) {
+			p.applyToStruct(fieldValue, path+".")
+			continue
+		}
+
+		if field.Tag.Get("pii") != "true" {
+			continue
+		}
+
+		if slices.Contains(p.omittedFields, path) {
+			fieldValue.SetZero()
+			continue
+		}
+
+		if !slices.Contains(p.hashedFields, path) {
+			continue
+		}
+
+		// Only text can be replaced by its hash, so other values are never published rather than published as is
+		if !isHashable(field.Type) {
+			fieldValue.SetZero()
+		} else if field.Type.Kind() == reflect.String {
+			fieldValue.SetString(p.hash(fieldValue.String()))
+		} else if !fieldValue.IsNil() {
+			hashed := p.hash(fieldValue.Elem().String())
+			fieldValue.Set(reflect.ValueOf(&hashed))
+		}
+	}
+}
+
+// Returns the paths of the personal data fields of a record
+func GetFields(record interface{}) []string {
+	recordType := reflect.TypeOf(record)
+	for recordType.Kind() == reflect.Pointer {
+		recordType = recordType.Elem()
+	}
+
+	return getStructFields(recordType, "", false)
+}
+
+// Returns the paths of the personal data fields of a record that can be hashed, as they hold text
+func GetHashableFields(record interface{}) []string {
+	recordType := reflect.TypeOf(record)
+	for recordType.Kind() == reflect.Pointer {
+		recordType = recordType.Elem()
+	}
+
+	return getStructFields(recordType, "", true)
+}
+
+func getStructFields(structType reflect.Type, prefix string, isHashableOnly bool) []string {
+	fields := []string{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		path := prefix + getJsonFieldName(field)
+
+		if isNestedStruct(field.Type) {
+			fields = append(fields, getStructFields(field.Type, path+".", isHashableOnly)...)
+		} else if field.Tag.Get("pii") == "true" && (!isHashableOnly || isHashable(field.Type)) {
+			fields = append(fields, path)
+		}
+	}
+
+	return fields
+}
+
+func isHashable(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	return fieldType.Kind() == reflect.String
+}
+
+func isNestedStruct(fieldType reflect.Type) bool {
+	return fieldType.Kind() == reflect.Struct && fieldType != timeType
+}
+
+func getJsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if len(name) == 0 {
+		return field.Name
+	}
+
+	return name
+}
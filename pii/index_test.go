@@ -0,0 +1,208 @@
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testContacts struct {
+	Email *string `json:"email,omitempty" pii:"true"`
+	Phone *string `json:"phone,omitempty" pii:"true"`
+}
+
+type testCustomer struct {
+	Id        string       `json:"id"`
+	Name      string       `json:"name" pii:"true"`
+	TaxId     *string      `json:"taxId,omitempty" pii:"true"`
+	BirthDate *time.Time   `json:"birthDate,omitempty" pii:"true"`
+	Age       int          `json:"age" pii:"true"`
+	Contacts  testContacts `json:"contacts"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+func getTestHash(key string, value string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func createTestCustomer() *testCustomer {
+	email := "ana@example.com"
+	taxId := "123456789"
+	birthDate := time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	return &testCustomer{
+		Id:        "C1",
+		Name:      "Ana",
+		TaxId:     &taxId,
+		BirthDate: &birthDate,
+		Age:       34,
+		Contacts:  testContacts{Email: &email},
+		UpdatedAt: birthDate,
+	}
+}
+
+func getStringValue(value *string) string {
+	if value == nil {
+		return "<nil>"
+	}
+
+	return *value
+}
+
+func TestPolicyApply(t *testing.T) {
+	testCases := []struct {
+		name            string
+		hashedFields    []string
+		omittedFields   []string
+		expectedName    string
+		expectedTaxId   string
+		expectedEmail   string
+		expectedPhone   string
+		isBirthDateKept bool
+		expectedAge     int
+	}{
+		{
+			name:            "empty policy",
+			expectedName:    "Ana",
+			expectedTaxId:   "123456789",
+			expectedEmail:   "ana@example.com",
+			expectedPhone:   "<nil>",
+			isBirthDateKept: true,
+			expectedAge:     34,
+		},
+		{
+			name:            "hashed text fields",
+			hashedFields:    []string{"name", "taxId", "contacts.email"},
+			expectedName:    getTestHash("secret", "Ana"),
+			expectedTaxId:   getTestHash("secret", "123456789"),
+			expectedEmail:   getTestHash("secret", "ana@example.com"),
+			expectedPhone:   "<nil>",
+			isBirthDateKept: true,
+			expectedAge:     34,
+		},
+		{
+			name:            "hashed nil pointer",
+			hashedFields:    []string{"contacts.phone"},
+			expectedName:    "Ana",
+			expectedTaxId:   "123456789",
+			expectedEmail:   "ana@example.com",
+			expectedPhone:   "<nil>",
+			isBirthDateKept: true,
+			expectedAge:     34,
+		},
+		{
+			name:          "omitted fields",
+			omittedFields: []string{"name", "taxId", "contacts.email", "birthDate", "age"},
+			expectedName:  "",
+			expectedTaxId: "<nil>",
+			expectedEmail: "<nil>",
+			expectedPhone: "<nil>",
+		},
+		{
+			name:          "hashed fields that are not text",
+			hashedFields:  []string{"birthDate", "age"},
+			expectedName:  "Ana",
+			expectedTaxId: "123456789",
+			expectedEmail: "ana@example.com",
+			expectedPhone: "<nil>",
+		},
+		{
+			name:            "fields that are not personal data",
+			hashedFields:    []string{"id"},
+			omittedFields:   []string{"updatedAt"},
+			expectedName:    "Ana",
+			expectedTaxId:   "123456789",
+			expectedEmail:   "ana@example.com",
+			expectedPhone:   "<nil>",
+			isBirthDateKept: true,
+			expectedAge:     34,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			policy := CreatePolicy(&PolicyCreationInput{
+				HashKey:       "secret",
+				HashedFields:  testCase.hashedFields,
+				OmittedFields: testCase.omittedFields,
+			})
+
+			customer := createTestCustomer()
+			applied, isCustomer := policy.Apply(customer).(*testCustomer)
+			if !isCustomer {
+				t.Fatalf("expected a customer, got %T", policy.Apply(customer))
+			}
+
+			values := map[string][2]string{
+				"name":           {testCase.expectedName, applied.Name},
+				"taxId":          {testCase.expectedTaxId, getStringValue(applied.TaxId)},
+				"contacts.email": {testCase.expectedEmail, getStringValue(applied.Contacts.Email)},
+				"contacts.phone": {testCase.expectedPhone, getStringValue(applied.Contacts.Phone)},
+			}
+			for path, value := range values {
+				if value[0] != value[1] {
+					t.Errorf("expected %s %s, got %s", path, value[0], value[1])
+				}
+			}
+
+			if isBirthDateKept := applied.BirthDate != nil; isBirthDateKept != testCase.isBirthDateKept {
+				t.Errorf("expected birth date kept %v, got %v", testCase.isBirthDateKept, applied.BirthDate)
+			}
+
+			if applied.Age != testCase.expectedAge {
+				t.Errorf("expected age %d, got %d", testCase.expectedAge, applied.Age)
+			}
+
+			if applied.Id != "C1" || !applied.UpdatedAt.Equal(customer.UpdatedAt) {
+				t.Errorf("expected the other fields to be kept, got %+v", applied)
+			}
+
+			expected := createTestCustomer()
+			if customer.Name != expected.Name || *customer.TaxId != *expected.TaxId || *customer.Contacts.Email != *expected.Contacts.Email ||
+				!customer.BirthDate.Equal(*expected.BirthDate) || customer.Age != expected.Age {
+				t.Errorf("expected the given customer not to be changed, got %+v", customer)
+			}
+		})
+	}
+}
+
+func TestPolicyApplyKeepsRecordsItCannotCopy(t *testing.T) {
+	policy := CreatePolicy(&PolicyCreationInput{HashKey: "secret", OmittedFields: []string{"name"}})
+
+	var nilCustomer *testCustomer
+	if applied := policy.Apply(nilCustomer); applied != nilCustomer {
+		t.Errorf("expected a nil record to be returned as is, got %v", applied)
+	}
+
+	if applied := policy.Apply(nil); applied != nil {
+		t.Errorf("expected nil to be returned as is, got %v", applied)
+	}
+
+	customer := testCustomer{Name: "Ana"}
+	if applied := policy.Apply(customer).(testCustomer); applied.Name != "Ana" {
+		t.Errorf("expected a record that is not a pointer to be returned as is, got %+v", applied)
+	}
+
+	var emptyPolicy *Policy
+	if applied := emptyPolicy.Apply(&customer); applied != &customer {
+		t.Errorf("expected a nil policy to return the record as is, got %v", applied)
+	}
+}
+
+func TestGetFields(t *testing.T) {
+	expectedFields := []string{"name", "taxId", "birthDate", "age", "contacts.email", "contacts.phone"}
+	if fields := GetFields(&testCustomer{}); !slices.Equal(fields, expectedFields) {
+		t.Errorf("expected fields %v, got %v", expectedFields, fields)
+	}
+
+	expectedHashableFields := []string{"name", "taxId", "contacts.email", "contacts.phone"}
+	if fields := GetHashableFields(testCustomer{}); !slices.Equal(fields, expectedHashableFields) {
+		t.Errorf("expected hashable fields %v, got %v", expectedHashableFields, fields)
+	}
+}
@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+var ErrMassChangeOnHold = errors.New("mass change is on hold until it is confirmed")
+
+// Returns whether the changed records of the stream are a mass change to be published to the bulk topic. Mass
+// changes that must be confirmed first are held back with an error, so the checkpoint does not move past them.
+func (p *Pipeline) checkMassChange(ctx context.Context, stream *EntityStream, changedCount int) (bool, error) {
+	massChangeConfig := p.app.GetConfig().MassChange
+	if stream.GetBulkTopicArn == nil || !massChangeConfig.IsEnabled() {
+		return false, nil
+	}
+
+	entityName := stream.GetName()
+	hold := p.source.State.GetMassChangeHold()
+	if hold != nil && hold.Entity == entityName && hold.IsConfirmed {
+		p.source.State.SetMassChangeHold(nil)
+		p.source.Logger.Info("release_mass_change", "Publishing confirmed mass change", &map[string]interface{}{
+			"entity":       entityName,
+			"changedCount": changedCount,
+		})
+		return false, nil
+	}
+
+	// The total is only needed by the percentage limit, so it is not counted when the other limit is enough
+	totalCount := 0
+	if massChangeConfig.MaxChangedPercentage > 0 && !massChangeConfig.IsMassChange(changedCount, 0) {
+		var err error
+		totalCount, err = p.source.Database.GetEntitiesCount(ctx, p.getEntityQuery(stream, nil))
+		if err != nil {
+			p.source.Logger.Error("failed_count_entities", "Failed to count entities to check for a mass change", &map[string]interface{}{
+				"entity": entityName,
+				"error":  err.Error(),
+			})
+			return false, err
+		}
+	}
+
+	if !massChangeConfig.IsMassChange(changedCount, totalCount) {
+		// The changes were skipped, e.g. by moving the checkpoint through the admin API
+		if hold != nil && hold.Entity == entityName {
+			p.source.State.SetMassChangeHold(nil)
+		}
+		return false, nil
+	}
+
+	p.source.Metrics.ObserveMassChange(massChangeConfig.Action)
+	if massChangeConfig.Action == xd_rsync.MASS_CHANGE_ACTION_BULK {
+		p.source.Logger.Warn("bulk_mass_change", "Changes are a mass change and will be published to the bulk topic", &map[string]interface{}{
+			"entity":       entityName,
+			"changedCount": changedCount,
+			"totalCount":   totalCount,
+		})
+		return true, nil
+	}
+
+	detectedAt := time.Now()
+	if hold != nil && hold.Entity == entityName {
+		detectedAt = hold.DetectedAt
+	}
+	p.source.State.SetMassChangeHold(&xd_rsync.MassChangeHold{
+		Entity:       entityName,
+		ChangedCount: changedCount,
+		TotalCount:   totalCount,
+		DetectedAt:   detectedAt,
+	})
+	p.source.Logger.Warn("mass_change_on_hold", "Changes are a mass change and will not be published until confirmed", &map[string]interface{}{
+		"entity":       entityName,
+		"changedCount": changedCount,
+		"totalCount":   totalCount,
+	})
+
+	return false, ErrMassChangeOnHold
+}
+
+// Publishes the records to the bulk topic of the stream in batches, waiting between them
+func (p *Pipeline) publishBulkEntities(ctx context.Context, stream *EntityStream, entities []xd_rsync.Entity) (int, []error) {
+	massChangeConfig := p.app.GetConfig().MassChange
+	topicArn := stream.GetBulkTopicArn(p.getSourceConfig())
+
+	successfulMessages := 0
+	for start := 0; start < len(entities); start += massChangeConfig.BulkBatchSize {
+		if start > 0 {
+			select {
+			case <-ctx.Done():
+				return successfulMessages, []error{ctx.Err()}
+			case <-time.After(massChangeConfig.BulkBatchInterval):
+			}
+		}
+
+		end := min(start+massChangeConfig.BulkBatchSize, len(entities))
+		batchSuccessfulMessages, errs := p.publishEntitiesToTopic(ctx, stream, topicArn, entities[start:end], nil)
+		successfulMessages += batchSuccessfulMessages
+		if len(errs) > 0 {
+			p.source.Logger.Error("failed_publish_bulk_entities", "Failed to publish entities to the bulk topic", &map[string]interface{}{
+				"entity": stream.GetName(),
+				"error":  errs,
+			})
+			return successfulMessages, errs
+		}
+	}
+
+	return successfulMessages, nil
+}
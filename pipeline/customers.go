@@ -0,0 +1,16 @@
+package pipeline
+
+import (
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Customers are only captured when the source has a topic for them
+var CUSTOMERS_STREAM = &EntityStream{
+	Entity: &xd_rsync.XdCustomer{},
+	GetTopicArn: func(source *xd_rsync.SourceConfig) string {
+		return source.Queues.CustomerUpdatesSnsQueueArn
+	},
+	IsEnabled: func(source *xd_rsync.SourceConfig) bool {
+		return len(source.Queues.CustomerUpdatesSnsQueueArn) > 0
+	},
+}
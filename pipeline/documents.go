@@ -0,0 +1,28 @@
+package pipeline
+
+import (
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Sales documents are only captured when the source has a topic for them
+var DOCUMENTS_STREAM = &EntityStream{
+	Entity: &xd_rsync.XdSalesDocument{},
+	GetTopicArn: func(source *xd_rsync.SourceConfig) string {
+		return source.Queues.SalesDocumentUpdatesSnsQueueArn
+	},
+	IsEnabled: func(source *xd_rsync.SourceConfig) bool {
+		return len(source.Queues.SalesDocumentUpdatesSnsQueueArn) > 0
+	},
+	GetConditions: getDocumentConditions,
+}
+
+// Only selects the document types allowed by the source filters
+func getDocumentConditions(source *xd_rsync.SourceConfig) []xd_rsync.Condition {
+	if source.Filters == nil || len(source.Filters.DocumentTypes) == 0 {
+		return nil
+	}
+
+	return []xd_rsync.Condition{
+		xd_rsync.NewCondition("d.DocType IN (?)", source.Filters.DocumentTypes),
+	}
+}
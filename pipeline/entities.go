@@ -0,0 +1,399 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+	"github.com/fabiofcferreira/xd-rsync/pii"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Change capture of an entity, published to its own topic
+type EntityStream struct {
+	// Prototype of the captured records, e.g. &xd_rsync.XdProduct{}
+	Entity xd_rsync.Entity
+	// Optional name used instead of the entity name, for records of an entity captured apart from its own stream
+	Name string
+	// Returns the topic where the records are published, from the current source configuration
+	GetTopicArn func(source *xd_rsync.SourceConfig) string
+	// Optional check leaving records out before they are published
+	IsIncluded func(source *xd_rsync.SourceConfig, entity xd_rsync.Entity) bool
+	// Optional check skipping the stream, e.g. when it has no topic configured
+	IsEnabled func(source *xd_rsync.SourceConfig) bool
+	// Keeps a checkpoint of its own instead of sharing the source checkpoint, so its failures do not hold back the
+	// other streams and the other streams' failures do not make it publish records again, e.g. for ledgers
+	HasOwnCheckpoint bool
+	// Optional changes to the records before they are published, from the current source configuration
+	Prepare func(source *xd_rsync.SourceConfig, entity xd_rsync.Entity)
+	// Optional step run with the records once all of them were published
+	AfterPublish func(p *Pipeline, ctx context.Context, entities []xd_rsync.Entity) []error
+	// Optional conditions selecting the records in the database, from the current source configuration
+	GetConditions func(source *xd_rsync.SourceConfig) []xd_rsync.Condition
+	// Optional topic where the records of a mass change are published. Streams with it are checked for mass changes.
+	GetBulkTopicArn func(source *xd_rsync.SourceConfig) string
+	// Optional check holding back records before they are published, returning the ones that can be published
+	Guard func(p *Pipeline, ctx context.Context, entities []xd_rsync.Entity) ([]xd_rsync.Entity, []error)
+	// Optional joins and change tracking columns added to the queries, from the current configuration
+	ExtendQuery func(cfg *xd_rsync.Config, query *xd_rsync.EntityQuery)
+	// Optional step reading more data into the fetched records, e.g. from tables set in the configuration
+	Load func(p *Pipeline, ctx context.Context, entities []xd_rsync.Entity) error
+}
+
+func (s *EntityStream) GetName() string {
+	if len(s.Name) > 0 {
+		return s.Name
+	}
+
+	return s.Entity.GetEntityName()
+}
+
+func (s *EntityStream) isEnabled(source *xd_rsync.SourceConfig) bool {
+	return s.IsEnabled == nil || s.IsEnabled(source)
+}
+
+// Streams captured on every sync run
+var ENTITY_STREAMS = []*EntityStream{PRODUCTS_STREAM, CUSTOMERS_STREAM, DOCUMENTS_STREAM, STOCK_MOVEMENTS_STREAM}
+
+// Returns the query selecting the records of the stream changed after the given time, or all of them when nil
+func (p *Pipeline) getEntityQuery(stream *EntityStream, changedAfter *time.Time) *xd_rsync.EntityQuery {
+	query := &xd_rsync.EntityQuery{
+		Entity:       stream.Entity,
+		ChangedAfter: changedAfter,
+	}
+	if stream.GetConditions != nil {
+		query.Conditions = stream.GetConditions(p.getSourceConfig())
+	}
+	if stream.ExtendQuery != nil {
+		stream.ExtendQuery(p.app.GetConfig(), query)
+	}
+
+	return query
+}
+
+// Reads the data the stream loads separately into the fetched records
+func (p *Pipeline) loadEntities(ctx context.Context, stream *EntityStream, entities []xd_rsync.Entity) error {
+	if stream.Load == nil || len(entities) == 0 {
+		return nil
+	}
+
+	return stream.Load(p, ctx, entities)
+}
+
+// Returns the personal data policy of the given sink, from the current configuration
+func (p *Pipeline) getPiiPolicy(sink string) *pii.Policy {
+	piiConfig := p.app.GetConfig().Pii
+	if piiConfig == nil || piiConfig.Sinks[sink] == nil {
+		return nil
+	}
+
+	return pii.CreatePolicy(&pii.PolicyCreationInput{
+		HashKey:       piiConfig.HashKey,
+		HashedFields:  piiConfig.Sinks[sink].HashedFields,
+		OmittedFields: piiConfig.Sinks[sink].OmittedFields,
+	})
+}
+
+// Leaves out the records excluded by the stream and prepares the others to be published
+func (p *Pipeline) filterEntities(stream *EntityStream, entities []xd_rsync.Entity) []xd_rsync.Entity {
+	if stream.IsIncluded == nil && stream.Prepare == nil {
+		return entities
+	}
+
+	sourceConfig := p.getSourceConfig()
+	filteredEntities := []xd_rsync.Entity{}
+	for _, entity := range entities {
+		if stream.IsIncluded != nil && !stream.IsIncluded(sourceConfig, entity) {
+			continue
+		}
+
+		if stream.Prepare != nil {
+			stream.Prepare(sourceConfig, entity)
+		}
+		filteredEntities = append(filteredEntities, entity)
+	}
+
+	return filteredEntities
+}
+
+// Leaves out the records held back by the guard of the stream
+func (p *Pipeline) guardEntities(ctx context.Context, stream *EntityStream, entities []xd_rsync.Entity) ([]xd_rsync.Entity, []error) {
+	if stream.Guard == nil {
+		return entities, nil
+	}
+
+	return stream.Guard(p, ctx, entities)
+}
+
+// Publishes the records of the stream changed since the given time
+func (p *Pipeline) captureChanges(ctx context.Context, stream *EntityStream, since time.Time) (int, []error) {
+	entityName := stream.GetName()
+	p.source.Logger.Info("init_capture_changes", "Starting process to send events for changed entities", &map[string]interface{}{
+		"entity": entityName,
+	})
+
+	entities, err := p.source.Database.GetEntities(ctx, p.getEntityQuery(stream, &since))
+	if err == nil {
+		err = p.loadEntities(ctx, stream, entities)
+	}
+	if err != nil {
+		p.source.Logger.Error("failed_get_changed_entities", "Failed to get changed entities", &map[string]interface{}{
+			"entity": entityName,
+			"error":  err.Error(),
+		})
+
+		return 0, []error{err}
+	}
+
+	lagTracker := p.source.Metrics.CreateReplicationLagTracker(entityName, p.app.GetConfig().ReplicationLagSlo)
+	return p.publishChanges(ctx, stream, entities, lagTracker)
+}
+
+// Publishes changed records of the stream through the mass change breaker and the guard of the stream. The replication
+// lag is only tracked when a tracker is given.
+func (p *Pipeline) publishChanges(ctx context.Context, stream *EntityStream, entities []xd_rsync.Entity, lagTracker *metrics.ReplicationLagTracker) (int, []error) {
+	entityName := stream.GetName()
+	entities = p.filterEntities(stream, entities)
+	p.source.Metrics.ObserveEntityChanges(entityName, len(entities))
+	isMassChange, err := p.checkMassChange(ctx, stream, len(entities))
+	if err != nil {
+		return len(entities), []error{err}
+	}
+
+	entities, errs := p.guardEntities(ctx, stream, entities)
+	if len(errs) > 0 {
+		return len(entities), errs
+	}
+	if len(entities) == 0 {
+		p.source.Logger.Info("skip_capture_changes", "No entities were changed since last check", &map[string]interface{}{
+			"entity": entityName,
+		})
+
+		return 0, nil
+	}
+
+	if isMassChange {
+		successfulMessages, errs := p.publishBulkEntities(ctx, stream, entities)
+		if len(errs) > 0 {
+			return len(entities), errs
+		}
+
+		p.source.Logger.Info("finished_capture_changes", "Finished sending changed entities' events to the bulk topic", &map[string]interface{}{
+			"entity":                  entityName,
+			"changedEntitiesCount":    len(entities),
+			"successfulMessagesCount": successfulMessages,
+		})
+		return len(entities), nil
+	}
+
+	successfulMessages, errs := p.publishEntities(ctx, stream, entities, lagTracker)
+	if lagTracker != nil {
+		p.logReplicationLag(stream, lagTracker)
+	}
+	if len(errs) > 0 {
+		p.source.Logger.Error("failed_capture_changes", "Failed to publish changed entities events", &map[string]interface{}{
+			"entity": entityName,
+			"error":  errs,
+		})
+
+		return len(entities), errs
+	}
+
+	p.source.Logger.Info("finished_capture_changes", "Finished sending changed entities' events", &map[string]interface{}{
+		"entity":                  entityName,
+		"changedEntitiesCount":    len(entities),
+		"successfulMessagesCount": successfulMessages,
+	})
+
+	return len(entities), nil
+}
+
+// Publishes the current state of the given records, regardless of the checkpoint
+func (p *Pipeline) republishEntities(ctx context.Context, stream *EntityStream, keys []string) (int, []error) {
+	p.runMutex.Lock()
+	defer p.runMutex.Unlock()
+
+	entityName := stream.GetName()
+	ctx, span := tracing.StartSpan(ctx, "republish_"+entityName,
+		attribute.Int("republish.requested_count", len(keys)),
+	)
+	defer span.End()
+
+	p.source.Logger.Info("init_republish_entities", "Republishing entities", &map[string]interface{}{
+		"entity": entityName,
+		"keys":   keys,
+	})
+
+	entities, err := p.source.Database.GetEntitiesByKey(ctx, p.getEntityQuery(stream, nil), keys)
+	if err == nil {
+		err = p.loadEntities(ctx, stream, entities)
+	}
+	if err != nil {
+		p.source.Logger.Error("failed_republish_entities", "Failed to get entities to republish", &map[string]interface{}{
+			"entity": entityName,
+			"error":  err.Error(),
+			"keys":   keys,
+		})
+		return 0, []error{err}
+	}
+
+	entities, errs := p.guardEntities(ctx, stream, p.filterEntities(stream, entities))
+	if len(errs) > 0 {
+		return 0, errs
+	}
+
+	successfulMessages, errs := p.publishEntities(ctx, stream, entities, nil)
+	if len(errs) > 0 {
+		p.source.Logger.Error("failed_republish_entities", "Failed to republish entities", &map[string]interface{}{
+			"entity": entityName,
+			"error":  errs,
+			"keys":   keys,
+		})
+		return successfulMessages, errs
+	}
+
+	p.source.Logger.Info("finished_republish_entities", "Republished entities", &map[string]interface{}{
+		"entity":                  entityName,
+		"requestedCount":          len(keys),
+		"foundCount":              len(entities),
+		"successfulMessagesCount": successfulMessages,
+	})
+	return successfulMessages, nil
+}
+
+// Publishes every record changed within the given range. A nil bound leaves that side of the range open.
+func (p *Pipeline) resyncEntities(ctx context.Context, stream *EntityStream, from *time.Time, to *time.Time) (int, []error) {
+	p.runMutex.Lock()
+	defer p.runMutex.Unlock()
+
+	entityName := stream.GetName()
+	ctx, span := tracing.StartSpan(ctx, "resync_"+entityName)
+	defer span.End()
+
+	p.source.Logger.Info("init_resync_entities", "Republishing entities", &map[string]interface{}{
+		"entity": entityName,
+		"from":   from,
+		"to":     to,
+	})
+
+	entities, err := p.source.Database.GetEntities(ctx, p.getEntityQuery(stream, from))
+	if err == nil {
+		err = p.loadEntities(ctx, stream, entities)
+	}
+	if err != nil {
+		p.source.Logger.Error("failed_resync_entities", "Failed to get entities to republish", &map[string]interface{}{
+			"entity": entityName,
+			"error":  err.Error(),
+		})
+		return 0, []error{err}
+	}
+
+	entitiesInRange := []xd_rsync.Entity{}
+	for _, entity := range p.filterEntities(stream, entities) {
+		lastChangedAt := entity.GetLastChangedAt()
+		if to != nil && lastChangedAt != nil && lastChangedAt.After(*to) {
+			continue
+		}
+
+		entitiesInRange = append(entitiesInRange, entity)
+	}
+	span.SetAttributes(attribute.Int("resync.entities_count", len(entitiesInRange)))
+
+	entitiesInRange, errs := p.guardEntities(ctx, stream, entitiesInRange)
+	if len(errs) > 0 {
+		return 0, errs
+	}
+
+	successfulMessages, errs := p.publishEntities(ctx, stream, entitiesInRange, nil)
+	if len(errs) > 0 {
+		p.source.Logger.Error("failed_resync_entities", "Failed to republish entities", &map[string]interface{}{
+			"entity": entityName,
+			"error":  errs,
+		})
+		return successfulMessages, errs
+	}
+
+	p.source.Logger.Info("finished_resync_entities", "Republished entities", &map[string]interface{}{
+		"entity":                  entityName,
+		"entitiesCount":           len(entitiesInRange),
+		"successfulMessagesCount": successfulMessages,
+	})
+	return successfulMessages, nil
+}
+
+func (p *Pipeline) publishEntities(ctx context.Context, stream *EntityStream, entities []xd_rsync.Entity, lagTracker *metrics.ReplicationLagTracker) (int, []error) {
+	return p.publishEntitiesToTopic(ctx, stream, stream.GetTopicArn(p.getSourceConfig()), entities, lagTracker)
+}
+
+func (p *Pipeline) publishEntitiesToTopic(ctx context.Context, stream *EntityStream, topicArn string, entities []xd_rsync.Entity, lagTracker *metrics.ReplicationLagTracker) (int, []error) {
+	entityName := stream.GetName()
+	piiPolicy := p.getPiiPolicy(pii.SINK_SNS)
+	events := []xd_rsync.MessagePublishInput{}
+	keys := []string{}
+	for _, entity := range entities {
+		key := entity.GetKey()
+		encodedEntity, err := p.app.Services.Encoder.Encode(piiPolicy.Apply(entity))
+		if err != nil {
+			p.source.Logger.Error("failed_encode_entity", "Failed to encode entity for SNS topic message", &map[string]interface{}{
+				"entity": entityName,
+				"error":  err.Error(),
+				"key":    key,
+			})
+
+			return 0, []error{err}
+		}
+
+		keys = append(keys, key)
+
+		lastChangedAt := entity.GetLastChangedAt()
+		events = append(events, xd_rsync.MessagePublishInput{
+			Message:        encodedEntity.Body,
+			MessageGroupId: key,
+			Attributes:     p.buildMessageAttributes(ctx, encodedEntity),
+			OnAcknowledged: func(acknowledgedAt time.Time) {
+				if lagTracker != nil && lastChangedAt != nil {
+					lagTracker.Observe(key, *lastChangedAt, acknowledgedAt)
+				}
+			},
+		})
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	p.source.Logger.Info("count_entity_change_events", "Got all entity change events", &map[string]interface{}{
+		"entity":               entityName,
+		"changedEntitiesCount": len(events),
+		"keys":                 keys,
+	})
+
+	successfulMessages, errs := p.source.SNS.SendMessagesBatch(ctx, topicArn, &events)
+	if len(errs) > 0 || stream.AfterPublish == nil {
+		return successfulMessages, errs
+	}
+
+	return successfulMessages, stream.AfterPublish(p, ctx, entities)
+}
+
+func (p *Pipeline) logReplicationLag(stream *EntityStream, lagTracker *metrics.ReplicationLagTracker) {
+	maxLag, breaches := lagTracker.Finish()
+	if len(breaches) == 0 {
+		return
+	}
+
+	breachingKeys := []string{}
+	for _, breach := range breaches {
+		breachingKeys = append(breachingKeys, breach.Key)
+	}
+
+	p.source.Logger.Warn("replication_lag_slo_breached", "Entities were published after the replication lag SLO", &map[string]interface{}{
+		"entity":        stream.GetName(),
+		"slo":           p.app.GetConfig().ReplicationLagSlo.String(),
+		"maxLag":        maxLag.String(),
+		"breachesCount": len(breaches),
+		"breachingKeys": breachingKeys,
+	})
+}
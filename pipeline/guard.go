@@ -0,0 +1,366 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Key of the last published base price of each product, by SKU, in the state store
+const PUBLISHED_PRICES_STATE_KEY = "published_prices"
+
+// Key of the products held back by the price guard, by SKU, in the state store
+const QUARANTINE_STATE_KEY = "quarantine"
+
+var ErrProductNotQuarantined = errors.New("product is not quarantined")
+
+// Quarantined product as kept in the state store, with the exact prices that are left out of the product events, so
+// an approved product is published with the prices it was quarantined with
+type storedQuarantinedProduct struct {
+	xd_rsync.XdQuarantinedProduct
+	ExactPrices *storedExactPrices `json:"exactPrices"`
+}
+
+type storedExactPrices struct {
+	CostPrice           xd_rsync.Decimal   `json:"costPrice"`
+	RetailPrice1        xd_rsync.Decimal   `json:"retailPrice1"`
+	RetailPrice2        xd_rsync.Decimal   `json:"retailPrice2"`
+	VatRate             xd_rsync.Decimal   `json:"vatRate"`
+	PriceListsSyncStamp *time.Time         `json:"priceListsSyncStamp"`
+	PriceListAmounts    []xd_rsync.Decimal `json:"priceListAmounts"`
+	PromotionAmount     *xd_rsync.Decimal  `json:"promotionAmount"`
+}
+
+func getStoredExactPrices(product *xd_rsync.XdProduct) *storedExactPrices {
+	exactPrices := &storedExactPrices{
+		CostPrice:           product.CostPrice,
+		RetailPrice1:        product.ExactRetailPrice1,
+		RetailPrice2:        product.ExactRetailPrice2,
+		VatRate:             product.VatRate,
+		PriceListsSyncStamp: product.PriceListsSyncStamp,
+		PriceListAmounts:    []xd_rsync.Decimal{},
+	}
+	for _, priceList := range product.PriceLists {
+		exactPrices.PriceListAmounts = append(exactPrices.PriceListAmounts, priceList.Amount)
+	}
+	if product.Promotion != nil {
+		exactPrices.PromotionAmount = &product.Promotion.Amount
+	}
+
+	return exactPrices
+}
+
+func (s *storedExactPrices) setInto(product *xd_rsync.XdProduct) {
+	product.CostPrice = s.CostPrice
+	product.ExactRetailPrice1 = s.RetailPrice1
+	product.ExactRetailPrice2 = s.RetailPrice2
+	product.VatRate = s.VatRate
+	product.PriceListsSyncStamp = s.PriceListsSyncStamp
+	for i := range product.PriceLists {
+		if i < len(s.PriceListAmounts) {
+			product.PriceLists[i].Amount = s.PriceListAmounts[i]
+		}
+	}
+	if product.Promotion != nil && s.PromotionAmount != nil {
+		product.Promotion.Amount = *s.PromotionAmount
+	}
+}
+
+func (p *Pipeline) getQuarantine() (map[string]*xd_rsync.XdQuarantinedProduct, error) {
+	storedQuarantine := map[string]*storedQuarantinedProduct{}
+	_, err := p.app.Services.Store.Get(p.getStateKey(QUARANTINE_STATE_KEY), &storedQuarantine)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantine := map[string]*xd_rsync.XdQuarantinedProduct{}
+	for sku, storedProduct := range storedQuarantine {
+		if storedProduct.ExactPrices != nil {
+			storedProduct.ExactPrices.setInto(&storedProduct.Product)
+		}
+		quarantine[sku] = &storedProduct.XdQuarantinedProduct
+	}
+
+	return quarantine, nil
+}
+
+func (p *Pipeline) setQuarantine(quarantine map[string]*xd_rsync.XdQuarantinedProduct) error {
+	storedQuarantine := map[string]*storedQuarantinedProduct{}
+	for sku, quarantinedProduct := range quarantine {
+		storedQuarantine[sku] = &storedQuarantinedProduct{
+			XdQuarantinedProduct: *quarantinedProduct,
+			ExactPrices:          getStoredExactPrices(&quarantinedProduct.Product),
+		}
+	}
+
+	return p.app.Services.Store.Set(p.getStateKey(QUARANTINE_STATE_KEY), storedQuarantine)
+}
+
+func (p *Pipeline) getPublishedPrices() (map[string]xd_rsync.Decimal, error) {
+	publishedPrices := map[string]xd_rsync.Decimal{}
+	_, err := p.app.Services.Store.Get(p.getStateKey(PUBLISHED_PRICES_STATE_KEY), &publishedPrices)
+	if err != nil {
+		return nil, err
+	}
+
+	return publishedPrices, nil
+}
+
+// Holds back the products whose price breaks a price guard rule, raising an alert for each of them. Quarantined
+// products that changed again and no longer break any rule are released.
+func (p *Pipeline) guardProducts(ctx context.Context, entities []xd_rsync.Entity) ([]xd_rsync.Entity, []error) {
+	guardConfig := p.app.GetConfig().PriceGuard
+	if !guardConfig.IsEnabled() || len(entities) == 0 {
+		return entities, nil
+	}
+
+	publishedPrices, err := p.getPublishedPrices()
+	if err != nil {
+		p.source.Logger.Error("failed_get_published_prices", "Failed to read the last published prices", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, []error{err}
+	}
+
+	quarantine, err := p.getQuarantine()
+	if err != nil {
+		p.source.Logger.Error("failed_get_quarantine", "Failed to read the quarantined products", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, []error{err}
+	}
+
+	quarantinedAt := time.Now()
+	allowedEntities := []xd_rsync.Entity{}
+	quarantinedProducts := []*xd_rsync.XdQuarantinedProduct{}
+	releasedSkus := []string{}
+	for _, entity := range entities {
+		product := entity.(*xd_rsync.XdProduct)
+
+		var previousPrice *xd_rsync.Decimal
+		if publishedPrice, hasPublishedPrice := publishedPrices[product.SKU]; hasPublishedPrice {
+			previousPrice = &publishedPrice
+		}
+
+		violations := guardConfig.Check(product, previousPrice)
+		if len(violations) == 0 {
+			if _, isQuarantined := quarantine[product.SKU]; isQuarantined {
+				delete(quarantine, product.SKU)
+				releasedSkus = append(releasedSkus, product.SKU)
+			}
+
+			allowedEntities = append(allowedEntities, entity)
+			continue
+		}
+
+		// Only the latest version of a product is kept, replacing any previous one
+		quarantinedProduct := &xd_rsync.XdQuarantinedProduct{
+			Product:       *product,
+			PreviousPrice: previousPrice,
+			Violations:    violations,
+			QuarantinedAt: quarantinedAt,
+		}
+		quarantine[product.SKU] = quarantinedProduct
+		quarantinedProducts = append(quarantinedProducts, quarantinedProduct)
+	}
+
+	if len(quarantinedProducts) == 0 && len(releasedSkus) == 0 {
+		return allowedEntities, nil
+	}
+
+	err = p.setQuarantine(quarantine)
+	if err != nil {
+		p.source.Logger.Error("failed_set_quarantine", "Failed to store the quarantined products", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, []error{err}
+	}
+
+	if len(releasedSkus) > 0 {
+		p.source.Logger.Info("released_quarantined_products", "Quarantined products changed and no longer break the price guard", &map[string]interface{}{
+			"skus": releasedSkus,
+		})
+	}
+
+	for _, quarantinedProduct := range quarantinedProducts {
+		rules := []string{}
+		for _, violation := range quarantinedProduct.Violations {
+			rules = append(rules, violation.Rule)
+		}
+
+		p.source.Metrics.ObserveProductQuarantined()
+		p.source.Logger.Warn("quarantined_product", "Product was quarantined by the price guard", &map[string]interface{}{
+			"sku":           quarantinedProduct.Product.SKU,
+			"price":         quarantinedProduct.Product.GetEffectiveAmount().String(),
+			"previousPrice": quarantinedProduct.PreviousPrice,
+			"rules":         strings.Join(rules, ","),
+		})
+	}
+
+	return allowedEntities, p.publishPriceAlerts(ctx, quarantinedProducts)
+}
+
+func (p *Pipeline) publishPriceAlerts(ctx context.Context, quarantinedProducts []*xd_rsync.XdQuarantinedProduct) []error {
+	topicArn := p.getSourceConfig().Queues.PriceAlertsSnsQueueArn
+	if len(topicArn) == 0 || len(quarantinedProducts) == 0 {
+		return nil
+	}
+
+	events := []xd_rsync.MessagePublishInput{}
+	for _, quarantinedProduct := range quarantinedProducts {
+		product := quarantinedProduct.Product
+		var previousPrice *float64
+		if quarantinedProduct.PreviousPrice != nil {
+			previousPrice = new(float64)
+			*previousPrice = quarantinedProduct.PreviousPrice.Float64()
+		}
+
+		encodedAlert, err := p.app.Services.Encoder.Encode(&xd_rsync.XdPriceAlert{
+			SKU:            product.SKU,
+			Price:          product.GetEffectiveAmount().Float64(),
+			PreviousPrice:  previousPrice,
+			CompareAtPrice: product.GetCompareAtAmount().Float64(),
+			CostPrice:      product.CostPrice.Float64(),
+			Violations:     quarantinedProduct.Violations,
+			QuarantinedAt:  quarantinedProduct.QuarantinedAt,
+		})
+		if err != nil {
+			p.source.Logger.Error("failed_encode_price_alert", "Failed to encode price alert for SNS topic message", &map[string]interface{}{
+				"error": err.Error(),
+				"sku":   product.SKU,
+			})
+			return []error{err}
+		}
+
+		events = append(events, xd_rsync.MessagePublishInput{
+			Message:        encodedAlert.Body,
+			MessageGroupId: product.SKU,
+			Attributes:     p.buildMessageAttributes(ctx, encodedAlert),
+		})
+	}
+
+	_, errs := p.source.SNS.SendMessagesBatch(ctx, topicArn, &events)
+	if len(errs) > 0 {
+		p.source.Logger.Error("failed_publish_price_alerts", "Failed to publish price alerts", &map[string]interface{}{
+			"error": errs,
+		})
+	}
+
+	return errs
+}
+
+// Keeps the base price of the published products, which the price guard compares the next prices with
+func (p *Pipeline) recordPublishedPrices(entities []xd_rsync.Entity) []error {
+	publishedPrices, err := p.getPublishedPrices()
+	if err != nil {
+		p.source.Logger.Error("failed_get_published_prices", "Failed to read the last published prices", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return []error{err}
+	}
+
+	for _, entity := range entities {
+		product := entity.(*xd_rsync.XdProduct)
+		publishedPrices[product.SKU] = product.GetBaseAmount()
+	}
+
+	err = p.app.Services.Store.Set(p.getStateKey(PUBLISHED_PRICES_STATE_KEY), publishedPrices)
+	if err != nil {
+		p.source.Logger.Error("failed_set_published_prices", "Failed to store the published prices", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return []error{err}
+	}
+
+	return nil
+}
+
+// Returns the products held back by the price guard, sorted by SKU
+func (p *Pipeline) GetQuarantinedProducts() ([]*xd_rsync.XdQuarantinedProduct, error) {
+	quarantine, err := p.getQuarantine()
+	if err != nil {
+		return nil, err
+	}
+
+	skus := []string{}
+	for sku := range quarantine {
+		skus = append(skus, sku)
+	}
+	slices.Sort(skus)
+
+	quarantinedProducts := []*xd_rsync.XdQuarantinedProduct{}
+	for _, sku := range skus {
+		quarantinedProducts = append(quarantinedProducts, quarantine[sku])
+	}
+
+	return quarantinedProducts, nil
+}
+
+// Publishes the quarantined version of a product, releasing it from quarantine
+func (p *Pipeline) ApproveQuarantinedProduct(ctx context.Context, sku string) []error {
+	p.runMutex.Lock()
+	defer p.runMutex.Unlock()
+
+	quarantine, err := p.getQuarantine()
+	if err != nil {
+		return []error{err}
+	}
+
+	quarantinedProduct, isQuarantined := quarantine[sku]
+	if !isQuarantined {
+		return []error{ErrProductNotQuarantined}
+	}
+
+	_, errs := p.publishEntities(ctx, PRODUCTS_STREAM, []xd_rsync.Entity{&quarantinedProduct.Product}, nil)
+	if len(errs) > 0 {
+		p.source.Logger.Error("failed_approve_quarantined_product", "Failed to publish approved product", &map[string]interface{}{
+			"sku":   sku,
+			"error": errs,
+		})
+		return errs
+	}
+
+	delete(quarantine, sku)
+	err = p.setQuarantine(quarantine)
+	if err != nil {
+		return []error{err}
+	}
+
+	p.source.Logger.Warn("approved_quarantined_product", "Quarantined product was approved and published", &map[string]interface{}{
+		"sku":   sku,
+		"price": quarantinedProduct.Product.GetEffectiveAmount().String(),
+	})
+	return nil
+}
+
+// Releases a product from quarantine without publishing it. It is checked again when it changes in XD.
+func (p *Pipeline) RejectQuarantinedProduct(sku string) error {
+	p.runMutex.Lock()
+	defer p.runMutex.Unlock()
+
+	quarantine, err := p.getQuarantine()
+	if err != nil {
+		return err
+	}
+
+	quarantinedProduct, isQuarantined := quarantine[sku]
+	if !isQuarantined {
+		return ErrProductNotQuarantined
+	}
+
+	delete(quarantine, sku)
+	err = p.setQuarantine(quarantine)
+	if err != nil {
+		return err
+	}
+
+	p.source.Logger.Warn("rejected_quarantined_product", "Quarantined product was rejected", &map[string]interface{}{
+		"sku":   sku,
+		"price": quarantinedProduct.Product.GetEffectiveAmount().String(),
+	})
+	return nil
+}
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+func parseTestDecimal(t *testing.T, text string) xd_rsync.Decimal {
+	decimal, err := xd_rsync.ParseDecimal(text)
+	if err != nil {
+		t.Fatalf("could not parse decimal: %v", err)
+	}
+
+	return decimal
+}
+
+func createTestGuardConfig() *xd_rsync.Config {
+	cfg := createTestConfig(&xd_rsync.QueuesConfig{ProductUpdatesSnsQueueArn: "products"})
+	cfg.Sources[0].Money = &xd_rsync.MoneyConfig{Currency: "EUR", Scale: 2}
+	cfg.PriceGuard = &xd_rsync.PriceGuardConfig{MaxDropPercentage: 20, MaxRisePercentage: 50}
+
+	return cfg
+}
+
+// Changes the product in the test database, so it is captured by the next run
+func changeTestProduct(product *xd_rsync.XdProduct, change func(product *xd_rsync.XdProduct)) {
+	change(product)
+	changedAt := time.Now()
+	product.SyncStamp = &changedAt
+}
+
+func TestGuardComparesBasePricesAcrossPromotions(t *testing.T) {
+	product := createTestProduct("A1", time.Now().Add(-time.Hour))
+	product.ExactRetailPrice2 = parseTestDecimal(t, "10.00")
+	database := &testDatabase{entities: map[string][]xd_rsync.Entity{"products": {product}}}
+	sns := &testSNS{messages: map[string][]xd_rsync.MessagePublishInput{}}
+	pipeline := createTestPipeline(t, createTestGuardConfig(), createTestStore(t, ""), database, sns)
+
+	steps := []struct {
+		name   string
+		change func(product *xd_rsync.XdProduct)
+	}{
+		{name: "first publish", change: func(product *xd_rsync.XdProduct) {}},
+		{name: "deep promotion starting", change: func(product *xd_rsync.XdProduct) {
+			product.SetPromotion(&xd_rsync.XdPromotion{Id: "P1", Price: parseTestDecimal(t, "5.00")})
+		}},
+		{name: "deep promotion ending", change: func(product *xd_rsync.XdProduct) {
+			product.Promotion = nil
+		}},
+	}
+
+	for index, step := range steps {
+		changeTestProduct(product, step.change)
+		errs := pipeline.Run(context.Background())
+		if len(errs) > 0 {
+			t.Fatalf("%s: expected the run to succeed, got %v", step.name, errs)
+		}
+
+		quarantinedProducts, err := pipeline.GetQuarantinedProducts()
+		if err != nil {
+			t.Fatalf("could not get quarantined products: %v", err)
+		}
+		if len(quarantinedProducts) > 0 {
+			t.Fatalf("%s: expected the product not to be quarantined, got %+v", step.name, quarantinedProducts[0].Violations)
+		}
+
+		if len(sns.messages["products"]) != index+1 {
+			t.Fatalf("%s: expected %d published messages, got %d", step.name, index+1, len(sns.messages["products"]))
+		}
+	}
+}
+
+func TestQuarantineKeepsExactPricesAcrossRestarts(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	cfg := createTestGuardConfig()
+	product := createTestProduct("A1", time.Now().Add(-time.Hour))
+	product.ExactRetailPrice2 = parseTestDecimal(t, "10.00")
+	database := &testDatabase{entities: map[string][]xd_rsync.Entity{"products": {product}}}
+	sns := &testSNS{messages: map[string][]xd_rsync.MessagePublishInput{}}
+
+	pipeline := createTestPipeline(t, cfg, createTestStore(t, statePath), database, sns)
+	if errs := pipeline.Run(context.Background()); len(errs) > 0 {
+		t.Fatalf("expected the first run to succeed, got %v", errs)
+	}
+
+	priceListsSyncStamp := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	changeTestProduct(product, func(product *xd_rsync.XdProduct) {
+		product.ExactRetailPrice1 = parseTestDecimal(t, "12.00")
+		product.ExactRetailPrice2 = parseTestDecimal(t, "2.00")
+		product.CostPrice = parseTestDecimal(t, "1.50")
+		product.VatRate = parseTestDecimal(t, "23")
+		product.AddPriceListPrice("wholesale", &xd_rsync.XdPriceListPrice{
+			ItemKeyId: "A1",
+			Price:     parseTestDecimal(t, "1.80"),
+			SyncStamp: &priceListsSyncStamp,
+		})
+		product.SetPromotion(&xd_rsync.XdPromotion{Id: "P1", Price: parseTestDecimal(t, "1.90")})
+	})
+	if errs := pipeline.Run(context.Background()); len(errs) > 0 {
+		t.Fatalf("expected the second run to succeed, got %v", errs)
+	}
+
+	expectedBody, err := json.Marshal(product)
+	if err != nil {
+		t.Fatalf("could not encode product: %v", err)
+	}
+
+	restartedPipeline := createTestPipeline(t, cfg, createTestStore(t, statePath), database, sns)
+	quarantinedProducts, err := restartedPipeline.GetQuarantinedProducts()
+	if err != nil {
+		t.Fatalf("could not get quarantined products: %v", err)
+	}
+	if len(quarantinedProducts) != 1 {
+		t.Fatalf("expected the product to be quarantined, got %d quarantined products", len(quarantinedProducts))
+	}
+
+	quarantinedProduct := quarantinedProducts[0].Product
+	exactPrices := map[string][2]string{
+		"cost price":        {"1.50", quarantinedProduct.CostPrice.String()},
+		"retail price 1":    {"12.00", quarantinedProduct.ExactRetailPrice1.String()},
+		"retail price 2":    {"2.00", quarantinedProduct.ExactRetailPrice2.String()},
+		"VAT rate":          {"23", quarantinedProduct.VatRate.String()},
+		"price list amount": {"1.80", quarantinedProduct.PriceLists[0].Amount.String()},
+		"promotion amount":  {"1.90", quarantinedProduct.Promotion.Amount.String()},
+	}
+	for name, prices := range exactPrices {
+		if prices[0] != prices[1] {
+			t.Errorf("expected %s %s, got %s", name, prices[0], prices[1])
+		}
+	}
+
+	if stamp := quarantinedProduct.PriceListsSyncStamp; stamp == nil || !stamp.Equal(priceListsSyncStamp) {
+		t.Errorf("expected price lists sync stamp %s, got %v", priceListsSyncStamp, stamp)
+	}
+
+	if errs := restartedPipeline.ApproveQuarantinedProduct(context.Background(), "A1"); len(errs) > 0 {
+		t.Fatalf("expected the product to be approved, got %v", errs)
+	}
+
+	messages := sns.messages["products"]
+	if len(messages) != 2 || messages[1].Message != string(expectedBody) {
+		t.Errorf("expected the approved product to be published as quarantined, got %d messages", len(messages))
+	}
+
+	publishedPrices, err := restartedPipeline.getPublishedPrices()
+	if err != nil {
+		t.Fatalf("could not get published prices: %v", err)
+	}
+	if publishedPrice := publishedPrices["A1"]; publishedPrice.String() != "2.00" {
+		t.Errorf("expected the published base price 2.00, got %s", publishedPrice)
+	}
+}
@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Message attribute identifying the source of each event
+const TENANT_ID_ATTRIBUTE = "tenantId"
+
+// Key of the source checkpoint in the state store
+const CHECKPOINT_STATE_KEY = "checkpoint"
+
+// Key of the checkpoints of the streams that do not share the source checkpoint, by stream name, in the state store
+const STREAM_CHECKPOINTS_STATE_KEY = "stream_checkpoints"
+
+// Synchronises the changes of a single source
+type Pipeline struct {
+	app    *xd_rsync.XdRsyncInstance
+	source *xd_rsync.XdRsyncSource
+	// Prevents scheduled runs and on-demand republishing from overlapping
+	runMutex sync.Mutex
+}
+
+type PipelineCreationInput struct {
+	App    *xd_rsync.XdRsyncInstance
+	Source *xd_rsync.XdRsyncSource
+}
+
+func CreatePipeline(input *PipelineCreationInput) *Pipeline {
+	pipeline := &Pipeline{
+		app:    input.App,
+		source: input.Source,
+	}
+	pipeline.loadCheckpoints()
+
+	return pipeline
+}
+
+// Restores the checkpoints kept in the state store, unless they were given at startup
+func (p *Pipeline) loadCheckpoints() {
+	if p.source.State.GetCheckpoint().Equal(xd_rsync.INITIAL_CHECKPOINT) {
+		var checkpoint time.Time
+		hasCheckpoint, err := p.app.Services.Store.Get(p.getStateKey(CHECKPOINT_STATE_KEY), &checkpoint)
+		if err != nil {
+			p.source.Logger.Error("failed_get_checkpoint", "Failed to read the checkpoint", &map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if hasCheckpoint {
+			p.source.State.SetCheckpoint(checkpoint)
+		}
+	}
+
+	streamCheckpoints := map[string]time.Time{}
+	_, err := p.app.Services.Store.Get(p.getStateKey(STREAM_CHECKPOINTS_STATE_KEY), &streamCheckpoints)
+	if err != nil {
+		p.source.Logger.Error("failed_get_stream_checkpoints", "Failed to read the stream checkpoints", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for stream, checkpoint := range streamCheckpoints {
+		if _, hasCheckpoint := p.source.State.LookupStreamCheckpoint(stream); !hasCheckpoint {
+			p.source.State.SetStreamCheckpoint(stream, checkpoint)
+		}
+	}
+}
+
+// Keeps the checkpoints in the state store, so they survive restarts
+func (p *Pipeline) storeCheckpoints() error {
+	err := p.app.Services.Store.Set(p.getStateKey(CHECKPOINT_STATE_KEY), p.source.State.GetCheckpoint())
+	if err != nil {
+		p.source.Logger.Error("failed_set_checkpoint", "Failed to store the checkpoint", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	streamCheckpoints := p.source.State.GetStatus().StreamCheckpoints
+	if streamCheckpoints == nil {
+		streamCheckpoints = map[string]time.Time{}
+	}
+
+	err = p.app.Services.Store.Set(p.getStateKey(STREAM_CHECKPOINTS_STATE_KEY), streamCheckpoints)
+	if err != nil {
+		p.source.Logger.Error("failed_set_stream_checkpoints", "Failed to store the stream checkpoints", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	return nil
+}
+
+// Returns the message attributes of an encoded record: its encoding attributes, the source ID and the trace context
+// of ctx, which the SNS client replaces with the one of the batch publishing the message
+func (p *Pipeline) buildMessageAttributes(ctx context.Context, encoded *xd_rsync.EncodedMessage) map[string]string {
+	attributes := tracing.InjectIntoAttributes(ctx, encoded.Attributes)
+	attributes[TENANT_ID_ATTRIBUTE] = p.source.Id
+
+	return attributes
+}
+
+func (p *Pipeline) GetSource() *xd_rsync.XdRsyncSource {
+	return p.source
+}
+
+// Returns the current configuration of the source, as it can be reloaded
+func (p *Pipeline) getSourceConfig() *xd_rsync.SourceConfig {
+	return p.app.GetConfig().GetSource(p.source.Id)
+}
+
+// Captures and publishes the changes since the current checkpoint, moving it forward
+func (p *Pipeline) Run(ctx context.Context) []error {
+	p.runMutex.Lock()
+	defer p.runMutex.Unlock()
+
+	checkpoint := p.source.State.GetCheckpoint()
+	ctx, span := tracing.StartSpan(ctx, "sync_run",
+		attribute.String("sync.tenant_id", p.source.Id),
+		attribute.String("sync.last_sync_timestamp", checkpoint.Format(time.RFC3339)),
+	)
+
+	p.source.State.StartRun()
+	runStartedAt := time.Now()
+	changedEntities := map[string]int{}
+	errs := []error{}
+	sharedCheckpointErrs := []error{}
+	// Streams sharing the checkpoint that keep their own while they are held back by a mass change
+	detachedStreams := []string{}
+	sourceConfig := p.getSourceConfig()
+	for _, stream := range ENTITY_STREAMS {
+		if !stream.isEnabled(sourceConfig) {
+			continue
+		}
+
+		if stream.HasOwnCheckpoint {
+			changedCount, streamErrs := p.captureChanges(ctx, stream, p.source.State.GetStreamCheckpoint(stream.GetName()))
+			changedEntities[stream.GetName()] = changedCount
+			errs = append(errs, streamErrs...)
+			if len(streamErrs) == 0 {
+				p.source.State.SetStreamCheckpoint(stream.GetName(), runStartedAt)
+			}
+			continue
+		}
+
+		streamCheckpoint, isDetached := p.source.State.LookupStreamCheckpoint(stream.GetName())
+		if !isDetached {
+			streamCheckpoint = checkpoint
+		}
+
+		changedCount, streamErrs := p.captureChanges(ctx, stream, streamCheckpoint)
+		changedEntities[stream.GetName()] = changedCount
+		errs = append(errs, streamErrs...)
+
+		// A held mass change only holds back its own stream, which stays at its checkpoint until it is confirmed
+		if slices.Contains(streamErrs, ErrMassChangeOnHold) {
+			p.source.State.SetStreamCheckpoint(stream.GetName(), streamCheckpoint)
+			continue
+		}
+
+		if isDetached {
+			if len(streamErrs) == 0 {
+				detachedStreams = append(detachedStreams, stream.GetName())
+			}
+			continue
+		}
+
+		sharedCheckpointErrs = append(sharedCheckpointErrs, streamErrs...)
+	}
+
+	// The checkpoint is shared by the other streams, so it only moves forward when all of them were published. It
+	// moves to the start of the run, so changes made while reading are captured again rather than missed.
+	if len(sharedCheckpointErrs) == 0 {
+		checkpoint = runStartedAt
+	}
+
+	// Released streams share the checkpoint again once it caught up with them
+	for _, stream := range detachedStreams {
+		if checkpoint.Equal(runStartedAt) {
+			p.source.State.DeleteStreamCheckpoint(stream)
+		} else {
+			p.source.State.SetStreamCheckpoint(stream, runStartedAt)
+		}
+	}
+
+	p.source.State.SetCheckpoint(checkpoint)
+	if err := p.storeCheckpoints(); err != nil {
+		errs = append(errs, err)
+	}
+
+	changedProducts := changedEntities[PRODUCTS_STREAM.GetName()]
+	p.source.Metrics.ObserveSyncRun(runStartedAt, changedProducts, errs)
+
+	summary := &xd_rsync.SyncRunSummary{
+		StartedAt:       runStartedAt,
+		FinishedAt:      time.Now(),
+		Checkpoint:      checkpoint,
+		ChangedProducts: changedProducts,
+		ChangedEntities: changedEntities,
+	}
+	for _, err := range errs {
+		summary.Errors = append(summary.Errors, err.Error())
+	}
+
+	p.source.State.FinishRun(summary)
+
+	span.SetAttributes(attribute.Int("sync.changed_products_count", changedProducts))
+	tracing.EndSpan(span, errors.Join(errs...))
+
+	return errs
+}
@@ -0,0 +1,226 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+	"github.com/fabiofcferreira/xd-rsync/store"
+)
+
+// Stand-in for the XD database, returning the records of each entity changed after the checkpoint of the query
+type testDatabase struct {
+	xd_rsync.DatabaseService
+	entities map[string][]xd_rsync.Entity
+	// Prices of each price list, by its name
+	priceListPrices map[string][]*xd_rsync.XdPriceListPrice
+}
+
+func (d *testDatabase) GetEntities(ctx context.Context, query *xd_rsync.EntityQuery) ([]xd_rsync.Entity, error) {
+	entities := []xd_rsync.Entity{}
+	for _, entity := range d.entities[query.Entity.GetEntityName()] {
+		lastChangedAt := entity.GetLastChangedAt()
+		if query.ChangedAfter == nil || (lastChangedAt != nil && lastChangedAt.After(*query.ChangedAfter)) {
+			entities = append(entities, entity)
+		}
+	}
+
+	return entities, nil
+}
+
+func (d *testDatabase) GetEntitiesCount(ctx context.Context, query *xd_rsync.EntityQuery) (int, error) {
+	return len(d.entities[query.Entity.GetEntityName()]), nil
+}
+
+func (d *testDatabase) GetPriceListPrices(ctx context.Context, priceList *xd_rsync.PriceListConfig, skus []string) ([]*xd_rsync.XdPriceListPrice, error) {
+	prices := []*xd_rsync.XdPriceListPrice{}
+	for _, price := range d.priceListPrices[priceList.Name] {
+		if slices.Contains(skus, price.ItemKeyId) {
+			prices = append(prices, price)
+		}
+	}
+
+	return prices, nil
+}
+
+// Stand-in for SNS, keeping the published messages by topic
+type testSNS struct {
+	xd_rsync.SNSService
+	messages map[string][]xd_rsync.MessagePublishInput
+	// Topics whose messages fail to be published
+	failingTopicArns []string
+}
+
+func (s *testSNS) SendMessage(ctx context.Context, topicArn string, input *xd_rsync.MessagePublishInput) error {
+	if slices.Contains(s.failingTopicArns, topicArn) {
+		return fmt.Errorf("could not publish to %s", topicArn)
+	}
+
+	s.messages[topicArn] = append(s.messages[topicArn], *input)
+	input.Acknowledge(time.Now())
+	return nil
+}
+
+func (s *testSNS) SendMessagesBatch(ctx context.Context, topicArn string, input *[]xd_rsync.MessagePublishInput) (int, []error) {
+	errs := []error{}
+	for index := range *input {
+		if err := s.SendMessage(ctx, topicArn, &(*input)[index]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return len(*input) - len(errs), errs
+}
+
+type testEncoder struct{}
+
+func (e *testEncoder) Encode(record interface{}) (*xd_rsync.EncodedMessage, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xd_rsync.EncodedMessage{Body: string(body), Attributes: map[string]string{}}, nil
+}
+
+func createTestConfig(queues *xd_rsync.QueuesConfig) *xd_rsync.Config {
+	return &xd_rsync.Config{
+		Sources: []*xd_rsync.SourceConfig{{Id: "store-a", Queues: queues}},
+	}
+}
+
+// Opens the state file as on startup
+func createTestStore(t *testing.T, path string) *store.FileStore {
+	stateStore, err := store.CreateFileStore(&store.FileStoreCreationInput{Path: path})
+	if err != nil {
+		t.Fatalf("could not create state store: %v", err)
+	}
+
+	return stateStore
+}
+
+// Creates the pipeline of the only source of the configuration as on startup, without an initial checkpoint
+func createTestPipeline(t *testing.T, cfg *xd_rsync.Config, stateStore xd_rsync.StateStore, database *testDatabase, sns *testSNS) *Pipeline {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction: true,
+		Level:        "error",
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	testMetrics, err := metrics.CreateMetrics(&metrics.MetricsCreationInput{Logger: testLogger})
+	if err != nil {
+		t.Fatalf("could not create metrics: %v", err)
+	}
+
+	sourceId := cfg.Sources[0].Id
+	app := &xd_rsync.XdRsyncInstance{
+		Config:   cfg,
+		Logger:   testLogger,
+		Metrics:  testMetrics,
+		Services: &xd_rsync.XdRsyncServices{Encoder: &testEncoder{}, Store: stateStore},
+	}
+	source := &xd_rsync.XdRsyncSource{
+		Id:       sourceId,
+		Logger:   testLogger,
+		Metrics:  testMetrics.WithTenant(sourceId),
+		State:    xd_rsync.CreateSyncState(xd_rsync.INITIAL_CHECKPOINT),
+		Database: database,
+		SNS:      sns,
+	}
+	app.Sources = []*xd_rsync.XdRsyncSource{source}
+
+	return CreatePipeline(&PipelineCreationInput{App: app, Source: source})
+}
+
+func createTestProduct(sku string, changedAt time.Time) *xd_rsync.XdProduct {
+	return &xd_rsync.XdProduct{SKU: sku, SyncStamp: &changedAt}
+}
+
+func createTestStockMovement(id string, changedAt time.Time) *xd_rsync.XdStockMovement {
+	return &xd_rsync.XdStockMovement{Id: id, SKU: "A1", SyncStamp: &changedAt}
+}
+
+func TestRunResumesFromStoredCheckpointAfterRestart(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	cfg := createTestConfig(&xd_rsync.QueuesConfig{ProductUpdatesSnsQueueArn: "products"})
+	cfg.MassChange = &xd_rsync.MassChangeConfig{MaxChangedCount: 2, Action: xd_rsync.MASS_CHANGE_ACTION_HOLD}
+
+	changedAt := time.Now().Add(-time.Hour)
+	database := &testDatabase{entities: map[string][]xd_rsync.Entity{
+		"products": {createTestProduct("A1", changedAt), createTestProduct("A2", changedAt)},
+	}}
+	sns := &testSNS{messages: map[string][]xd_rsync.MessagePublishInput{}}
+
+	errs := createTestPipeline(t, cfg, createTestStore(t, statePath), database, sns).Run(context.Background())
+	if len(errs) > 0 {
+		t.Fatalf("expected the first run to succeed, got %v", errs)
+	}
+
+	// A single product changed while the process was stopped
+	database.entities["products"] = append(database.entities["products"], createTestProduct("A3", time.Now()))
+
+	restartedPipeline := createTestPipeline(t, cfg, createTestStore(t, statePath), database, sns)
+	errs = restartedPipeline.Run(context.Background())
+	if len(errs) > 0 {
+		t.Fatalf("expected the run after the restart not to trip the breaker, got %v", errs)
+	}
+
+	if hold := restartedPipeline.GetSource().State.GetMassChangeHold(); hold != nil {
+		t.Errorf("expected no mass change on hold, got %+v", hold)
+	}
+
+	if len(sns.messages["products"]) != 3 {
+		t.Errorf("expected only the product changed after the restart to be published again, got %d messages", len(sns.messages["products"]))
+	}
+}
+
+func TestRunResumesStockMovementsFromStoredCheckpointAfterRestart(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	cfg := createTestConfig(&xd_rsync.QueuesConfig{ProductUpdatesSnsQueueArn: "products", StockMovementsSnsQueueArn: "movements"})
+
+	changedAt := time.Now().Add(-time.Hour)
+	database := &testDatabase{entities: map[string][]xd_rsync.Entity{
+		"products":        {createTestProduct("A1", changedAt)},
+		"stock_movements": {createTestStockMovement("M1", changedAt), createTestStockMovement("M2", changedAt)},
+	}}
+	// The products are not published, so only the stock movements move past the initial checkpoint
+	sns := &testSNS{messages: map[string][]xd_rsync.MessagePublishInput{}, failingTopicArns: []string{"products"}}
+
+	errs := createTestPipeline(t, cfg, createTestStore(t, statePath), database, sns).Run(context.Background())
+	if len(errs) == 0 {
+		t.Fatal("expected the products of the first run to fail")
+	}
+
+	if len(sns.messages["movements"]) != 2 {
+		t.Fatalf("expected the stock movements to be published, got %d messages", len(sns.messages["movements"]))
+	}
+
+	// A single stock movement was recorded while the process was stopped
+	database.entities["stock_movements"] = append(database.entities["stock_movements"], createTestStockMovement("M3", time.Now()))
+	sns.failingTopicArns = nil
+
+	restartedPipeline := createTestPipeline(t, cfg, createTestStore(t, statePath), database, sns)
+	errs = restartedPipeline.Run(context.Background())
+	if len(errs) > 0 {
+		t.Fatalf("expected the run after the restart to succeed, got %v", errs)
+	}
+
+	if len(sns.messages["products"]) != 1 {
+		t.Errorf("expected the product to be published after the restart, got %d messages", len(sns.messages["products"]))
+	}
+
+	movements := sns.messages["movements"]
+	if len(movements) != 3 || !strings.Contains(movements[2].Message, `"id":"M3"`) {
+		t.Errorf("expected only the stock movement recorded after the restart to be published, got %d messages", len(movements))
+	}
+}
@@ -0,0 +1,18 @@
+package pipeline
+
+import (
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Stock movements are only captured when the source has a topic for them. They are a ledger rather than a snapshot,
+// so they keep their own checkpoint.
+var STOCK_MOVEMENTS_STREAM = &EntityStream{
+	Entity: &xd_rsync.XdStockMovement{},
+	GetTopicArn: func(source *xd_rsync.SourceConfig) string {
+		return source.Queues.StockMovementsSnsQueueArn
+	},
+	IsEnabled: func(source *xd_rsync.SourceConfig) bool {
+		return len(source.Queues.StockMovementsSnsQueueArn) > 0
+	},
+	HasOwnCheckpoint: true,
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+var PRODUCTS_STREAM = &EntityStream{
+	Entity: &xd_rsync.XdProduct{},
+	GetTopicArn: func(source *xd_rsync.SourceConfig) string {
+		return source.Queues.ProductUpdatesSnsQueueArn
+	},
+	GetBulkTopicArn: func(source *xd_rsync.SourceConfig) string {
+		return source.Queues.ProductBulkUpdatesSnsQueueArn
+	},
+	Prepare:       prepareProduct,
+	Guard:         (*Pipeline).guardProducts,
+	AfterPublish:  (*Pipeline).afterProductsPublished,
+	GetConditions: getProductConditions,
+	ExtendQuery:   extendProductsQuery,
+	Load:          (*Pipeline).loadProductDetails,
+}
+
+// Only selects the products meeting the product filter rules of the source
+func getProductConditions(source *xd_rsync.SourceConfig) []xd_rsync.Condition {
+	if source.Filters == nil {
+		return xd_rsync.PRICED_PRODUCT_CONDITION
+	}
+
+	return source.Filters.ProductConditions
+}
+
+// Captures the products whose price table rows changed, when price lists are read from price tables
+func extendProductsQuery(cfg *xd_rsync.Config, query *xd_rsync.EntityQuery) {
+	if !xd_rsync.HasPriceTables(cfg.PriceLists) {
+		return
+	}
+
+	query.Joins = append(query.Joins, xd_rsync.ITEM_TO_ITEM_PRICES_JOIN_EXPRESSION)
+	query.ChangeColumns = append(query.ChangeColumns, "iprice.SyncStamp")
+}
+
+// Reads the prices of the configured price lists and the active promotions into the products
+func (p *Pipeline) loadProductDetails(ctx context.Context, entities []xd_rsync.Entity) error {
+	productsBySku := map[string]*xd_rsync.XdProduct{}
+	skus := []string{}
+	for _, entity := range entities {
+		product := entity.(*xd_rsync.XdProduct)
+		productsBySku[product.SKU] = product
+		skus = append(skus, product.SKU)
+	}
+
+	err := p.loadPriceLists(ctx, productsBySku, skus)
+	if err != nil {
+		return err
+	}
+
+	return p.loadPromotions(ctx, productsBySku, skus)
+}
+
+func (p *Pipeline) loadPriceLists(ctx context.Context, productsBySku map[string]*xd_rsync.XdProduct, skus []string) error {
+	for _, priceList := range p.app.GetConfig().PriceLists {
+		prices, err := p.source.Database.GetPriceListPrices(ctx, priceList, skus)
+		if err != nil {
+			return err
+		}
+
+		for _, price := range prices {
+			if product, isFetched := productsBySku[price.ItemKeyId]; isFetched {
+				product.AddPriceListPrice(priceList.Name, price)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Pipeline) afterProductsPublished(ctx context.Context, entities []xd_rsync.Entity) []error {
+	errs := p.recordPublishedPrices(entities)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return p.publishStockTransitions(ctx, entities)
+}
+
+// Sums the stock of the sellable warehouses of the source and sets the prices in its currency
+func prepareProduct(source *xd_rsync.SourceConfig, entity xd_rsync.Entity) {
+	product := entity.(*xd_rsync.XdProduct)
+	if source.Stock != nil {
+		product.SetSellableWarehouses(source.Stock.SellableWarehouseIds)
+	}
+
+	if source.Money != nil {
+		product.SetPrices(source.Money)
+	}
+}
+
+// Returns the product as it would be published, read and prepared as in the sync runs. Products left out by the
+// product filters are not found.
+func (p *Pipeline) PreviewProduct(ctx context.Context, sku string) (*xd_rsync.XdProduct, error) {
+	entities, err := p.source.Database.GetEntitiesByKey(ctx, p.getEntityQuery(PRODUCTS_STREAM, nil), []string{sku})
+	if err == nil {
+		err = p.loadEntities(ctx, PRODUCTS_STREAM, entities)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entities = p.filterEntities(PRODUCTS_STREAM, entities)
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("could not get product: %w", sql.ErrNoRows)
+	}
+
+	return entities[0].(*xd_rsync.XdProduct), nil
+}
+
+// Publishes the current state of the given products, regardless of the checkpoint
+func (p *Pipeline) RepublishProducts(ctx context.Context, skus []string) (int, []error) {
+	return p.republishEntities(ctx, PRODUCTS_STREAM, skus)
+}
+
+// Publishes every priced product changed within the given range. A nil bound leaves that side of the range open.
+func (p *Pipeline) Resync(ctx context.Context, from *time.Time, to *time.Time) (int, []error) {
+	return p.resyncEntities(ctx, PRODUCTS_STREAM, from, to)
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+func TestRunPublishesPriceListPrices(t *testing.T) {
+	cfg := createTestGuardConfig()
+	cfg.PriceGuard = nil
+	cfg.PriceLists = []*xd_rsync.PriceListConfig{
+		{Name: "wholesale", Column: "RetailPrice3"},
+		{Name: "resellers", PriceTableId: "2"},
+	}
+
+	changedAt := time.Now().Add(-time.Hour)
+	priceChangedAt := changedAt.Add(time.Minute)
+	database := &testDatabase{
+		entities: map[string][]xd_rsync.Entity{
+			"products": {createTestProduct("A1", changedAt), createTestProduct("A2", changedAt)},
+		},
+		priceListPrices: map[string][]*xd_rsync.XdPriceListPrice{
+			"wholesale": {
+				{ItemKeyId: "A1", Price: parseTestDecimal(t, "8.504")},
+			},
+			"resellers": {
+				{ItemKeyId: "A2", Price: parseTestDecimal(t, "7.5"), SyncStamp: &priceChangedAt},
+				{ItemKeyId: "A1", Price: parseTestDecimal(t, "9.00"), SyncStamp: &changedAt},
+			},
+		},
+	}
+	sns := &testSNS{messages: map[string][]xd_rsync.MessagePublishInput{}}
+
+	errs := createTestPipeline(t, cfg, createTestStore(t, ""), database, sns).Run(context.Background())
+	if len(errs) > 0 {
+		t.Fatalf("expected the run to succeed, got %v", errs)
+	}
+
+	// Price lists are in the order they are configured, leaving out the ones without a price
+	expectedPriceLists := map[string][]string{
+		"A1": {"wholesale 8.50", "resellers 9.00"},
+		"A2": {"resellers 7.50"},
+	}
+	messages := sns.messages["products"]
+	if len(messages) != len(expectedPriceLists) {
+		t.Fatalf("expected %d published products, got %d", len(expectedPriceLists), len(messages))
+	}
+
+	for _, message := range messages {
+		product := struct {
+			SKU        string `json:"sku"`
+			PriceLists []struct {
+				Name  string `json:"name"`
+				Price struct {
+					Amount   string `json:"amount"`
+					Currency string `json:"currency"`
+				} `json:"price"`
+			} `json:"priceLists"`
+		}{}
+		err := json.Unmarshal([]byte(message.Message), &product)
+		if err != nil {
+			t.Fatalf("could not decode product: %v", err)
+		}
+
+		priceLists := []string{}
+		for _, priceList := range product.PriceLists {
+			priceLists = append(priceLists, priceList.Name+" "+priceList.Price.Amount)
+			if priceList.Price.Currency != "EUR" {
+				t.Errorf("expected %s price list %s in EUR, got %s", product.SKU, priceList.Name, priceList.Price.Currency)
+			}
+		}
+
+		if !slices.Equal(priceLists, expectedPriceLists[product.SKU]) {
+			t.Errorf("expected %s price lists %v, got %v", product.SKU, expectedPriceLists[product.SKU], priceLists)
+		}
+	}
+
+	for _, entity := range database.entities["products"] {
+		product := entity.(*xd_rsync.XdProduct)
+		if product.SKU == "A2" && (product.PriceListsSyncStamp == nil || !product.PriceListsSyncStamp.Equal(priceChangedAt)) {
+			t.Errorf("expected A2 price lists sync stamp %s, got %v", priceChangedAt, product.PriceListsSyncStamp)
+		}
+	}
+}
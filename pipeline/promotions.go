@@ -0,0 +1,153 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Key of the time until which the promotion boundaries were published, in the state store
+const PROMOTIONS_CHECKPOINT_STATE_KEY = "promotions_checkpoint"
+
+// Products published when their promotion starts or ends. They are checked for mass changes apart from the changed
+// products, so a mass change of either is held and confirmed on its own.
+var PROMOTION_BOUNDARIES_STREAM = func() *EntityStream {
+	stream := *PRODUCTS_STREAM
+	stream.Name = "product_promotions"
+	return &stream
+}()
+
+// Reads the active promotion of each product into it, when promotions are enabled
+func (p *Pipeline) loadPromotions(ctx context.Context, productsBySku map[string]*xd_rsync.XdProduct, skus []string) error {
+	promotionsConfig := p.app.GetConfig().Promotions
+	if promotionsConfig == nil || !promotionsConfig.Enabled {
+		return nil
+	}
+
+	promotions, err := p.source.Database.GetActivePromotions(ctx, skus, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, promotion := range promotions {
+		if product, isFetched := productsBySku[promotion.ItemKeyId]; isFetched {
+			product.SetPromotion(promotion)
+		}
+	}
+
+	return nil
+}
+
+// Publishes the products whose promotion starts or ends at the time it does, until the context is done. The next
+// boundary is read again every sync frequency, so promotions created in XD meanwhile are scheduled as well. Nothing is
+// published while the sync runs are paused, and the promotions that started or ended meanwhile are published once
+// they are resumed.
+func (p *Pipeline) WatchPromotions(ctx context.Context, isPaused func() bool) {
+	var scheduledBoundary *time.Time
+	for {
+		cfg := p.app.GetConfig()
+		wait := cfg.SyncFrequency
+		if cfg.Promotions != nil && cfg.Promotions.Enabled && !isPaused() {
+			nextBoundary, err := p.publishPromotionBoundaries(ctx)
+			if err == nil && nextBoundary != nil {
+				wait = max(min(wait, time.Until(*nextBoundary)), 0)
+				if scheduledBoundary == nil || !scheduledBoundary.Equal(*nextBoundary) {
+					p.source.Logger.Info("scheduled_promotion_boundary", "Scheduled the publication of the next promotion start or end", &map[string]interface{}{
+						"at": *nextBoundary,
+					})
+				}
+			}
+			scheduledBoundary = nextBoundary
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Publishes the products whose promotion started or ended since the promotions checkpoint, moving it forward, and
+// returns the next time a promotion starts or ends
+func (p *Pipeline) publishPromotionBoundaries(ctx context.Context) (*time.Time, error) {
+	p.runMutex.Lock()
+	defer p.runMutex.Unlock()
+
+	stateKey := p.getStateKey(PROMOTIONS_CHECKPOINT_STATE_KEY)
+	now := time.Now()
+	checkpoint := now
+	hasCheckpoint, err := p.app.Services.Store.Get(stateKey, &checkpoint)
+	if err != nil {
+		p.source.Logger.Error("failed_get_promotions_checkpoint", "Failed to read the promotions checkpoint", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	if hasCheckpoint && checkpoint.Before(now) {
+		promotions, err := p.source.Database.GetPromotionsBetween(ctx, checkpoint, now)
+		if err != nil {
+			return nil, err
+		}
+
+		skus := []string{}
+		promotionIds := []string{}
+		for _, promotion := range promotions {
+			promotionIds = append(promotionIds, promotion.Id)
+			if !slices.Contains(skus, promotion.ItemKeyId) {
+				skus = append(skus, promotion.ItemKeyId)
+			}
+		}
+
+		if len(skus) > 0 {
+			p.source.Logger.Info("init_publish_promotion_boundaries", "Publishing products whose promotion started or ended", &map[string]interface{}{
+				"promotionIds": promotionIds,
+				"from":         checkpoint,
+				"to":           now,
+			})
+
+			_, errs := p.publishPromotionProducts(ctx, skus)
+			if len(errs) > 0 {
+				p.source.Logger.Error("failed_publish_promotion_boundaries", "Failed to publish products whose promotion started or ended", &map[string]interface{}{
+					"promotionIds": promotionIds,
+					"error":        errs,
+				})
+				return nil, errs[0]
+			}
+		}
+	}
+
+	err = p.app.Services.Store.Set(stateKey, now)
+	if err != nil {
+		p.source.Logger.Error("failed_set_promotions_checkpoint", "Failed to store the promotions checkpoint", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	return p.source.Database.GetNextPromotionBoundary(ctx, now)
+}
+
+// Publishes the current state of the given products as changes, through the mass change breaker and the price guard
+// as in the sync runs
+func (p *Pipeline) publishPromotionProducts(ctx context.Context, skus []string) (int, []error) {
+	stream := PROMOTION_BOUNDARIES_STREAM
+	entities, err := p.source.Database.GetEntitiesByKey(ctx, p.getEntityQuery(stream, nil), skus)
+	if err == nil {
+		err = p.loadEntities(ctx, stream, entities)
+	}
+	if err != nil {
+		p.source.Logger.Error("failed_get_changed_entities", "Failed to get changed entities", &map[string]interface{}{
+			"entity": stream.GetName(),
+			"error":  err.Error(),
+		})
+		return 0, []error{err}
+	}
+
+	return p.publishChanges(ctx, stream, entities, nil)
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Key of the last published available quantity of each product, by SKU, in the state store
+const STOCK_LEVELS_STATE_KEY = "stock_levels"
+
+func (p *Pipeline) getStateKey(key string) string {
+	return p.source.Id + "/" + key
+}
+
+// Publishes a transition for every published product whose available quantity crossed a threshold since it was
+// previously published. Products published for the first time have no previous quantity, so they have no transition.
+func (p *Pipeline) publishStockTransitions(ctx context.Context, entities []xd_rsync.Entity) []error {
+	sourceConfig := p.getSourceConfig()
+	topicArn := sourceConfig.Queues.StockTransitionsSnsQueueArn
+	if len(topicArn) == 0 {
+		return nil
+	}
+
+	stateKey := p.getStateKey(STOCK_LEVELS_STATE_KEY)
+	stockLevels := map[string]float64{}
+	_, err := p.app.Services.Store.Get(stateKey, &stockLevels)
+	if err != nil {
+		p.source.Logger.Error("failed_get_stock_levels", "Failed to read the last published stock levels", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return []error{err}
+	}
+
+	detectedAt := time.Now()
+	updatedStockLevels := maps.Clone(stockLevels)
+	transitions := []*xd_rsync.XdStockTransition{}
+	for _, entity := range entities {
+		product := entity.(*xd_rsync.XdProduct)
+		updatedStockLevels[product.SKU] = product.AvailableQuantity
+
+		previousQuantity, hasPreviousQuantity := stockLevels[product.SKU]
+		if !hasPreviousQuantity {
+			continue
+		}
+
+		transitionType, threshold := xd_rsync.GetStockTransition(sourceConfig.Stock.GetThresholds(product.Family), previousQuantity, product.AvailableQuantity)
+		if len(transitionType) == 0 {
+			continue
+		}
+
+		transitions = append(transitions, &xd_rsync.XdStockTransition{
+			SKU:               product.SKU,
+			Type:              transitionType,
+			Family:            product.Family,
+			PreviousQuantity:  previousQuantity,
+			AvailableQuantity: product.AvailableQuantity,
+			Threshold:         threshold,
+			DetectedAt:        detectedAt,
+		})
+	}
+
+	events := []xd_rsync.MessagePublishInput{}
+	for _, transition := range transitions {
+		encodedTransition, err := p.app.Services.Encoder.Encode(transition)
+		if err != nil {
+			p.source.Logger.Error("failed_encode_stock_transition", "Failed to encode stock transition for SNS topic message", &map[string]interface{}{
+				"error": err.Error(),
+				"sku":   transition.SKU,
+			})
+			return []error{err}
+		}
+
+		events = append(events, xd_rsync.MessagePublishInput{
+			Message:        encodedTransition.Body,
+			MessageGroupId: transition.SKU,
+			Attributes:     p.buildMessageAttributes(ctx, encodedTransition),
+		})
+	}
+
+	if len(events) > 0 {
+		_, errs := p.source.SNS.SendMessagesBatch(ctx, topicArn, &events)
+		if len(errs) > 0 {
+			p.source.Logger.Error("failed_publish_stock_transitions", "Failed to publish stock transitions", &map[string]interface{}{
+				"error": errs,
+			})
+			// Levels are kept as they were, so the transitions are detected again when the products are republished
+			return errs
+		}
+
+		for _, transition := range transitions {
+			p.source.Metrics.ObserveStockTransition(transition.Type)
+			p.source.Logger.Info("published_stock_transition", "Published stock transition", &map[string]interface{}{
+				"sku":               transition.SKU,
+				"type":              transition.Type,
+				"previousQuantity":  transition.PreviousQuantity,
+				"availableQuantity": transition.AvailableQuantity,
+			})
+		}
+	}
+
+	err = p.app.Services.Store.Set(stateKey, updatedStockLevels)
+	if err != nil {
+		p.source.Logger.Error("failed_set_stock_levels", "Failed to store the published stock levels", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return []error{err}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+func TestRunPublishesStockTransitions(t *testing.T) {
+	drinks := "Drinks"
+	drinksUppercase := "DRINKS"
+	food := "FOOD"
+
+	tests := []struct {
+		name       string
+		family     *string
+		quantities []float64
+		// Type, threshold and previous quantity of the published transitions
+		expectedTransitions []string
+	}{
+		{name: "first publish", quantities: []float64{0}, expectedTransitions: []string{}},
+		{name: "out of stock", quantities: []float64{10, 0}, expectedTransitions: []string{"out_of_stock 0 from 10"}},
+		{name: "out of stock from low stock", quantities: []float64{3, 0}, expectedTransitions: []string{"out_of_stock 0 from 3"}},
+		{name: "out of stock skipping low stock", quantities: []float64{10, -1}, expectedTransitions: []string{"out_of_stock 0 from 10"}},
+		{name: "low stock", quantities: []float64{10, 5}, expectedTransitions: []string{"low_stock 5 from 10"}},
+		{name: "back in stock", quantities: []float64{0, 4}, expectedTransitions: []string{"back_in_stock 0 from 0"}},
+		{name: "no crossing", quantities: []float64{10, 8}, expectedTransitions: []string{}},
+		{name: "staying low on stock", quantities: []float64{4, 2}, expectedTransitions: []string{}},
+		{name: "back above low stock", quantities: []float64{4, 20}, expectedTransitions: []string{}},
+		{name: "family low stock", family: &drinks, quantities: []float64{20, 10}, expectedTransitions: []string{"low_stock 10 from 20"}},
+		{name: "family out of stock ignoring case", family: &drinksUppercase, quantities: []float64{5, 2}, expectedTransitions: []string{"out_of_stock 2 from 5"}},
+		{name: "family back in stock", family: &drinks, quantities: []float64{2, 3}, expectedTransitions: []string{"back_in_stock 2 from 2"}},
+		{name: "family without thresholds", family: &food, quantities: []float64{10, 5}, expectedTransitions: []string{"low_stock 5 from 10"}},
+		{
+			name:                "compared against the last published level",
+			quantities:          []float64{10, 6, 4, 3, 0, 0, 1},
+			expectedTransitions: []string{"low_stock 5 from 6", "out_of_stock 0 from 3", "back_in_stock 0 from 0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := createTestConfig(&xd_rsync.QueuesConfig{ProductUpdatesSnsQueueArn: "products", StockTransitionsSnsQueueArn: "transitions"})
+			cfg.Sources[0].Stock = &xd_rsync.StockConfig{
+				Thresholds: &xd_rsync.StockThresholdsConfig{OutOfStockQuantity: 0, LowStockQuantity: 5},
+				FamilyThresholds: map[string]*xd_rsync.StockThresholdsConfig{
+					"drinks": {OutOfStockQuantity: 2, LowStockQuantity: 10},
+				},
+			}
+
+			product := createTestProduct("A1", time.Now().Add(-time.Hour))
+			product.Family = test.family
+			database := &testDatabase{entities: map[string][]xd_rsync.Entity{"products": {product}}}
+			sns := &testSNS{messages: map[string][]xd_rsync.MessagePublishInput{}}
+			pipeline := createTestPipeline(t, cfg, createTestStore(t, ""), database, sns)
+
+			for _, quantity := range test.quantities {
+				changeTestProduct(product, func(product *xd_rsync.XdProduct) {
+					product.Warehouses = []xd_rsync.XdProductWarehouseStock{{ItemKeyId: "A1", WarehouseId: "1", AvailableQuantity: quantity}}
+				})
+
+				if errs := pipeline.Run(context.Background()); len(errs) > 0 {
+					t.Fatalf("expected the run to succeed, got %v", errs)
+				}
+			}
+
+			transitions := []string{}
+			for _, message := range sns.messages["transitions"] {
+				transition := xd_rsync.XdStockTransition{}
+				err := json.Unmarshal([]byte(message.Message), &transition)
+				if err != nil {
+					t.Fatalf("could not decode transition: %v", err)
+				}
+
+				transitions = append(transitions, fmt.Sprintf("%s %v from %v", transition.Type, transition.Threshold, transition.PreviousQuantity))
+			}
+
+			if !slices.Equal(transitions, test.expectedTransitions) {
+				t.Errorf("expected transitions %v, got %v", test.expectedTransitions, transitions)
+			}
+		})
+	}
+}
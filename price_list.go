@@ -0,0 +1,65 @@
+package xd_rsync
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var PRICE_LIST_NAME_PATTERN = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// XD price tables have a row per product and table, whose changes are tracked by their own SyncStamp
+var ITEM_PRICE_TABLE = "itemprices ip"
+
+// Latest change of the price table rows of each product
+var ITEM_TO_ITEM_PRICES_JOIN_EXPRESSION = "LEFT JOIN (" +
+	"SELECT ItemKeyId, MAX(SyncStamp) as SyncStamp FROM itemprices GROUP BY ItemKeyId" +
+	") iprice ON iprice.ItemKeyId = i.KeyId"
+
+// Price published in the product events under the given name, read from either a column of the items table or an
+// XD price table, e.g. {name: wholesale, column: RetailPrice3} or {name: resellers, priceTableId: "2"}
+type PriceListConfig struct {
+	Name         string `json:"name"`
+	Column       string `json:"column,omitempty"`
+	PriceTableId string `json:"priceTableId,omitempty"`
+}
+
+func (c *PriceListConfig) IsPriceTable() bool {
+	return len(c.PriceTableId) > 0
+}
+
+// Column of the items table the prices are read from, which is only used when it is a plain identifier as it becomes
+// part of the query
+func (c *PriceListConfig) GetColumn() (string, error) {
+	if !PRODUCT_FILTER_COLUMN_PATTERN.MatchString(c.Column) {
+		return "", fmt.Errorf("column '%s' of price list %s is not a valid column name", c.Column, c.Name)
+	}
+
+	return "i." + c.Column, nil
+}
+
+// Whether any of the price lists is read from an XD price table
+func HasPriceTables(priceLists []*PriceListConfig) bool {
+	for _, priceList := range priceLists {
+		if priceList.IsPriceTable() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Price of a product in one price list, as read from XD
+type XdPriceListPrice struct {
+	ItemKeyId string     `db:"ItemKeyId"`
+	Price     Decimal    `db:"Price"`
+	SyncStamp *time.Time `db:"SyncStamp"`
+}
+
+// Price of a product in a named price list
+type XdProductPriceList struct {
+	Name string `json:"name"`
+	// Exact price read from XD, published through Price
+	Amount Decimal  `json:"-"`
+	Price  *XdMoney `json:"price"`
+}
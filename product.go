@@ -4,26 +4,117 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"sync"
+	"slices"
 	"time"
 )
 
 var ErrProductJsonNotValid = fmt.Errorf("emitted product JSON is not valid")
 
 type XdProduct struct {
-	SKU               string     `db:"KeyId" dbSelector:"i.KeyId" json:"sku"`
-	Description       string     `db:"Description" dbSelector:"i.Description" json:"name"`
-	RetailPrice1      float64    `db:"RetailPrice1" dbSelector:"i.RetailPrice1" json:"clientCompareAtPrice"`
-	RetailPrice2      float64    `db:"RetailPrice2" dbSelector:"i.RetailPrice2" json:"clientPrice"`
-	AvailableQuantity float64    `db:"AvailableQuantity" dbSelector:"IFNULL(istock.AvailableQuantity, 0) as AvailableQuantity" json:"availableQuantity"`
+	SKU         string `db:"KeyId" dbSelector:"i.KeyId" json:"sku"`
+	Description string `db:"Description" dbSelector:"i.Description" json:"name"`
+	// Float copies of the rounded CompareAtPrice and Price amounts, kept for existing consumers
+	RetailPrice1 float64 `db:"-" json:"clientCompareAtPrice"`
+	RetailPrice2 float64 `db:"-" json:"clientPrice"`
+	// Summed from the sellable warehouses
+	AvailableQuantity float64    `db:"-" json:"availableQuantity"`
 	SyncStamp         *time.Time `db:"SyncStamp" dbSelector:"i.SyncStamp as SyncStamp" json:"syncStamp"`
 	StockSyncStamp    *time.Time `db:"StockSyncStamp" dbSelector:"istock.SyncStamp as StockSyncStamp" json:"stockSyncStamp"`
 	StockLastEntrance *time.Time `db:"StockLastEntrance" dbSelector:"istock.LastEntrance as StockLastEntrance" json:"stockLastEntrance"`
 	StockLastExit     *time.Time `db:"StockLastExit" dbSelector:"istock.LastExit as StockLastExit" json:"stockLastExit"`
+	// Summed from the sellable warehouses
+	ReservedQuantity float64                   `db:"-" json:"reservedQuantity,omitempty"`
+	Warehouses       []XdProductWarehouseStock `db:"-" json:"warehouses,omitempty"`
+	Family           *string                   `db:"Family" dbSelector:"i.Family" json:"family,omitempty"`
+	// Only used by the price guard, so margins are never published
+	CostPrice Decimal `db:"CostPrice" dbSelector:"IFNULL(i.CostPrice, 0) as CostPrice" json:"-"`
+	// Exact retail prices, published through Price and CompareAtPrice
+	ExactRetailPrice1 Decimal `db:"ExactRetailPrice1" dbSelector:"i.RetailPrice1 as ExactRetailPrice1" json:"-"`
+	ExactRetailPrice2 Decimal `db:"ExactRetailPrice2" dbSelector:"i.RetailPrice2 as ExactRetailPrice2" json:"-"`
+	// VAT rate of the tax code of the product, in percentage
+	VatRate        Decimal  `db:"VatRate" dbSelector:"IFNULL(tax.TaxRate, 0) as VatRate" json:"-"`
+	Price          *XdMoney `db:"-" json:"price,omitempty"`
+	CompareAtPrice *XdMoney `db:"-" json:"compareAtPrice,omitempty"`
+	// Prices of the configured price lists, in the order they are configured
+	PriceLists []XdProductPriceList `db:"-" json:"priceLists,omitempty"`
+	// Latest change of the price table rows of the price lists
+	PriceListsSyncStamp *time.Time `db:"-" json:"-"`
+	// Promotion active when the product was read, when promotions are enabled
+	Promotion *XdProductPromotion `db:"-" json:"promotion,omitempty"`
+	// Price the product is sold at: the promotional price while a promotion is active, or the price otherwise
+	EffectivePrice *XdMoney `db:"-" json:"effectivePrice,omitempty"`
+}
+
+// Stock of a product in one warehouse
+type XdProductWarehouseStock struct {
+	ItemKeyId         string     `db:"ItemKeyId" dbSelector:"s.ItemKeyId" json:"-"`
+	WarehouseId       string     `db:"WarehouseId" dbSelector:"s.WarehouseId" json:"warehouseId"`
+	AvailableQuantity float64    `db:"AvailableQuantity" dbSelector:"IFNULL(s.AvailableQuantity, 0) as AvailableQuantity" json:"availableQuantity"`
+	ReservedQuantity  float64    `db:"ReservedQuantity" dbSelector:"IFNULL(s.ReservedQuantity, 0) as ReservedQuantity" json:"reservedQuantity"`
+	LastEntrance      *time.Time `db:"LastEntrance" dbSelector:"s.LastEntrance" json:"lastEntrance"`
+	LastExit          *time.Time `db:"LastExit" dbSelector:"s.LastExit" json:"lastExit"`
+	// Whether the stock counts towards the available quantity of the product
+	IsSellable bool `db:"-" json:"sellable"`
+}
+
+func (w *XdProductWarehouseStock) GetTableName() string {
+	return "xd.itemstock s"
+}
+
+func (w *XdProductWarehouseStock) GetJoinExpressions() []string {
+	return nil
+}
+
+func (w *XdProductWarehouseStock) GetConditions() []Condition {
+	return nil
+}
+
+func (w *XdProductWarehouseStock) GetParentKeyColumnName() string {
+	return "s.ItemKeyId"
+}
+
+func (w *XdProductWarehouseStock) GetOrderColumnName() string {
+	return "s.WarehouseId"
+}
+
+func (w *XdProductWarehouseStock) GetParentKey() string {
+	return w.ItemKeyId
+}
+
+// Products with a price, regardless of the product filter rules of the sources
+var PRICED_PRODUCT_CONDITION = []Condition{
+	NewCondition("i.RetailPrice2 > 0"),
+}
+
+// Items have a stock row per warehouse, which are aggregated so each product is a single row. The latest timestamps
+// of all warehouses are kept, so a change in any of them is captured.
+var ITEM_TO_ITEMSTOCK_JOIN_EXPRESSION = "LEFT JOIN (" +
+	"SELECT ItemKeyId, MAX(SyncStamp) as SyncStamp, MAX(LastEntrance) as LastEntrance, MAX(LastExit) as LastExit " +
+	"FROM xd.itemstock GROUP BY ItemKeyId" +
+	") istock ON istock.ItemKeyId = i.KeyId"
+
+// Products without a tax code have no VAT
+var ITEM_TO_TAX_JOIN_EXPRESSION = "LEFT JOIN taxes tax ON tax.KeyId = i.TaxKeyId"
+
+func (p *XdProduct) GetEntityName() string {
+	return "products"
 }
 
 func (p *XdProduct) GetTableName() string {
-	return "items"
+	return "items i"
+}
+
+func (p *XdProduct) GetJoinExpressions() []string {
+	return []string{ITEM_TO_ITEMSTOCK_JOIN_EXPRESSION, ITEM_TO_TAX_JOIN_EXPRESSION}
+}
+
+// Products are selected by the product filter rules of each source instead
+func (p *XdProduct) GetConditions() []Condition {
+	return nil
+}
+
+func (p *XdProduct) GetChangeColumns() []string {
+	return []string{"i.SyncStamp", "istock.SyncStamp", "istock.LastEntrance", "istock.LastExit"}
 }
 
 func (p *XdProduct) GetPrimaryKeyColumnName() string {
@@ -34,59 +125,117 @@ func (p *XdProduct) GetPrimaryKeyColumnName() string {
 	return tag.Get("dbSelector")
 }
 
-func (p *XdProduct) GetKnownColumns() []string {
-	columnNames := []string{}
-	val := reflect.ValueOf(p).Elem()
-	for i := 0; i < val.NumField(); i++ {
-		tag := val.Type().Field(i).Tag
-		columnNames = append(columnNames, tag.Get("dbSelector"))
+func (p *XdProduct) GetKey() string {
+	return p.SKU
+}
+
+func (p *XdProduct) GetLastChangedAt() *time.Time {
+	return GetLatestTimestamp(p.SyncStamp, p.StockSyncStamp, p.StockLastEntrance, p.StockLastExit, p.PriceListsSyncStamp)
+}
+
+func (p *XdProduct) GetChildEntity() ChildEntity {
+	return &XdProductWarehouseStock{}
+}
+
+func (p *XdProduct) SetChildren(children []ChildEntity) {
+	p.Warehouses = make([]XdProductWarehouseStock, 0, len(children))
+	for _, child := range children {
+		p.Warehouses = append(p.Warehouses, *child.(*XdProductWarehouseStock))
 	}
 
-	return columnNames
+	p.SetSellableWarehouses(nil)
 }
 
-func (p *XdProduct) GetKnownColumnsQuerySelectors() string {
-	columnNames := p.GetKnownColumns()
+// Sets the exact prices of the product and of its price lists, with their VAT variants
+func (p *XdProduct) SetPrices(money *MoneyConfig) {
+	p.Price = money.NewMoney(p.ExactRetailPrice2, p.VatRate)
+	p.CompareAtPrice = money.NewMoney(p.ExactRetailPrice1, p.VatRate)
+	p.RetailPrice2 = p.Price.Amount.Float64()
+	p.RetailPrice1 = p.CompareAtPrice.Amount.Float64()
+	for i := range p.PriceLists {
+		p.PriceLists[i].Price = money.NewMoney(p.PriceLists[i].Amount, p.VatRate)
+	}
 
-	var expression string = ""
-	for index, name := range columnNames {
-		expression += name
+	p.EffectivePrice = p.Price
+	if p.Promotion != nil {
+		p.Promotion.Price = money.NewMoney(p.Promotion.Amount, p.VatRate)
+		p.EffectivePrice = p.Promotion.Price
+	}
+}
 
-		if index < len(columnNames)-1 {
-			expression += ", "
-		}
+// Keeps the given promotion when it has a lower price than the one already set, as products can have several active
+// promotions at once
+func (p *XdProduct) SetPromotion(promotion *XdPromotion) {
+	if p.Promotion != nil && p.Promotion.Amount.Cmp(promotion.Price) <= 0 {
+		return
 	}
 
-	return expression
+	p.Promotion = &XdProductPromotion{
+		Id:       promotion.Id,
+		Amount:   promotion.Price,
+		StartsAt: promotion.StartsAt,
+		EndsAt:   promotion.EndsAt,
+	}
 }
 
-func (p *XdProduct) ToJSON() (string, error) {
-	bytes, err := json.Marshal(p)
-	if err != nil {
-		return "", ErrProductJsonNotValid
+// Exact amount the product is sold at, as published: the effective price once the prices are set, or the
+// promotional or retail price otherwise
+func (p *XdProduct) GetEffectiveAmount() Decimal {
+	if p.EffectivePrice != nil {
+		return p.EffectivePrice.Amount
 	}
 
-	return string(bytes), nil
+	if p.Promotion != nil {
+		return p.Promotion.Amount
+	}
+
+	return p.ExactRetailPrice2
 }
 
-type XdProducts []XdProduct
+// Exact retail amount, as published, regardless of promotions
+func (p *XdProduct) GetBaseAmount() Decimal {
+	if p.Price != nil {
+		return p.Price.Amount
+	}
 
-func (ps *XdProducts) GetTableName() string {
-	return "items i"
+	return p.ExactRetailPrice2
 }
 
-func (ps *XdProducts) GetPrimaryKeyColumnName() string {
-	product := &XdProduct{}
-	return product.GetPrimaryKeyColumnName()
+// Exact compare-at amount, as published
+func (p *XdProduct) GetCompareAtAmount() Decimal {
+	if p.CompareAtPrice != nil {
+		return p.CompareAtPrice.Amount
+	}
+
+	return p.ExactRetailPrice1
 }
 
-func (ps *XdProducts) GetKnownColumnsQuerySelectors() string {
-	product := &XdProduct{}
-	return product.GetKnownColumnsQuerySelectors()
+func (p *XdProduct) AddPriceListPrice(name string, price *XdPriceListPrice) {
+	p.PriceLists = append(p.PriceLists, XdProductPriceList{
+		Name:   name,
+		Amount: price.Price,
+	})
+	p.PriceListsSyncStamp = GetLatestTimestamp(p.PriceListsSyncStamp, price.SyncStamp)
 }
 
-func (ps *XdProducts) ToJSON() (string, error) {
-	bytes, err := json.Marshal(ps)
+// Sums the stock of the given warehouses into the product quantities, or of every warehouse when none is given
+func (p *XdProduct) SetSellableWarehouses(warehouseIds []string) {
+	p.AvailableQuantity = 0
+	p.ReservedQuantity = 0
+	for i := range p.Warehouses {
+		warehouse := &p.Warehouses[i]
+		warehouse.IsSellable = len(warehouseIds) == 0 || slices.Contains(warehouseIds, warehouse.WarehouseId)
+		if !warehouse.IsSellable {
+			continue
+		}
+
+		p.AvailableQuantity += warehouse.AvailableQuantity
+		p.ReservedQuantity += warehouse.ReservedQuantity
+	}
+}
+
+func (p *XdProduct) ToJSON() (string, error) {
+	bytes, err := json.Marshal(p)
 	if err != nil {
 		return "", ErrProductJsonNotValid
 	}
@@ -94,20 +243,13 @@ func (ps *XdProducts) ToJSON() (string, error) {
 	return string(bytes), nil
 }
 
-type XdProductsChunksWithMutex struct {
-	mutex  sync.Mutex
-	Chunks *map[int]XdProducts
-}
-
-func (c *XdProductsChunksWithMutex) UpdateChunk(index int, productsList *XdProducts) {
-	c.mutex.Lock()
-
-	defer c.mutex.Unlock()
-	(*c.Chunks)[index] = *productsList
-}
+type XdProducts []XdProduct
 
-func (c *XdProductsChunksWithMutex) GetList(list *XdProducts) {
-	for chunkNumber := 0; chunkNumber < len(*c.Chunks); chunkNumber++ {
-		*list = append(*list, (*c.Chunks)[chunkNumber]...)
+func (ps *XdProducts) ToJSON() (string, error) {
+	bytes, err := json.Marshal(ps)
+	if err != nil {
+		return "", ErrProductJsonNotValid
 	}
+
+	return string(bytes), nil
 }
@@ -0,0 +1,170 @@
+package xd_rsync
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	FILTER_OPERATOR_EQ              = "eq"
+	FILTER_OPERATOR_NE              = "ne"
+	FILTER_OPERATOR_GT              = "gt"
+	FILTER_OPERATOR_GTE             = "gte"
+	FILTER_OPERATOR_LT              = "lt"
+	FILTER_OPERATOR_LTE             = "lte"
+	FILTER_OPERATOR_IN              = "in"
+	FILTER_OPERATOR_NOT_IN          = "notIn"
+	FILTER_OPERATOR_STARTS_WITH     = "startsWith"
+	FILTER_OPERATOR_NOT_STARTS_WITH = "notStartsWith"
+	FILTER_OPERATOR_IS_NULL         = "isNull"
+	FILTER_OPERATOR_IS_NOT_NULL     = "isNotNull"
+)
+
+var FILTER_COMPARISON_OPERATORS = map[string]string{
+	FILTER_OPERATOR_EQ:  "=",
+	FILTER_OPERATOR_NE:  "<>",
+	FILTER_OPERATOR_GT:  ">",
+	FILTER_OPERATOR_GTE: ">=",
+	FILTER_OPERATOR_LT:  "<",
+	FILTER_OPERATOR_LTE: "<=",
+}
+
+// Names of the product fields that can be used in filters, by the column they are read from
+var PRODUCT_FILTER_FIELDS = map[string]string{
+	"sku":            "i.KeyId",
+	"family":         "i.Family",
+	"price":          "i.RetailPrice2",
+	"compareAtPrice": "i.RetailPrice1",
+	"costPrice":      "i.CostPrice",
+}
+
+// Any other column of the items table can be used by its name, as long as it is a plain identifier
+var PRODUCT_FILTER_COLUMN_PATTERN = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// Products are only synchronised when they have a price, unless other rules are configured
+var DEFAULT_PRODUCT_FILTER_RULES = []*ProductFilterRule{
+	{Field: "price", Operator: FILTER_OPERATOR_GT, Value: 0},
+}
+
+// Rule every synchronised product must meet, e.g. {field: family, operator: in, values: [DRINKS]}
+type ProductFilterRule struct {
+	// Product field or column of the items table
+	Field    string        `json:"field"`
+	Operator string        `json:"operator"`
+	Value    interface{}   `json:"value,omitempty"`
+	Values   []interface{} `json:"values,omitempty"`
+}
+
+func getProductFilterColumn(field string) (string, error) {
+	column, isKnownField := PRODUCT_FILTER_FIELDS[field]
+	if isKnownField {
+		return column, nil
+	}
+
+	if !PRODUCT_FILTER_COLUMN_PATTERN.MatchString(field) {
+		return "", fmt.Errorf("field '%s' is not a valid column name", field)
+	}
+
+	return "i." + field, nil
+}
+
+// Values are bound as SQL arguments, so only text, numbers and booleans are accepted, not lists or maps
+func isScalarFilterValue(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// Escapes the LIKE wildcards of a prefix
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// Compiles the SKU prefixes and exclusions of a source into conditions, so they apply to every product query
+func CompileSkuFilters(skuPrefixes []string, excludedSkus []string) []Condition {
+	column := PRODUCT_FILTER_FIELDS["sku"]
+	conditions := []Condition{}
+	if len(excludedSkus) > 0 {
+		conditions = append(conditions, NewCondition(column+" NOT IN (?)", excludedSkus))
+	}
+
+	if len(skuPrefixes) > 0 {
+		expressions := []string{}
+		args := []interface{}{}
+		for _, prefix := range skuPrefixes {
+			expressions = append(expressions, column+" LIKE ?")
+			args = append(args, escapeLikePattern(prefix)+"%")
+		}
+		conditions = append(conditions, NewCondition(strings.Join(expressions, " OR "), args...))
+	}
+
+	return conditions
+}
+
+// Compiles the rule into a condition, binding its values as arguments
+func (r *ProductFilterRule) Compile() (Condition, error) {
+	column, err := getProductFilterColumn(r.Field)
+	if err != nil {
+		return Condition{}, err
+	}
+
+	if sqlOperator, isComparison := FILTER_COMPARISON_OPERATORS[r.Operator]; isComparison {
+		if r.Value == nil {
+			return Condition{}, fmt.Errorf("operator '%s' requires a value", r.Operator)
+		}
+
+		if !isScalarFilterValue(r.Value) {
+			return Condition{}, fmt.Errorf("operator '%s' requires a text, number or boolean value", r.Operator)
+		}
+
+		return NewCondition(column+" "+sqlOperator+" ?", r.Value), nil
+	}
+
+	switch r.Operator {
+	case FILTER_OPERATOR_IN, FILTER_OPERATOR_NOT_IN:
+		if len(r.Values) == 0 {
+			return Condition{}, fmt.Errorf("operator '%s' requires a list of values", r.Operator)
+		}
+
+		if slices.Contains(r.Values, nil) {
+			return Condition{}, fmt.Errorf("operator '%s' does not support empty values", r.Operator)
+		}
+
+		for _, value := range r.Values {
+			if !isScalarFilterValue(value) {
+				return Condition{}, fmt.Errorf("operator '%s' only supports text, number or boolean values", r.Operator)
+			}
+		}
+
+		if r.Operator == FILTER_OPERATOR_NOT_IN {
+			return NewCondition(column+" NOT IN (?)", r.Values), nil
+		}
+		return NewCondition(column+" IN (?)", r.Values), nil
+	case FILTER_OPERATOR_STARTS_WITH, FILTER_OPERATOR_NOT_STARTS_WITH:
+		prefix, isString := r.Value.(string)
+		if !isString || len(prefix) == 0 {
+			return Condition{}, fmt.Errorf("operator '%s' requires a text value", r.Operator)
+		}
+
+		pattern := escapeLikePattern(prefix) + "%"
+		if r.Operator == FILTER_OPERATOR_NOT_STARTS_WITH {
+			return NewCondition(column+" NOT LIKE ?", pattern), nil
+		}
+		return NewCondition(column+" LIKE ?", pattern), nil
+	case FILTER_OPERATOR_IS_NULL:
+		return NewCondition(column + " IS NULL"), nil
+	case FILTER_OPERATOR_IS_NOT_NULL:
+		return NewCondition(column + " IS NOT NULL"), nil
+	}
+
+	return Condition{}, fmt.Errorf("operator '%s' is not supported", r.Operator)
+}
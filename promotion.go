@@ -0,0 +1,40 @@
+package xd_rsync
+
+import (
+	"time"
+)
+
+// Promotional price of a product, valid from its start date until its end date
+type XdPromotion struct {
+	Id        string     `db:"KeyId" dbSelector:"pr.KeyId"`
+	ItemKeyId string     `db:"ItemKeyId" dbSelector:"pr.ItemKeyId"`
+	Price     Decimal    `db:"Price" dbSelector:"pr.PromotionalPrice as Price"`
+	StartsAt  *time.Time `db:"StartDate" dbSelector:"pr.StartDate"`
+	// Open-ended when empty
+	EndsAt *time.Time `db:"EndDate" dbSelector:"pr.EndDate"`
+}
+
+func (p *XdPromotion) GetTableName() string {
+	return "promotions pr"
+}
+
+// Whether the promotion is valid at the given time
+func (p *XdPromotion) IsActive(at time.Time) bool {
+	return p.StartsAt != nil && !p.StartsAt.After(at) && (p.EndsAt == nil || p.EndsAt.After(at))
+}
+
+// Promotion a product is sold at while it is valid
+type XdProductPromotion struct {
+	Id string `json:"id"`
+	// Exact promotional price read from XD, published through Price
+	Amount   Decimal    `json:"-"`
+	Price    *XdMoney   `json:"price"`
+	StartsAt *time.Time `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt"`
+}
+
+type PromotionsConfig struct {
+	// Whether the active promotion of each product is published, and products are published again when their
+	// promotion starts or ends
+	Enabled bool `json:"enabled"`
+}
@@ -0,0 +1,394 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/pipeline"
+	"github.com/fabiofcferreira/xd-rsync/tickers"
+)
+
+const MAX_REPUBLISH_SKUS = 1000
+
+var ErrAdminTokenRequired = errors.New("admin API requires a token")
+
+// Pipeline and scheduler of a source, controlled through the admin API
+type AdminSource struct {
+	Pipeline  *pipeline.Pipeline
+	Scheduler *tickers.Scheduler
+}
+
+type AdminServerCreationInput struct {
+	ListenAddress string
+	Token         string
+	App           *xd_rsync.XdRsyncInstance
+	Sources       map[string]*AdminSource
+}
+
+type adminHandlers struct {
+	app     *xd_rsync.XdRsyncInstance
+	sources map[string]*AdminSource
+}
+
+type sourceSummary struct {
+	Id        string                  `json:"id"`
+	Scheduler tickers.SchedulerStatus `json:"scheduler"`
+	Status    xd_rsync.SyncStatus     `json:"status"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type republishRequest struct {
+	Skus []string `json:"skus"`
+}
+
+type republishResponse struct {
+	RequestedCount int      `json:"requestedCount"`
+	PublishedCount int      `json:"publishedCount"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+type checkpointPayload struct {
+	Checkpoint time.Time `json:"checkpoint"`
+}
+
+type quarantineActionResponse struct {
+	Sku    string   `json:"sku"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type triggerResponse struct {
+	IsTriggered bool `json:"isTriggered"`
+}
+
+// Creates the admin API server, which refuses to be created without a token as every request would be accepted
+func CreateAdminServer(input *AdminServerCreationInput) (*Server, error) {
+	if len(input.Token) == 0 {
+		return nil, ErrAdminTokenRequired
+	}
+
+	server := &Server{
+		app: input.App,
+	}
+
+	handlers := &adminHandlers{
+		app:     input.App,
+		sources: input.Sources,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/sources", handlers.handleListSources)
+	mux.HandleFunc("GET /admin/runs", handlers.handleListRuns)
+	mux.HandleFunc("POST /admin/runs", handlers.handleTriggerRun)
+	mux.HandleFunc("POST /admin/products/republish", handlers.handleRepublishProducts)
+	mux.HandleFunc("GET /admin/scheduler", handlers.handleGetScheduler)
+	mux.HandleFunc("POST /admin/scheduler/pause", handlers.handlePauseScheduler)
+	mux.HandleFunc("POST /admin/scheduler/resume", handlers.handleResumeScheduler)
+	mux.HandleFunc("GET /admin/checkpoint", handlers.handleGetCheckpoint)
+	mux.HandleFunc("PUT /admin/checkpoint", handlers.handleSetCheckpoint)
+	mux.HandleFunc("POST /admin/mass-change/confirm", handlers.handleConfirmMassChange)
+	mux.HandleFunc("GET /admin/quarantine", handlers.handleListQuarantine)
+	mux.HandleFunc("POST /admin/quarantine/{sku}/approve", handlers.handleApproveQuarantinedProduct)
+	mux.HandleFunc("POST /admin/quarantine/{sku}/reject", handlers.handleRejectQuarantinedProduct)
+
+	server.httpServer = &http.Server{
+		Addr:              input.ListenAddress,
+		Handler:           requireBearerToken(input.Token, input.App, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return server, nil
+}
+
+// Only lets through the requests with the given bearer token. Every request is rejected when the token is empty.
+func requireBearerToken(token string, app *xd_rsync.XdRsyncInstance, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		providedToken, hasBearerPrefix := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if len(token) == 0 || !hasBearerPrefix || subtle.ConstantTimeCompare([]byte(providedToken), []byte(token)) != 1 {
+			app.Logger.Warn("unauthorized_admin_request", "Rejected unauthorized admin API request", &map[string]interface{}{
+				"method": r.Method,
+				"path":   r.URL.Path,
+			})
+			writeJson(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+
+		app.Logger.Info("admin_request", "Received admin API request", &map[string]interface{}{
+			"method": r.Method,
+			"path":   r.URL.Path,
+		})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Finds the source given in the "source" query parameter, which can be left out when there is a single source
+func (h *adminHandlers) resolveSource(w http.ResponseWriter, r *http.Request) (*xd_rsync.XdRsyncSource, *AdminSource, bool) {
+	sourceId := r.URL.Query().Get("source")
+	if len(sourceId) == 0 && len(h.app.Sources) == 1 {
+		sourceId = h.app.Sources[0].Id
+	}
+
+	if len(sourceId) == 0 {
+		writeJson(w, http.StatusBadRequest, errorResponse{Error: "the source query parameter is required"})
+		return nil, nil, false
+	}
+
+	source := h.app.GetSource(sourceId)
+	adminSource, isControlled := h.sources[sourceId]
+	if source == nil || !isControlled {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "source '" + sourceId + "' was not found"})
+		return nil, nil, false
+	}
+
+	return source, adminSource, true
+}
+
+func (h *adminHandlers) handleListSources(w http.ResponseWriter, r *http.Request) {
+	summaries := []sourceSummary{}
+	for _, source := range h.app.Sources {
+		adminSource, isControlled := h.sources[source.Id]
+		if !isControlled {
+			continue
+		}
+
+		summaries = append(summaries, sourceSummary{
+			Id:        source.Id,
+			Scheduler: adminSource.Scheduler.GetStatus(),
+			Status:    source.State.GetStatus(),
+		})
+	}
+
+	writeJson(w, http.StatusOK, summaries)
+}
+
+func (h *adminHandlers) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	source, _, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	writeJson(w, http.StatusOK, source.State.GetRecentRuns())
+}
+
+func (h *adminHandlers) handleTriggerRun(w http.ResponseWriter, r *http.Request) {
+	_, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	writeJson(w, http.StatusAccepted, triggerResponse{
+		IsTriggered: adminSource.Scheduler.Trigger(),
+	})
+}
+
+func (h *adminHandlers) handleRepublishProducts(w http.ResponseWriter, r *http.Request) {
+	_, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	request := &republishRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, errorResponse{Error: "request body is not valid: " + err.Error()})
+		return
+	}
+
+	if len(request.Skus) == 0 || len(request.Skus) > MAX_REPUBLISH_SKUS {
+		writeJson(w, http.StatusBadRequest, errorResponse{Error: "between 1 and 1000 SKUs must be provided"})
+		return
+	}
+
+	publishedCount, errs := adminSource.Pipeline.RepublishProducts(r.Context(), request.Skus)
+
+	response := republishResponse{
+		RequestedCount: len(request.Skus),
+		PublishedCount: publishedCount,
+	}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	statusCode := http.StatusOK
+	if len(errs) > 0 {
+		statusCode = http.StatusBadGateway
+	}
+
+	writeJson(w, statusCode, response)
+}
+
+func (h *adminHandlers) handleGetScheduler(w http.ResponseWriter, r *http.Request) {
+	_, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	writeJson(w, http.StatusOK, adminSource.Scheduler.GetStatus())
+}
+
+func (h *adminHandlers) handlePauseScheduler(w http.ResponseWriter, r *http.Request) {
+	source, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	adminSource.Scheduler.Pause()
+	source.Logger.Warn("paused_scheduler", "Scheduler was paused through the admin API", nil)
+
+	writeJson(w, http.StatusOK, adminSource.Scheduler.GetStatus())
+}
+
+func (h *adminHandlers) handleResumeScheduler(w http.ResponseWriter, r *http.Request) {
+	source, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	adminSource.Scheduler.Resume()
+	source.Logger.Info("resumed_scheduler", "Scheduler was resumed through the admin API", nil)
+
+	writeJson(w, http.StatusOK, adminSource.Scheduler.GetStatus())
+}
+
+func (h *adminHandlers) handleGetCheckpoint(w http.ResponseWriter, r *http.Request) {
+	source, _, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	writeJson(w, http.StatusOK, checkpointPayload{
+		Checkpoint: source.State.GetCheckpoint(),
+	})
+}
+
+func (h *adminHandlers) handleSetCheckpoint(w http.ResponseWriter, r *http.Request) {
+	source, _, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	request := &checkpointPayload{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Checkpoint.IsZero() {
+		writeJson(w, http.StatusBadRequest, errorResponse{Error: "checkpoint must be a valid RFC 3339 timestamp"})
+		return
+	}
+
+	if request.Checkpoint.After(time.Now()) {
+		writeJson(w, http.StatusBadRequest, errorResponse{Error: "checkpoint cannot be in the future"})
+		return
+	}
+
+	if source.State.GetStatus().IsRunning {
+		writeJson(w, http.StatusConflict, errorResponse{Error: "checkpoint cannot be changed while a sync run is in progress"})
+		return
+	}
+
+	previousCheckpoint := source.State.GetCheckpoint()
+	source.State.SetCheckpoint(request.Checkpoint)
+	// The stream held back by a mass change moves with the checkpoint, skipping the held changes
+	if hold := source.State.GetMassChangeHold(); hold != nil {
+		source.State.DeleteStreamCheckpoint(hold.Entity)
+	}
+	source.Logger.Warn("changed_checkpoint", "Checkpoint was changed through the admin API", &map[string]interface{}{
+		"previousCheckpoint": previousCheckpoint,
+		"checkpoint":         request.Checkpoint,
+	})
+
+	writeJson(w, http.StatusOK, request)
+}
+
+func (h *adminHandlers) handleConfirmMassChange(w http.ResponseWriter, r *http.Request) {
+	source, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	hold, isHeld := source.State.ConfirmMassChangeHold()
+	if !isHeld {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "no mass change is on hold"})
+		return
+	}
+
+	source.Logger.Warn("confirmed_mass_change", "Mass change was confirmed through the admin API", &map[string]interface{}{
+		"entity":       hold.Entity,
+		"changedCount": hold.ChangedCount,
+	})
+
+	// The confirmed changes are published on the next run, which is requested right away
+	adminSource.Scheduler.Trigger()
+	writeJson(w, http.StatusAccepted, hold)
+}
+
+func (h *adminHandlers) handleListQuarantine(w http.ResponseWriter, r *http.Request) {
+	_, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	quarantinedProducts, err := adminSource.Pipeline.GetQuarantinedProducts()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJson(w, http.StatusOK, quarantinedProducts)
+}
+
+func (h *adminHandlers) handleApproveQuarantinedProduct(w http.ResponseWriter, r *http.Request) {
+	_, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	sku := r.PathValue("sku")
+	errs := adminSource.Pipeline.ApproveQuarantinedProduct(r.Context(), sku)
+	if len(errs) == 1 && errors.Is(errs[0], pipeline.ErrProductNotQuarantined) {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "product '" + sku + "' is not quarantined"})
+		return
+	}
+
+	response := quarantineActionResponse{
+		Sku: sku,
+	}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	statusCode := http.StatusOK
+	if len(errs) > 0 {
+		statusCode = http.StatusBadGateway
+	}
+
+	writeJson(w, statusCode, response)
+}
+
+func (h *adminHandlers) handleRejectQuarantinedProduct(w http.ResponseWriter, r *http.Request) {
+	_, adminSource, isFound := h.resolveSource(w, r)
+	if !isFound {
+		return
+	}
+
+	sku := r.PathValue("sku")
+	err := adminSource.Pipeline.RejectQuarantinedProduct(sku)
+	if errors.Is(err, pipeline.ErrProductNotQuarantined) {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "product '" + sku + "' is not quarantined"})
+		return
+	}
+
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJson(w, http.StatusOK, quarantineActionResponse{
+		Sku: sku,
+	})
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+	"github.com/fabiofcferreira/xd-rsync/logger"
+)
+
+func createTestAdminApp(t *testing.T) *xd_rsync.XdRsyncInstance {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction: true,
+		Level:        "error",
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	return &xd_rsync.XdRsyncInstance{Config: &xd_rsync.Config{}, Logger: testLogger}
+}
+
+func TestAdminServerRequiresBearerToken(t *testing.T) {
+	server, err := CreateAdminServer(&AdminServerCreationInput{
+		Token:   "s3cret",
+		App:     createTestAdminApp(t),
+		Sources: map[string]*AdminSource{},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name               string
+		authorization      string
+		expectedStatusCode int
+	}{
+		{name: "missing token", expectedStatusCode: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer other", expectedStatusCode: http.StatusUnauthorized},
+		{name: "empty token", authorization: "Bearer ", expectedStatusCode: http.StatusUnauthorized},
+		{name: "token without the bearer scheme", authorization: "s3cret", expectedStatusCode: http.StatusUnauthorized},
+		{name: "token with another scheme", authorization: "Basic s3cret", expectedStatusCode: http.StatusUnauthorized},
+		{name: "token with a suffix", authorization: "Bearer s3cret2", expectedStatusCode: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer s3cret", expectedStatusCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/admin/sources", nil)
+			if len(test.authorization) > 0 {
+				request.Header.Set("Authorization", test.authorization)
+			}
+
+			response := httptest.NewRecorder()
+			server.httpServer.Handler.ServeHTTP(response, request)
+			if response.Code != test.expectedStatusCode {
+				t.Errorf("expected status %d, got %d with %s", test.expectedStatusCode, response.Code, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminServerRefusesEmptyToken(t *testing.T) {
+	_, err := CreateAdminServer(&AdminServerCreationInput{
+		App:     createTestAdminApp(t),
+		Sources: map[string]*AdminSource{},
+	})
+	if !errors.Is(err, ErrAdminTokenRequired) {
+		t.Errorf("expected %v, got %v", ErrAdminTokenRequired, err)
+	}
+
+	isCalled := false
+	handler := requireBearerToken("", createTestAdminApp(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isCalled = true
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/admin/sources", nil)
+	request.Header.Set("Authorization", "Bearer ")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if isCalled || response.Code != http.StatusUnauthorized {
+		t.Errorf("expected every request to be rejected without a token, got status %d", response.Code)
+	}
+}
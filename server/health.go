@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+const READINESS_CHECK_TIMEOUT = 5 * time.Second
+
+// How long the result of an SNS topic check is reused, so frequent probes do not call SNS on every request
+const TOPIC_CHECK_TTL = time.Minute
+
+const (
+	STATUS_OK        = "ok"
+	STATUS_FAILED    = "failed"
+	STATUS_READY     = "ready"
+	STATUS_NOT_READY = "not_ready"
+)
+
+type healthResponse struct {
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"startedAt"`
+	Uptime    string    `json:"uptime"`
+}
+
+type readinessCheck struct {
+	Status string  `json:"status"`
+	Error  *string `json:"error,omitempty"`
+}
+
+type sourceReadiness struct {
+	Checks map[string]readinessCheck `json:"checks"`
+	xd_rsync.SyncStatus
+}
+
+type topicCheck struct {
+	err       error
+	checkedAt time.Time
+}
+
+// Topics checked by the readiness probe, by check name, when they are configured
+var READINESS_TOPICS = map[string]func(queues *xd_rsync.QueuesConfig) string{
+	"sns":                 func(queues *xd_rsync.QueuesConfig) string { return queues.ProductUpdatesSnsQueueArn },
+	"customersSns":        func(queues *xd_rsync.QueuesConfig) string { return queues.CustomerUpdatesSnsQueueArn },
+	"documentsSns":        func(queues *xd_rsync.QueuesConfig) string { return queues.SalesDocumentUpdatesSnsQueueArn },
+	"stockMovementsSns":   func(queues *xd_rsync.QueuesConfig) string { return queues.StockMovementsSnsQueueArn },
+	"stockTransitionsSns": func(queues *xd_rsync.QueuesConfig) string { return queues.StockTransitionsSnsQueueArn },
+	"productsBulkSns":     func(queues *xd_rsync.QueuesConfig) string { return queues.ProductBulkUpdatesSnsQueueArn },
+	"priceAlertsSns":      func(queues *xd_rsync.QueuesConfig) string { return queues.PriceAlertsSnsQueueArn },
+}
+
+type readinessResponse struct {
+	Status  string                     `json:"status"`
+	Sources map[string]sourceReadiness `json:"sources"`
+}
+
+func createReadinessCheck(err error) readinessCheck {
+	if err != nil {
+		message := err.Error()
+		return readinessCheck{
+			Status: STATUS_FAILED,
+			Error:  &message,
+		}
+	}
+
+	return readinessCheck{Status: STATUS_OK}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, healthResponse{
+		Status:    STATUS_OK,
+		StartedAt: s.app.StartedAt,
+		Uptime:    time.Since(s.app.StartedAt).Round(time.Second).String(),
+	})
+}
+
+// The last successful run is considered stale once it is older than the allowed number of missed runs
+func (s *Server) checkLastSuccessfulRun(status *xd_rsync.SyncStatus) error {
+	cfg := s.app.GetConfig()
+	maxRunAge := time.Duration(cfg.Health.MaxMissedRuns) * cfg.SyncFrequency
+
+	if status.LastSuccessfulRunAt == nil {
+		if time.Since(status.StartedAt) > maxRunAge {
+			return fmt.Errorf("no successful run since startup %s ago", time.Since(status.StartedAt).Round(time.Second))
+		}
+
+		return nil
+	}
+
+	if time.Since(*status.LastSuccessfulRunAt) > maxRunAge {
+		return fmt.Errorf("last successful run was %s ago", time.Since(*status.LastSuccessfulRunAt).Round(time.Second))
+	}
+
+	return nil
+}
+
+// Checks the topic through SNS, reusing the result of the last check for TOPIC_CHECK_TTL
+func (s *Server) checkTopic(ctx context.Context, source *xd_rsync.XdRsyncSource, topicArn string) error {
+	key := source.Id + "/" + topicArn
+	s.topicChecksMutex.Lock()
+	check, isChecked := s.topicChecks[key]
+	s.topicChecksMutex.Unlock()
+	if isChecked && time.Since(check.checkedAt) < TOPIC_CHECK_TTL {
+		return check.err
+	}
+
+	check = &topicCheck{
+		err:       source.SNS.CheckTopic(ctx, topicArn),
+		checkedAt: time.Now(),
+	}
+	s.topicChecksMutex.Lock()
+	s.topicChecks[key] = check
+	s.topicChecksMutex.Unlock()
+
+	return check.err
+}
+
+func (s *Server) checkSourceReadiness(ctx context.Context, source *xd_rsync.XdRsyncSource) sourceReadiness {
+	status := source.State.GetStatus()
+	checks := map[string]readinessCheck{
+		"database":          createReadinessCheck(source.Database.Ping(ctx)),
+		"lastSuccessfulRun": createReadinessCheck(s.checkLastSuccessfulRun(&status)),
+	}
+
+	sourceConfig := s.app.GetConfig().GetSource(source.Id)
+	if sourceConfig == nil {
+		return sourceReadiness{
+			Checks:     checks,
+			SyncStatus: status,
+		}
+	}
+
+	for name, getTopicArn := range READINESS_TOPICS {
+		topicArn := getTopicArn(sourceConfig.Queues)
+		if len(topicArn) > 0 {
+			checks[name] = createReadinessCheck(s.checkTopic(ctx, source, topicArn))
+		}
+	}
+
+	return sourceReadiness{
+		Checks:     checks,
+		SyncStatus: status,
+	}
+}
+
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), READINESS_CHECK_TIMEOUT)
+	defer cancel()
+
+	response := readinessResponse{
+		Status:  STATUS_READY,
+		Sources: map[string]sourceReadiness{},
+	}
+
+	statusCode := http.StatusOK
+	for _, source := range s.app.Sources {
+		readiness := s.checkSourceReadiness(ctx, source)
+		response.Sources[source.Id] = readiness
+
+		for _, check := range readiness.Checks {
+			if check.Status != STATUS_OK {
+				response.Status = STATUS_NOT_READY
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	writeJson(w, statusCode, response)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+// Stand-in for SNS, counting the checks of each topic
+type testSNS struct {
+	xd_rsync.SNSService
+	checks       map[string]int
+	failedTopics map[string]bool
+}
+
+func (s *testSNS) CheckTopic(ctx context.Context, topicArn string) error {
+	s.checks[topicArn]++
+	if s.failedTopics[topicArn] {
+		return errors.New("topic not found")
+	}
+
+	return nil
+}
+
+type testDatabase struct {
+	xd_rsync.DatabaseService
+}
+
+func (d *testDatabase) Ping(ctx context.Context) error {
+	return nil
+}
+
+func createTestServer(queues *xd_rsync.QueuesConfig, sns *testSNS) *Server {
+	return &Server{
+		app: &xd_rsync.XdRsyncInstance{
+			Config: &xd_rsync.Config{
+				SyncFrequency: time.Minute,
+				Health:        &xd_rsync.HealthConfig{MaxMissedRuns: 3},
+				Sources:       []*xd_rsync.SourceConfig{{Id: "store-a", Queues: queues}},
+			},
+			Sources: []*xd_rsync.XdRsyncSource{{
+				Id:       "store-a",
+				State:    xd_rsync.CreateSyncState(time.Now()),
+				Database: &testDatabase{},
+				SNS:      sns,
+			}},
+		},
+		topicChecks: map[string]*topicCheck{},
+	}
+}
+
+func TestReadinessSkipsUnconfiguredTopics(t *testing.T) {
+	sns := &testSNS{checks: map[string]int{}}
+	server := createTestServer(&xd_rsync.QueuesConfig{CustomerUpdatesSnsQueueArn: "customers"}, sns)
+
+	recorder := httptest.NewRecorder()
+	server.handleReadiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200 without a products topic, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	if len(sns.checks) != 1 || sns.checks["customers"] != 1 {
+		t.Errorf("expected only the customers topic to be checked, got %v", sns.checks)
+	}
+}
+
+func TestReadinessCachesTopicChecks(t *testing.T) {
+	sns := &testSNS{checks: map[string]int{}, failedTopics: map[string]bool{"products": true}}
+	server := createTestServer(&xd_rsync.QueuesConfig{
+		ProductUpdatesSnsQueueArn:  "products",
+		CustomerUpdatesSnsQueueArn: "customers",
+	}, sns)
+
+	for probe := 0; probe < 3; probe++ {
+		recorder := httptest.NewRecorder()
+		server.handleReadiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if recorder.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status 503 with a failed topic, got %d", recorder.Code)
+		}
+	}
+
+	if sns.checks["products"] != 1 || sns.checks["customers"] != 1 {
+		t.Errorf("expected each topic to be checked once within the TTL, got %v", sns.checks)
+	}
+
+	// Expired checks are made again
+	for _, check := range server.topicChecks {
+		check.checkedAt = check.checkedAt.Add(-TOPIC_CHECK_TTL)
+	}
+	server.handleReadiness(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if sns.checks["products"] != 2 || sns.checks["customers"] != 2 {
+		t.Errorf("expected each topic to be checked again once expired, got %v", sns.checks)
+	}
+}
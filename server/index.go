@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	xd_rsync "github.com/fabiofcferreira/xd-rsync"
+)
+
+type Server struct {
+	app        *xd_rsync.XdRsyncInstance
+	httpServer *http.Server
+	// Results of the SNS topic checks of the readiness probe, by source ID and topic ARN
+	topicChecks      map[string]*topicCheck
+	topicChecksMutex sync.Mutex
+}
+
+type ServerCreationInput struct {
+	ListenAddress string
+	App           *xd_rsync.XdRsyncInstance
+}
+
+func CreateServer(input *ServerCreationInput) *Server {
+	server := &Server{
+		app:         input.App,
+		topicChecks: map[string]*topicCheck{},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", input.App.Metrics.Handler())
+	mux.HandleFunc("GET /healthz", server.handleHealth)
+	mux.HandleFunc("GET /readyz", server.handleReadiness)
+
+	server.httpServer = &http.Server{
+		Addr:              input.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return server
+}
+
+func (s *Server) Start() {
+	s.app.Logger.Info("init_http_server", "Starting HTTP server", &map[string]interface{}{
+		"listenAddress": s.httpServer.Addr,
+	})
+
+	go func() {
+		err := s.httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.app.Logger.Error("failed_http_server", "HTTP server stopped", &map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
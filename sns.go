@@ -1,11 +1,26 @@
 package xd_rsync
 
+import (
+	"context"
+	"time"
+)
+
 type MessagePublishInput struct {
 	Message        string
 	MessageGroupId string
+	Attributes     map[string]string
+	// Called once SNS acknowledges the message
+	OnAcknowledged func(acknowledgedAt time.Time)
+}
+
+func (m *MessagePublishInput) Acknowledge(acknowledgedAt time.Time) {
+	if m.OnAcknowledged != nil {
+		m.OnAcknowledged(acknowledgedAt)
+	}
 }
 
 type SNSService interface {
-	SendMessage(topicArn string, input *MessagePublishInput) error
-	SendMessagesBatch(topicArn string, input *[]MessagePublishInput) (int, []error)
+	CheckTopic(ctx context.Context, topicArn string) error
+	SendMessage(ctx context.Context, topicArn string, input *MessagePublishInput) error
+	SendMessagesBatch(ctx context.Context, topicArn string, input *[]MessagePublishInput) (int, []error)
 }
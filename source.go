@@ -0,0 +1,28 @@
+package xd_rsync
+
+import (
+	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
+)
+
+const DEFAULT_SOURCE_ID = "default"
+
+// Services and state of a single XD database. Its logger and metrics are labelled with the source ID.
+type XdRsyncSource struct {
+	Id       string
+	Logger   *logger.Logger
+	Metrics  *metrics.Metrics
+	State    *SyncState
+	Database DatabaseService
+	SNS      SNSService
+}
+
+func (c *Config) GetSource(id string) *SourceConfig {
+	for _, source := range c.Sources {
+		if source.Id == id {
+			return source
+		}
+	}
+
+	return nil
+}
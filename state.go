@@ -0,0 +1,185 @@
+package xd_rsync
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+type SyncRunSummary struct {
+	StartedAt       time.Time      `json:"startedAt"`
+	FinishedAt      time.Time      `json:"finishedAt"`
+	Checkpoint      time.Time      `json:"checkpoint"`
+	ChangedProducts int            `json:"changedProducts"`
+	ChangedEntities map[string]int `json:"changedEntities"`
+	Errors          []string       `json:"errors,omitempty"`
+}
+
+type SyncStatus struct {
+	StartedAt  time.Time `json:"startedAt"`
+	IsRunning  bool      `json:"isRunning"`
+	Checkpoint time.Time `json:"checkpoint"`
+	// Checkpoints of the streams that do not share the source checkpoint, by stream name
+	StreamCheckpoints   map[string]time.Time `json:"streamCheckpoints,omitempty"`
+	LastRun             *SyncRunSummary      `json:"lastRun"`
+	LastSuccessfulRunAt *time.Time           `json:"lastSuccessfulRunAt"`
+	LastError           *string              `json:"lastError"`
+	MassChangeHold      *MassChangeHold      `json:"massChangeHold,omitempty"`
+}
+
+const MAX_RECENT_RUNS = 20
+
+// Checkpoint of the sources that were never synchronised, so every record is captured
+var INITIAL_CHECKPOINT = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Keeps track of the sync progress, shared between the scheduler and the HTTP server
+type SyncState struct {
+	mutex      sync.RWMutex
+	status     SyncStatus
+	recentRuns []SyncRunSummary
+}
+
+func CreateSyncState(checkpoint time.Time) *SyncState {
+	return &SyncState{
+		status: SyncStatus{
+			StartedAt:  time.Now(),
+			Checkpoint: checkpoint,
+		},
+	}
+}
+
+func (s *SyncState) GetCheckpoint() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.status.Checkpoint
+}
+
+func (s *SyncState) SetCheckpoint(checkpoint time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.status.Checkpoint = checkpoint
+}
+
+// Returns the checkpoint of a stream that keeps its own, falling back to the source checkpoint until it has one
+func (s *SyncState) GetStreamCheckpoint(stream string) time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	checkpoint, hasCheckpoint := s.status.StreamCheckpoints[stream]
+	if !hasCheckpoint {
+		return s.status.Checkpoint
+	}
+
+	return checkpoint
+}
+
+// Returns the checkpoint of the stream when it has its own, e.g. while it is held back by a mass change
+func (s *SyncState) LookupStreamCheckpoint(stream string) (time.Time, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	checkpoint, hasCheckpoint := s.status.StreamCheckpoints[stream]
+	return checkpoint, hasCheckpoint
+}
+
+// Makes the stream share the source checkpoint again
+func (s *SyncState) DeleteStreamCheckpoint(stream string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.status.StreamCheckpoints, stream)
+}
+
+func (s *SyncState) SetStreamCheckpoint(stream string, checkpoint time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.status.StreamCheckpoints == nil {
+		s.status.StreamCheckpoints = map[string]time.Time{}
+	}
+	s.status.StreamCheckpoints[stream] = checkpoint
+}
+
+func (s *SyncState) GetMassChangeHold() *MassChangeHold {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.status.MassChangeHold == nil {
+		return nil
+	}
+
+	hold := *s.status.MassChangeHold
+	return &hold
+}
+
+// Holds back a mass change, or releases it when nil
+func (s *SyncState) SetMassChangeHold(hold *MassChangeHold) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.status.MassChangeHold = hold
+}
+
+// Allows the held back mass change to be published on the next run. Returns false if there is none.
+func (s *SyncState) ConfirmMassChangeHold() (*MassChangeHold, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.status.MassChangeHold == nil {
+		return nil, false
+	}
+
+	s.status.MassChangeHold.IsConfirmed = true
+	hold := *s.status.MassChangeHold
+	return &hold, true
+}
+
+func (s *SyncState) StartRun() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.status.IsRunning = true
+}
+
+func (s *SyncState) FinishRun(summary *SyncRunSummary) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.status.IsRunning = false
+	s.status.LastRun = summary
+
+	s.recentRuns = append([]SyncRunSummary{*summary}, s.recentRuns...)
+	if len(s.recentRuns) > MAX_RECENT_RUNS {
+		s.recentRuns = s.recentRuns[:MAX_RECENT_RUNS]
+	}
+
+	if len(summary.Errors) > 0 {
+		s.status.LastError = &summary.Errors[0]
+		return
+	}
+
+	s.status.LastSuccessfulRunAt = &summary.FinishedAt
+}
+
+func (s *SyncState) GetStatus() SyncStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	status := s.status
+	status.StreamCheckpoints = maps.Clone(s.status.StreamCheckpoints)
+	if s.status.MassChangeHold != nil {
+		hold := *s.status.MassChangeHold
+		status.MassChangeHold = &hold
+	}
+	return status
+}
+
+// Returns the summaries of the most recent runs, newest first
+func (s *SyncState) GetRecentRuns() []SyncRunSummary {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]SyncRunSummary{}, s.recentRuns...)
+}
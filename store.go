@@ -0,0 +1,9 @@
+package xd_rsync
+
+// Keeps state between sync runs and restarts, e.g. the last published stock levels. Values are stored as JSON.
+type StateStore interface {
+	// Reads the value of the key into the given pointer, returning false when there is none
+	Get(key string, value interface{}) (bool, error)
+	Set(key string, value interface{}) error
+	Delete(key string) error
+}
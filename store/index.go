@@ -0,0 +1,121 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Keeps JSON values by key in a single file, which is replaced on every change. Values are only kept in memory
+// when no path is given.
+type FileStore struct {
+	path   string
+	mutex  sync.Mutex
+	values map[string]json.RawMessage
+}
+
+type FileStoreCreationInput struct {
+	Path string
+}
+
+func CreateFileStore(input *FileStoreCreationInput) (*FileStore, error) {
+	store := &FileStore{
+		path:   input.Path,
+		values: map[string]json.RawMessage{},
+	}
+
+	if len(store.path) == 0 {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(store.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read state file: %w", err)
+	}
+
+	err = json.Unmarshal(content, &store.values)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse state file: %w", err)
+	}
+
+	return store, nil
+}
+
+// Reads the value of the key into the given pointer, returning false when there is none
+func (s *FileStore) Get(key string, value interface{}) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	content, hasValue := s.values[key]
+	if !hasValue {
+		return false, nil
+	}
+
+	err := json.Unmarshal(content, value)
+	if err != nil {
+		return false, fmt.Errorf("could not parse state of %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func (s *FileStore) Set(key string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("could not serialise state of %s: %w", key, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values[key] = content
+	return s.write()
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.values, key)
+	return s.write()
+}
+
+// Replaces the state file atomically, so an interrupted write never leaves it corrupted
+func (s *FileStore) write() error {
+	if len(s.path) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(s.values)
+	if err != nil {
+		return fmt.Errorf("could not serialise state: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create state file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(content)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	err = os.Rename(tempFile.Name(), s.path)
+	if err != nil {
+		return fmt.Errorf("could not replace state file: %w", err)
+	}
+
+	return nil
+}
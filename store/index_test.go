@@ -0,0 +1,125 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createTestFileStore(t *testing.T, path string) *FileStore {
+	store, err := CreateFileStore(&FileStoreCreationInput{Path: path})
+	if err != nil {
+		t.Fatalf("could not create store: %v", err)
+	}
+
+	return store
+}
+
+// Returns the names of the files in the directory, failing when there is any temporary file left
+func getStateFileNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read directory: %v", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".tmp" {
+			t.Errorf("expected no temporary files, found %s", entry.Name())
+		}
+		names = append(names, entry.Name())
+	}
+
+	return names
+}
+
+func TestFileStoreGetMissingKey(t *testing.T) {
+	paths := map[string]string{
+		"in memory":            "",
+		"without a state file": filepath.Join(t.TempDir(), "state.json"),
+	}
+
+	for name, path := range paths {
+		t.Run(name, func(t *testing.T) {
+			store := createTestFileStore(t, path)
+			err := store.Set("other", "value")
+			if err != nil {
+				t.Fatalf("could not set value: %v", err)
+			}
+
+			value := "unchanged"
+			hasValue, err := store.Get("missing", &value)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if hasValue || value != "unchanged" {
+				t.Errorf("expected no value, got %v with %s", hasValue, value)
+			}
+		})
+	}
+}
+
+func TestFileStoreReplacesStateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	err := os.WriteFile(path, []byte(`{"previous":"kept"}`), 0600)
+	if err != nil {
+		t.Fatalf("could not write state file: %v", err)
+	}
+
+	store := createTestFileStore(t, path)
+	for _, change := range []func() error{
+		func() error { return store.Set("checkpoint", "2024-05-01T10:30:00Z") },
+		func() error { return store.Set("published_prices", map[string]string{"A1": "10.00"}) },
+		func() error { return store.Delete("checkpoint") },
+	} {
+		err := change()
+		if err != nil {
+			t.Fatalf("could not change state: %v", err)
+		}
+
+		// The file is replaced as a whole, so it is always complete and valid
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("could not read state file: %v", err)
+		}
+		if !json.Valid(content) {
+			t.Errorf("expected the state file to be valid JSON, got %s", content)
+		}
+
+		if names := getStateFileNames(t, dir); len(names) != 1 {
+			t.Errorf("expected only the state file, got %v", names)
+		}
+	}
+
+	reopenedStore := createTestFileStore(t, path)
+	previous := ""
+	if hasValue, err := reopenedStore.Get("previous", &previous); err != nil || !hasValue || previous != "kept" {
+		t.Errorf("expected the previous value to be kept, got %s (%v)", previous, err)
+	}
+
+	prices := map[string]string{}
+	if hasValue, err := reopenedStore.Get("published_prices", &prices); err != nil || !hasValue || prices["A1"] != "10.00" {
+		t.Errorf("expected the published prices to be read back, got %v (%v)", prices, err)
+	}
+
+	checkpoint := ""
+	if hasValue, _ := reopenedStore.Get("checkpoint", &checkpoint); hasValue {
+		t.Errorf("expected the deleted checkpoint to be gone, got %s", checkpoint)
+	}
+}
+
+func TestCreateFileStoreRejectsCorruptStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	err := os.WriteFile(path, []byte(`{"checkpoint":`), 0600)
+	if err != nil {
+		t.Fatalf("could not write state file: %v", err)
+	}
+
+	_, err = CreateFileStore(&FileStoreCreationInput{Path: path})
+	if err == nil {
+		t.Error("expected a corrupt state file to be rejected")
+	}
+}
@@ -0,0 +1,116 @@
+package tickers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type TickerAction func(ctx context.Context) []error
+
+// Runs the action on a fixed frequency, allowing it to be paused, resumed and triggered on demand
+type Scheduler struct {
+	mutex     sync.Mutex
+	frequency time.Duration
+	isPaused  bool
+	action    TickerAction
+	triggers  chan struct{}
+	resets    chan struct{}
+}
+
+type SchedulerStatus struct {
+	Frequency string `json:"frequency"`
+	IsPaused  bool   `json:"isPaused"`
+}
+
+func CreateScheduler(frequency time.Duration, action TickerAction) *Scheduler {
+	return &Scheduler{
+		frequency: frequency,
+		action:    action,
+		triggers:  make(chan struct{}, 1),
+		resets:    make(chan struct{}, 1),
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.getFrequency())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.resets:
+			ticker.Reset(s.getFrequency())
+		case <-ticker.C:
+			if s.IsPaused() {
+				continue
+			}
+
+			// Failed runs are recorded by the action and retried on the next tick
+			s.action(ctx)
+		case <-s.triggers:
+			s.action(ctx)
+		}
+	}
+}
+
+// Requests an immediate run, even when paused. Returns false if a run was already requested.
+func (s *Scheduler) Trigger() bool {
+	select {
+	case s.triggers <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Scheduler) getFrequency() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.frequency
+}
+
+// Changes the time between runs, restarting the wait for the next run
+func (s *Scheduler) SetFrequency(frequency time.Duration) {
+	s.mutex.Lock()
+	s.frequency = frequency
+	s.mutex.Unlock()
+
+	select {
+	case s.resets <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.isPaused = true
+}
+
+func (s *Scheduler) Resume() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.isPaused = false
+}
+
+func (s *Scheduler) IsPaused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.isPaused
+}
+
+func (s *Scheduler) GetStatus() SchedulerStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return SchedulerStatus{
+		Frequency: s.frequency.String(),
+		IsPaused:  s.isPaused,
+	}
+}
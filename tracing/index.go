@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fabiofcferreira/xd-rsync/logger"
+)
+
+const TRACER_NAME = "github.com/fabiofcferreira/xd-rsync"
+
+type TracerProviderCreationInput struct {
+	OtlpEndpoint string
+	Insecure     bool
+	SampleRatio  float64
+	Environment  string
+	Logger       *logger.Logger
+}
+
+type ShutdownFunc func(ctx context.Context) error
+
+// Sets up the global tracer provider. Spans are only exported when an OTLP endpoint is provided.
+func CreateTracerProvider(input *TracerProviderCreationInput) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if len(input.OtlpEndpoint) == 0 {
+		input.Logger.Info("skip_tracer_provider_create", "OTLP endpoint not specified. Tracing is disabled", nil)
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	exporterOptions := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(input.OtlpEndpoint),
+	}
+	if input.Insecure {
+		exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), exporterOptions...)
+	if err != nil {
+		input.Logger.Error("failed_tracer_provider_create", "Failed to create OTLP trace exporter", &map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("could not create OTLP trace exporter: %w", err)
+	}
+
+	traceResource, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("xd-rsync"),
+		semconv.DeploymentEnvironment(input.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("could not create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(traceResource),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(input.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	input.Logger.Info("created_tracer_provider", "Created tracer provider", &map[string]interface{}{
+		"otlpEndpoint": input.OtlpEndpoint,
+		"sampleRatio":  input.SampleRatio,
+	})
+
+	return provider.Shutdown, nil
+}
+
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TRACER_NAME).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Returns a copy of the attributes with the trace context of ctx, so consumers can continue the trace
+func InjectIntoAttributes(ctx context.Context, attributes map[string]string) map[string]string {
+	carrier := propagation.MapCarrier{}
+	maps.Copy(carrier, attributes)
+
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
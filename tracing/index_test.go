@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fabiofcferreira/xd-rsync/logger"
+)
+
+// Records the spans in memory instead of exporting them, as a collector stand-in
+func useInMemoryExporter(t *testing.T) *tracetest.InMemoryExporter {
+	testLogger, err := logger.CreateLogger(&logger.LoggerOptions{
+		IsProduction:  true,
+		InitialFields: map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	// Without an OTLP endpoint only the propagator is set up
+	shutdown, err := CreateTracerProvider(&TracerProviderCreationInput{Logger: testLogger})
+	if err != nil {
+		t.Fatalf("could not create tracer provider: %v", err)
+	}
+	t.Cleanup(func() { shutdown(context.Background()) })
+
+	exporter := tracetest.NewInMemoryExporter()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+
+	return exporter
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	t.Fatalf("expected span %s to be exported", name)
+	return tracetest.SpanStub{}
+}
+
+func getSpanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, keyValue := range span.Attributes {
+		if keyValue.Key == key {
+			return keyValue.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestSpanHierarchyAndPropagation(t *testing.T) {
+	exporter := useInMemoryExporter(t)
+
+	runCtx, runSpan := StartSpan(context.Background(), "sync_run", attribute.String("sync.tenant_id", "store-a"))
+	_, pageSpan := StartSpan(runCtx, "db.paginated_products",
+		attribute.Int("db.page.offset", 200),
+		attribute.Int("db.page.limit", 200),
+	)
+	pageSpan.SetAttributes(attribute.Int("db.entities_count", 37))
+	EndSpan(pageSpan, nil)
+
+	batchCtx, batchSpan := StartSpan(runCtx, "sns.publish_batch")
+	batchSpan.SetAttributes(attribute.Int("messaging.retry_count", 2))
+	attributes := map[string]string{"tenantId": "store-a"}
+	injectedAttributes := InjectIntoAttributes(batchCtx, attributes)
+	EndSpan(batchSpan, errors.New("throttled"))
+	EndSpan(runSpan, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	run := findSpan(t, spans, "sync_run")
+	page := findSpan(t, spans, "db.paginated_products")
+	batch := findSpan(t, spans, "sns.publish_batch")
+	if run.Parent.IsValid() {
+		t.Errorf("expected sync_run to be a root span, got parent %s", run.Parent.SpanID())
+	}
+	for _, child := range []tracetest.SpanStub{page, batch} {
+		if child.Parent.SpanID() != run.SpanContext.SpanID() || child.SpanContext.TraceID() != run.SpanContext.TraceID() {
+			t.Errorf("expected %s to be a child of sync_run", child.Name)
+		}
+	}
+
+	if getSpanAttribute(run, "sync.tenant_id").AsString() != "store-a" {
+		t.Errorf("expected the tenant of sync_run, got %v", run.Attributes)
+	}
+	if getSpanAttribute(page, "db.page.offset").AsInt64() != 200 || getSpanAttribute(page, "db.entities_count").AsInt64() != 37 {
+		t.Errorf("expected the offset and count of the page, got %v", page.Attributes)
+	}
+	if getSpanAttribute(batch, "messaging.retry_count").AsInt64() != 2 {
+		t.Errorf("expected the retry count of the batch, got %v", batch.Attributes)
+	}
+
+	if batch.Status.Code != codes.Error || batch.Status.Description != "throttled" || len(batch.Events) != 1 {
+		t.Errorf("expected the batch error to be recorded, got status %v and events %v", batch.Status, batch.Events)
+	}
+	if run.Status.Code != codes.Unset {
+		t.Errorf("expected sync_run not to have an error, got %v", run.Status)
+	}
+
+	expectedTraceparent := fmt.Sprintf("00-%s-%s-01", batch.SpanContext.TraceID(), batch.SpanContext.SpanID())
+	if injectedAttributes["traceparent"] != expectedTraceparent {
+		t.Errorf("expected traceparent %s, got %s", expectedTraceparent, injectedAttributes["traceparent"])
+	}
+	if injectedAttributes["tenantId"] != "store-a" {
+		t.Errorf("expected the original attributes to be kept, got %v", injectedAttributes)
+	}
+	if _, isInjected := attributes["traceparent"]; isInjected {
+		t.Error("expected the original attributes not to be changed")
+	}
+}
+
+func TestInjectIntoAttributesWithoutSpan(t *testing.T) {
+	useInMemoryExporter(t)
+
+	attributes := InjectIntoAttributes(context.Background(), map[string]string{"tenantId": "store-a"})
+	if _, isInjected := attributes["traceparent"]; isInjected || attributes["tenantId"] != "store-a" {
+		t.Errorf("expected only the original attributes without a span, got %v", attributes)
+	}
+}
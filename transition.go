@@ -0,0 +1,43 @@
+package xd_rsync
+
+import (
+	"time"
+)
+
+const (
+	STOCK_TRANSITION_OUT_OF_STOCK  = "out_of_stock"
+	STOCK_TRANSITION_BACK_IN_STOCK = "back_in_stock"
+	STOCK_TRANSITION_LOW_STOCK     = "low_stock"
+)
+
+// Crossing of a stock threshold by the available quantity of a product, since its previously published state
+type XdStockTransition struct {
+	SKU    string  `json:"sku"`
+	Type   string  `json:"type"`
+	Family *string `json:"family,omitempty"`
+	// Available quantity when the product was previously published
+	PreviousQuantity  float64   `json:"previousQuantity"`
+	AvailableQuantity float64   `json:"availableQuantity"`
+	Threshold         float64   `json:"threshold"`
+	DetectedAt        time.Time `json:"detectedAt"`
+}
+
+// Returns the transition between the given available quantities, or an empty type when no threshold was crossed.
+// A product that runs out of stock only has an out of stock transition, even if it was above the low stock level.
+func GetStockTransition(thresholds *StockThresholdsConfig, previousQuantity float64, availableQuantity float64) (string, float64) {
+	wasOutOfStock := previousQuantity <= thresholds.OutOfStockQuantity
+	isOutOfStock := availableQuantity <= thresholds.OutOfStockQuantity
+	if !wasOutOfStock && isOutOfStock {
+		return STOCK_TRANSITION_OUT_OF_STOCK, thresholds.OutOfStockQuantity
+	}
+
+	if wasOutOfStock && !isOutOfStock {
+		return STOCK_TRANSITION_BACK_IN_STOCK, thresholds.OutOfStockQuantity
+	}
+
+	if thresholds.LowStockQuantity > 0 && previousQuantity > thresholds.LowStockQuantity && availableQuantity <= thresholds.LowStockQuantity {
+		return STOCK_TRANSITION_LOW_STOCK, thresholds.LowStockQuantity
+	}
+
+	return "", 0
+}
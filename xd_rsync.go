@@ -1,38 +1,190 @@
 package xd_rsync
 
 import (
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fabiofcferreira/xd-rsync/logger"
+	"github.com/fabiofcferreira/xd-rsync/metrics"
 )
 
 type QueuesConfig struct {
-	ProductUpdatesSnsQueueArn string `json:"productUpdatesSnsQueueArn,omitempty"`
+	ProductUpdatesSnsQueueArn       string `json:"productUpdatesSnsQueueArn,omitempty"`
+	CustomerUpdatesSnsQueueArn      string `json:"customerUpdatesSnsQueueArn,omitempty"`
+	SalesDocumentUpdatesSnsQueueArn string `json:"salesDocumentUpdatesSnsQueueArn,omitempty"`
+	StockMovementsSnsQueueArn       string `json:"stockMovementsSnsQueueArn,omitempty"`
+	StockTransitionsSnsQueueArn     string `json:"stockTransitionsSnsQueueArn,omitempty"`
+	PriceAlertsSnsQueueArn          string `json:"priceAlertsSnsQueueArn,omitempty"`
+	ProductBulkUpdatesSnsQueueArn   string `json:"productBulkUpdatesSnsQueueArn,omitempty"`
+}
+
+type SourceFiltersConfig struct {
+	// Only products whose SKU starts with one of them are synchronised, unless it is empty
+	SkuPrefixes  []string `json:"skuPrefixes"`
+	ExcludedSkus []string `json:"excludedSkus"`
+	// Sales document types to publish, e.g. "FT". All types are published when empty.
+	DocumentTypes []string `json:"documentTypes"`
+	// Rules selecting the synchronised products. Defaults to the top-level product filters
+	Products []*ProductFilterRule `json:"products"`
+	// Conditions compiled from the product rules and the SKU filters when the configuration is read
+	ProductConditions []Condition `json:"-"`
+}
+
+// Available quantities whose crossing publishes a stock transition
+type StockThresholdsConfig struct {
+	// Products are out of stock when their available quantity is at or below it
+	OutOfStockQuantity float64 `json:"outOfStockQuantity"`
+	// Products are low on stock when their available quantity is at or below it. Disabled when 0.
+	LowStockQuantity float64 `json:"lowStockQuantity"`
+}
+
+type StockConfig struct {
+	// Warehouses whose stock counts towards the available quantity of the products. Every warehouse counts when empty.
+	SellableWarehouseIds []string               `json:"sellableWarehouseIds"`
+	Thresholds           *StockThresholdsConfig `json:"thresholds"`
+	// Thresholds replacing the default ones for the products of a family, by lowercase family code
+	FamilyThresholds map[string]*StockThresholdsConfig `json:"familyThresholds"`
+}
+
+// Returns the thresholds of the products of the given family
+func (c *StockConfig) GetThresholds(family *string) *StockThresholdsConfig {
+	if family != nil {
+		if thresholds, hasThresholds := c.FamilyThresholds[strings.ToLower(*family)]; hasThresholds {
+			return thresholds
+		}
+	}
+
+	return c.Thresholds
+}
+
+// An XD database synchronised independently from the others, e.g. one per shop
+type SourceConfig struct {
+	Id      string               `json:"id"`
+	DSN     string               `json:"dsn"`
+	Queues  *QueuesConfig        `json:"queues"`
+	Filters *SourceFiltersConfig `json:"filters"`
+	// Defaults to the top-level stock settings
+	Stock *StockConfig `json:"stock"`
+	// Defaults to the top-level money settings
+	Money *MoneyConfig `json:"money"`
 }
 
 type DatadogConfig struct {
 	IngestHost      *string                 `json:"ingestHost"`
 	ApiKey          *string                 `json:"datadogApiKey"`
 	EventBaseFields *map[string]interface{} `json:"eventBaseFields"`
+	StatsdAddress   *string                 `json:"statsdAddress"`
+}
+
+type HttpConfig struct {
+	ListenAddress string `json:"listenAddress"`
+}
+
+type AdminConfig struct {
+	ListenAddress string `json:"listenAddress"`
+	Token         string `json:"token"`
+}
+
+type HealthConfig struct {
+	MaxMissedRuns int `json:"maxMissedRuns"`
+}
+
+type SchemaRegistryConfig struct {
+	Url      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type EncodingConfig struct {
+	Format         string                `json:"format"`
+	SchemaRegistry *SchemaRegistryConfig `json:"schemaRegistry"`
+}
+
+// Personal data fields hashed or left out before reaching a sink, e.g. "sns"
+type PiiSinkConfig struct {
+	HashedFields  []string `json:"hashedFields"`
+	OmittedFields []string `json:"omittedFields"`
+}
+
+type PiiConfig struct {
+	HashKey string                    `json:"hashKey"`
+	Sinks   map[string]*PiiSinkConfig `json:"sinks"`
+}
+
+type TracingConfig struct {
+	OtlpEndpoint string  `json:"otlpEndpoint"`
+	Insecure     bool    `json:"insecure"`
+	SampleRatio  float64 `json:"sampleRatio"`
 }
 
 type Config struct {
-	Environment      string         `json:"environment"`
-	IsProductionMode bool           `json:"isProductionMode"`
-	AwsRegion        string         `json:"awsRegion"`
-	DSN              string         `json:"dsn"`
-	Queues           *QueuesConfig  `json:"queues"`
-	SyncFrequency    time.Duration  `json:"syncFrequency"`
-	DatadogConfig    *DatadogConfig `json:"datadog"`
+	Environment      string            `json:"environment"`
+	LogLevel         string            `json:"logLevel"`
+	IsProductionMode bool              `json:"isProductionMode"`
+	AwsRegion        string            `json:"awsRegion"`
+	DSN              string            `json:"dsn"`
+	Queues           *QueuesConfig     `json:"queues"`
+	Sources          []*SourceConfig   `json:"sources"`
+	Stock            *StockConfig      `json:"stock"`
+	PriceGuard       *PriceGuardConfig `json:"priceGuard"`
+	MassChange       *MassChangeConfig `json:"massChange"`
+	Money            *MoneyConfig      `json:"money"`
+	// Prices published besides the retail prices, e.g. wholesale
+	PriceLists []*PriceListConfig `json:"priceLists"`
+	Promotions *PromotionsConfig  `json:"promotions"`
+	// Rules selecting the synchronised products of the sources without their own
+	ProductFilters    []*ProductFilterRule `json:"productFilters"`
+	SyncFrequency     time.Duration        `json:"syncFrequency"`
+	ReplicationLagSlo time.Duration        `json:"replicationLagSlo"`
+	DatadogConfig     *DatadogConfig       `json:"datadog"`
+	Encoding          *EncodingConfig      `json:"encoding"`
+	Http              *HttpConfig          `json:"http"`
+	Health            *HealthConfig        `json:"health"`
+	Admin             *AdminConfig         `json:"admin"`
+	Tracing           *TracingConfig       `json:"tracing"`
+	Pii               *PiiConfig           `json:"pii"`
+	StateFile         string               `json:"stateFile"`
 }
 
 type XdRsyncServices struct {
-	Database DatabaseService
-	SNS      SNSService
+	Encoder MessageEncoder
+	Store   StateStore
 }
 
 type XdRsyncInstance struct {
+	// Initial configuration. Use GetConfig once running, as the configuration can be reloaded
 	Config   *Config
 	Logger   *logger.Logger
+	Metrics  *metrics.Metrics
 	Services *XdRsyncServices
+	Sources  []*XdRsyncSource
+	// Time at which the process was started
+	StartedAt time.Time
+
+	configMutex sync.RWMutex
+}
+
+func (i *XdRsyncInstance) GetConfig() *Config {
+	i.configMutex.RLock()
+	defer i.configMutex.RUnlock()
+
+	return i.Config
+}
+
+func (i *XdRsyncInstance) GetSource(id string) *XdRsyncSource {
+	for _, source := range i.Sources {
+		if source.Id == id {
+			return source
+		}
+	}
+
+	return nil
+}
+
+func (i *XdRsyncInstance) SetConfig(cfg *Config) {
+	i.configMutex.Lock()
+	defer i.configMutex.Unlock()
+
+	i.Config = cfg
 }